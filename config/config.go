@@ -1,9 +1,11 @@
 package config
 
 import (
+	"net/url"
 	"os"
 	"path/filepath"
 	"sentinel/logger"
+	"sentinel/models"
 
 	"github.com/davecgh/go-spew/spew"
 	"github.com/spf13/viper"
@@ -38,6 +40,74 @@ type config struct {
 		FromName string `mapstructure:"from_name"`
 		FromMail string `mapstructure:"from_mail"`
 	} `mapstructure:"mail"`
+
+	Revocation struct {
+		OCSP         bool `mapstructure:"ocsp"`
+		CRL          bool `mapstructure:"crl"`
+		CRLCacheSize int  `mapstructure:"crl_cache_size"` // megabytes
+		Timeout      int  `mapstructure:"timeout"`        // seconds
+	} `mapstructure:"revocation"`
+
+	Scan struct {
+		Deep              bool `mapstructure:"deep"`            // enumerate protocol versions, cipher suites, ALPN and groups
+		ResolveAll        bool `mapstructure:"resolve_all"`     // probe every A/AAAA address of each domain
+		HTTP              bool `mapstructure:"http"`            // send GET / after the handshake and record the response
+		HTTPTimeout       int  `mapstructure:"http_timeout"`    // seconds
+		HTTPMaxSize       int  `mapstructure:"http_max_size"`   // kilobytes read from the response
+		Crawl             bool `mapstructure:"crawl"`           // follow redirects and check the hosts the landing page depends on
+		CrawlMaxHosts     int  `mapstructure:"crawl_max_hosts"` // dependent hosts checked per target
+		CrawlMaxRedirects int  `mapstructure:"crawl_max_redirects"`
+		CrawlMaxSize      int  `mapstructure:"crawl_max_size"`     // kilobytes of HTML parsed
+		ReportUnreachable bool `mapstructure:"report_unreachable"` // resolve_all: report addresses down while the others answer, also SSH hosts that stay down
+	} `mapstructure:"scan"`
+
+	RDAP struct {
+		Enabled   bool              `mapstructure:"enabled"`
+		Bootstrap string            `mapstructure:"bootstrap"`  // bootstrap registry URL, default: IANA
+		Servers   map[string]string `mapstructure:"servers"`    // tld: RDAP base URL
+		CacheTTL  int               `mapstructure:"cache_ttl"`  // hours
+		RateLimit int               `mapstructure:"rate_limit"` // requests per minute to one RDAP server
+		Timeout   int               `mapstructure:"timeout"`    // seconds
+	} `mapstructure:"rdap"`
+
+	CAA struct {
+		Enabled bool                `mapstructure:"enabled"`
+		Issuers map[string][]string `mapstructure:"issuers"` // part of the issuer organization or CN: CAA issuer domains
+	} `mapstructure:"caa"`
+
+	DANE struct {
+		Enabled bool `mapstructure:"enabled"` // validate every target against its TLSA records
+	} `mapstructure:"dane"`
+
+	CT struct {
+		Enabled bool           `mapstructure:"enabled"`
+		LogList string         `mapstructure:"log_list"` // log list file in the v3 JSON format browsers publish
+		Logs    []models.CTLog `mapstructure:"logs"`     // logs besides the ones in the list
+	} `mapstructure:"ct"`
+
+	Simulation struct {
+		Enabled  bool                   `mapstructure:"enabled"`
+		Profiles []models.ClientProfile `mapstructure:"profiles"` // default: every built-in client
+	} `mapstructure:"simulation"`
+
+	Lint struct {
+		Enabled  bool     `mapstructure:"enabled"`
+		Suppress []string `mapstructure:"suppress"` // lint names ignored for every target
+	} `mapstructure:"lint"`
+
+	Egress      models.Egress       `mapstructure:"egress"`
+	Targets     []models.Target     `mapstructure:"targets"`
+	MailDomains []models.MailDomain `mapstructure:"mail_domains"` // MTA-STS policy, TLS-RPT and MX certificates
+	Files       []models.FileTarget `mapstructure:"files"`
+	Kubernetes  []models.FileTarget `mapstructure:"kubernetes"` // kubeconfig files and manifests, passwords are not used
+	Images      []models.FileTarget `mapstructure:"images"`     // docker save, OCI layout or plain tar archives
+
+	SSH struct {
+		Files []models.FileTarget `mapstructure:"files"` // *-cert.pub files
+		Hosts []models.Target     `mapstructure:"hosts"` // servers presenting a host certificate, host:port
+	} `mapstructure:"ssh"`
+	Policies    []models.Policy     `mapstructure:"policies"`
+	TrustStores []models.TrustStore `mapstructure:"trust_stores"`
 }
 
 var C config
@@ -49,6 +119,20 @@ func ReadConfig(processCwdir string) {
 	viper.AddConfigPath(filepath.Join(processCwdir, "config"))
 	viper.AutomaticEnv()
 
+	// Defaults
+	viper.SetDefault("revocation.ocsp", true)
+	viper.SetDefault("revocation.crl", true)
+	viper.SetDefault("revocation.crl_cache_size", 64)
+	viper.SetDefault("revocation.timeout", 10)
+	viper.SetDefault("scan.http_timeout", 10)
+	viper.SetDefault("scan.http_max_size", 64)
+	viper.SetDefault("scan.crawl_max_hosts", 50)
+	viper.SetDefault("scan.crawl_max_redirects", 10)
+	viper.SetDefault("scan.crawl_max_size", 1024)
+	viper.SetDefault("rdap.cache_ttl", 24)
+	viper.SetDefault("rdap.rate_limit", 10)
+	viper.SetDefault("rdap.timeout", 10)
+
 	if err := viper.ReadInConfig(); err != nil {
 		logger.CLogger.Error("INIT: Cannot read config file.")
 	}
@@ -58,5 +142,89 @@ func ReadConfig(processCwdir string) {
 		os.Exit(1)
 	}
 
-	spew.Dump(C)
+	// A mistyped severity would silently turn a policy into a non-reportable one
+	for _, policy := range C.Policies {
+		if policy.Severity != "" && !models.ValidSeverity(policy.Severity) {
+			logger.CLogger.Errorf("INIT: Policy %q has unknown severity %q, use info, warning or critical.", policy.Name, policy.Severity)
+			os.Exit(1)
+		}
+	}
+
+	spew.Dump(redacted(C))
+}
+
+// Printed in place of secrets
+const mask = "***"
+
+// Copy of the config without passwords and proxy credentials, safe to print
+func redacted(c config) config {
+	if c.DB.Password != "" {
+		c.DB.Password = mask
+	}
+	if c.Mail.Password != "" {
+		c.Mail.Password = mask
+	}
+	c.Egress = redactEgress(c.Egress)
+
+	targets := func(list []models.Target) []models.Target {
+		list = append([]models.Target(nil), list...)
+		for i, target := range list {
+			if target.ClientCert != nil {
+				clientCert := *target.ClientCert
+				if clientCert.Password != "" {
+					clientCert.Password = mask
+				}
+				list[i].ClientCert = &clientCert
+			}
+			if target.Egress != nil {
+				e := redactEgress(*target.Egress)
+				list[i].Egress = &e
+			}
+		}
+		return list
+	}
+	c.Targets = targets(c.Targets)
+	c.SSH.Hosts = targets(c.SSH.Hosts)
+
+	c.MailDomains = append([]models.MailDomain(nil), c.MailDomains...)
+	for i, domain := range c.MailDomains {
+		if domain.Egress != nil {
+			e := redactEgress(*domain.Egress)
+			c.MailDomains[i].Egress = &e
+		}
+	}
+
+	files := func(list []models.FileTarget) []models.FileTarget {
+		list = append([]models.FileTarget(nil), list...)
+		for i, file := range list {
+			if len(file.Passwords) > 0 {
+				list[i].Passwords = []string{mask}
+			}
+		}
+		return list
+	}
+	c.Files = files(c.Files)
+	c.Kubernetes = files(c.Kubernetes)
+	c.Images = files(c.Images)
+	c.SSH.Files = files(c.SSH.Files)
+
+	c.TrustStores = append([]models.TrustStore(nil), c.TrustStores...)
+	for i, store := range c.TrustStores {
+		if len(store.Passwords) > 0 {
+			c.TrustStores[i].Passwords = []string{mask}
+		}
+	}
+	return c
+}
+
+// Proxy URLs keep the user name, the password is masked
+func redactEgress(e models.Egress) models.Egress {
+	for _, proxy := range []*string{&e.HTTPProxy, &e.SOCKS5Proxy} {
+		if u, err := url.Parse(*proxy); err == nil {
+			*proxy = u.Redacted()
+		} else if *proxy != "" {
+			*proxy = mask
+		}
+	}
+	return e
 }
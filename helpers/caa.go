@@ -0,0 +1,174 @@
+package helpers
+
+import (
+	"crypto/x509"
+	"net"
+	"sort"
+	"strings"
+
+	"sentinel/config"
+	"sentinel/models"
+	"sentinel/pkg/dnsquery"
+)
+
+// CAA issuer domains of well known CAs, keyed by a lower case part of the issuer organization or CN
+var caaIssuerDomains = map[string][]string{
+	"let's encrypt":                 {"letsencrypt.org"},
+	"digicert":                      {"digicert.com", "symantec.com", "geotrust.com", "rapidssl.com", "thawte.com", "digitalcertvalidation.com"},
+	"geotrust":                      {"digicert.com", "geotrust.com"},
+	"rapidssl":                      {"digicert.com", "rapidssl.com"},
+	"thawte":                        {"digicert.com", "thawte.com"},
+	"cloudflare":                    {"digicert.com", "letsencrypt.org", "pki.goog", "sectigo.com", "ssl.com"},
+	"sectigo":                       {"sectigo.com", "comodoca.com", "comodo.com", "usertrust.com", "trust-provider.com"},
+	"comodo":                        {"sectigo.com", "comodoca.com", "comodo.com"},
+	"zerossl":                       {"sectigo.com", "zerossl.com"},
+	"globalsign":                    {"globalsign.com"},
+	"google trust services":         {"pki.goog"},
+	"amazon":                        {"amazon.com", "amazontrust.com", "awstrust.com", "amazonaws.com"},
+	"godaddy":                       {"godaddy.com", "starfieldtech.com"},
+	"starfield":                     {"starfieldtech.com", "godaddy.com"},
+	"entrust":                       {"entrust.net", "affirmtrust.com"},
+	"buypass":                       {"buypass.com", "buypass.no"},
+	"ssl corporation":               {"ssl.com"},
+	"microsoft":                     {"microsoft.com"},
+	"identrust":                     {"identrust.com"},
+	"asseco":                        {"certum.pl", "certum.eu"},
+	"certum":                        {"certum.pl", "certum.eu"},
+	"actalis":                       {"actalis.it"},
+	"harica":                        {"harica.gr"},
+	"hellenic academic":             {"harica.gr"},
+	"trustasia":                     {"trustasia.com"},
+	"apple":                         {"apple.com"},
+	"telia":                         {"telia.com", "teliasonera.com"},
+	"swisssign":                     {"swisssign.com"},
+	"quovadis":                      {"quovadisglobal.com", "digicert.com"},
+	"network solutions":             {"networksolutions.com", "sectigo.com"},
+	"gandi":                         {"sectigo.com", "gandi.net"},
+	"trustwave":                     {"trustwave.com"},
+	"certainly":                     {"certainly.com"},
+	"e-tugra":                       {"e-tugra.com"},
+	"secom":                         {"secomtrust.net"},
+	"izenpe":                        {"izenpe.com"},
+	"firmaprofesional":              {"firmaprofesional.com"},
+	"naver":                         {"navercloudtrust.com"},
+	"government of the netherlands": {"pkioverheid.nl"},
+}
+
+// Compare the CAA records of the hostname with the issuer of the served certificate
+func CheckCAA(data *models.Log, target models.Target, hostname string, leaf *x509.Certificate) {
+	if net.ParseIP(hostname) != nil {
+		return
+	}
+
+	name, records, err := dnsquery.LookupCAA(target.DNSServer, hostname)
+	if err != nil {
+		data.CAAError = err.Error()
+		AddFinding(data, "caa", models.SeverityInfo, "CAA records of "+hostname+" could not be resolved: "+err.Error())
+		return
+	}
+	data.CAADomain = name
+	var presentation []string
+	for _, record := range records {
+		presentation = append(presentation, record.String())
+	}
+	data.CAARecords = strings.Join(presentation, ", ")
+	if len(records) == 0 {
+		return // no CAA records, every CA may issue
+	}
+
+	// issuewild governs wildcard names when present, issue everything else
+	tag := "issue"
+	if isWildcardFor(leaf, hostname) && hasTag(records, "issuewild") {
+		tag = "issuewild"
+	}
+
+	allowed := map[string]bool{}
+	forbidden := false
+	for _, record := range records {
+		switch {
+		case record.Tag == tag && record.IssuerDomain() == "":
+			forbidden = true
+		case record.Tag == tag:
+			allowed[record.IssuerDomain()] = true
+		case record.Critical() && record.Tag != "issue" && record.Tag != "issuewild" && record.Tag != "iodef":
+			AddFinding(data, "caa", models.SeverityWarning, "CAA record "+record.String()+" at "+name+" has an unknown critical tag, CAs must refuse to issue.")
+		}
+	}
+
+	issuer := leaf.Issuer.CommonName
+	if len(leaf.Issuer.Organization) > 0 {
+		issuer = leaf.Issuer.Organization[0]
+	}
+	switch {
+	case len(allowed) == 0 && forbidden:
+		AddFinding(data, "caa", models.SeverityCritical, "CAA records at "+name+" forbid certificate issuance for "+hostname+".")
+	case len(allowed) == 0:
+		// Only iodef or unknown tags, issuance is not restricted
+	default:
+		domains := IssuerCAADomains(leaf)
+		if len(domains) == 0 {
+			AddFinding(data, "caa", models.SeverityInfo, "Issuer "+issuer+" has no known CAA identifier, CAA records at "+name+" could not be compared.")
+			return
+		}
+		for _, domain := range domains {
+			if allowed[domain] {
+				return
+			}
+		}
+		AddFinding(data, "caa", models.SeverityWarning, "CAA records at "+name+" allow only "+strings.Join(sortedSet(allowed), ", ")+", a renewal through the current issuer "+issuer+" ("+strings.Join(domains, ", ")+") would fail.")
+	}
+}
+
+// CAA issuer domains the certificate issuer is known by in the configured and built-in mappings, sorted
+func IssuerCAADomains(cert *x509.Certificate) []string {
+	names := append([]string{cert.Issuer.CommonName}, cert.Issuer.Organization...)
+	seen := map[string]bool{}
+	var domains []string
+	match := func(mapping map[string][]string) {
+		for key, values := range mapping {
+			for _, name := range names {
+				if name != "" && strings.Contains(strings.ToLower(name), strings.ToLower(key)) {
+					for _, value := range values {
+						value = strings.ToLower(value)
+						if !seen[value] {
+							seen[value] = true
+							domains = append(domains, value)
+						}
+					}
+				}
+			}
+		}
+	}
+	match(config.C.CAA.Issuers)
+	match(caaIssuerDomains)
+	sort.Strings(domains)
+	return domains
+}
+
+func isWildcardFor(cert *x509.Certificate, hostname string) bool {
+	_, parent, _ := strings.Cut(hostname, ".")
+	for _, name := range cert.DNSNames {
+		if strings.EqualFold(name, "*."+parent) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasTag(records []dnsquery.CAA, tag string) bool {
+	for _, record := range records {
+		if record.Tag == tag {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedSet(set map[string]bool) []string {
+	var list []string
+	for item := range set {
+		list = append(list, item)
+	}
+	sort.Strings(list)
+	return list
+}
@@ -0,0 +1,191 @@
+package helpers
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"strconv"
+	"strings"
+
+	"sentinel/models"
+)
+
+// TLS Feature extension (RFC 7633), status_request means OCSP must-staple
+var oidTLSFeature = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 1, 24}
+
+const tlsFeatureStatusRequest = 5
+
+var keyUsageNames = []struct {
+	usage x509.KeyUsage
+	name  string
+}{
+	{x509.KeyUsageDigitalSignature, "Digital Signature"},
+	{x509.KeyUsageContentCommitment, "Content Commitment"},
+	{x509.KeyUsageKeyEncipherment, "Key Encipherment"},
+	{x509.KeyUsageDataEncipherment, "Data Encipherment"},
+	{x509.KeyUsageKeyAgreement, "Key Agreement"},
+	{x509.KeyUsageCertSign, "Certificate Sign"},
+	{x509.KeyUsageCRLSign, "CRL Sign"},
+	{x509.KeyUsageEncipherOnly, "Encipher Only"},
+	{x509.KeyUsageDecipherOnly, "Decipher Only"},
+}
+
+var extKeyUsageNames = map[x509.ExtKeyUsage]string{
+	x509.ExtKeyUsageAny:                            "Any",
+	x509.ExtKeyUsageServerAuth:                     "Server Authentication",
+	x509.ExtKeyUsageClientAuth:                     "Client Authentication",
+	x509.ExtKeyUsageCodeSigning:                    "Code Signing",
+	x509.ExtKeyUsageEmailProtection:                "Email Protection",
+	x509.ExtKeyUsageIPSECEndSystem:                 "IPSEC End System",
+	x509.ExtKeyUsageIPSECTunnel:                    "IPSEC Tunnel",
+	x509.ExtKeyUsageIPSECUser:                      "IPSEC User",
+	x509.ExtKeyUsageTimeStamping:                   "Time Stamping",
+	x509.ExtKeyUsageOCSPSigning:                    "OCSP Signing",
+	x509.ExtKeyUsageMicrosoftServerGatedCrypto:     "Microsoft Server Gated Crypto",
+	x509.ExtKeyUsageNetscapeServerGatedCrypto:      "Netscape Server Gated Crypto",
+	x509.ExtKeyUsageMicrosoftCommercialCodeSigning: "Microsoft Commercial Code Signing",
+	x509.ExtKeyUsageMicrosoftKernelCodeSigning:     "Microsoft Kernel Code Signing",
+}
+
+// Build the certificate part of a log from the given certificate
+func CertificateToLog(cert *x509.Certificate) models.Log {
+	keyAlgorithm, keySize := PublicKeyInfo(cert)
+	sha1Sum := sha1.Sum(cert.Raw)
+	sha256Sum := sha256.Sum256(cert.Raw)
+
+	var ipAddresses []string
+	for _, ip := range cert.IPAddresses {
+		ipAddresses = append(ipAddresses, ip.String())
+	}
+
+	var policies []string
+	for _, oid := range cert.PolicyIdentifiers {
+		policies = append(policies, oid.String())
+	}
+
+	return models.Log{
+		Version:            cert.Version,
+		SerialNumber:       cert.SerialNumber.String(),
+		Subject:            cert.Subject.String(),
+		IssuerSubject:      cert.Issuer.String(),
+		CommonName:         cert.Subject.CommonName,
+		Organization:       ArrayToString(cert.Subject.Organization),
+		IssuedOn:           cert.NotBefore,
+		ExpiresOn:          cert.NotAfter,
+		CertificateData:    CertificatesToPEM(cert),
+		SignatureAlgorithm: cert.SignatureAlgorithm.String(),
+		SubjectKeyID:       hex.EncodeToString(cert.SubjectKeyId),
+		AuthorityKeyID:     hex.EncodeToString(cert.AuthorityKeyId),
+		IsCA:               cert.IsCA,
+		Issuer:             cert.Issuer.CommonName,
+		IsExpired:          cert.NotAfter.Before(cert.NotBefore),
+		DNSNames:           strings.Join(cert.DNSNames, ", "),
+		IPAddresses:        strings.Join(ipAddresses, ", "),
+		EmailAddresses:     strings.Join(cert.EmailAddresses, ", "),
+		PublicKeyAlgorithm: keyAlgorithm,
+		PublicKeySize:      keySize,
+		FingerprintSHA1:    Fingerprint(sha1Sum[:]),
+		FingerprintSHA256:  Fingerprint(sha256Sum[:]),
+		SPKISHA256:         SPKIHash(cert),
+		KeyUsage:           strings.Join(KeyUsageToStrings(cert.KeyUsage), ", "),
+		ExtKeyUsage:        strings.Join(ExtKeyUsageToStrings(cert), ", "),
+		IssuingCertURLs:    strings.Join(cert.IssuingCertificateURL, ", "),
+		OCSPServers:        strings.Join(cert.OCSPServer, ", "),
+		CRLDistribution:    strings.Join(cert.CRLDistributionPoints, ", "),
+		PolicyOIDs:         strings.Join(policies, ", "),
+		MustStaple:         IsMustStaple(cert),
+	}
+}
+
+// Public key algorithm and size in bits
+func PublicKeyInfo(cert *x509.Certificate) (string, int) {
+	switch key := cert.PublicKey.(type) {
+	case *rsa.PublicKey:
+		return "RSA", key.N.BitLen()
+	case *ecdsa.PublicKey:
+		return "ECDSA", key.Curve.Params().BitSize
+	case ed25519.PublicKey:
+		return "Ed25519", 256
+	default:
+		return cert.PublicKeyAlgorithm.String(), 0
+	}
+}
+
+// Fingerprint formats a digest as colon separated upper case hex (AB:CD:...)
+func Fingerprint(digest []byte) string {
+	parts := make([]string, len(digest))
+	for i, v := range digest {
+		parts[i] = strings.ToUpper(hex.EncodeToString([]byte{v}))
+	}
+	return strings.Join(parts, ":")
+}
+
+// Base64 SHA-256 hash of the SubjectPublicKeyInfo (pin-sha256 format)
+func SPKIHash(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// Key usage names of the given bit set
+func KeyUsageToStrings(usage x509.KeyUsage) []string {
+	var names []string
+	for _, v := range keyUsageNames {
+		if usage&v.usage != 0 {
+			names = append(names, v.name)
+		}
+	}
+	return names
+}
+
+// Extended key usage names, unknown usages are returned as OIDs
+func ExtKeyUsageToStrings(cert *x509.Certificate) []string {
+	var names []string
+	for _, usage := range cert.ExtKeyUsage {
+		name, ok := extKeyUsageNames[usage]
+		if !ok {
+			// Recognized by crypto/x509 but not named here yet
+			name = "ExtKeyUsage(" + strconv.Itoa(int(usage)) + ")"
+		}
+		names = append(names, name)
+	}
+	for _, oid := range cert.UnknownExtKeyUsage {
+		names = append(names, oid.String())
+	}
+	return names
+}
+
+// Check TLS Feature extension for status_request
+func IsMustStaple(cert *x509.Certificate) bool {
+	for _, ext := range cert.Extensions {
+		if !ext.Id.Equal(oidTLSFeature) {
+			continue
+		}
+		var features []int
+		if _, err := asn1.Unmarshal(ext.Value, &features); err != nil {
+			return false
+		}
+		for _, feature := range features {
+			if feature == tlsFeatureStatusRequest {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Encode certificates as concatenated PEM blocks
+func CertificatesToPEM(certs ...*x509.Certificate) string {
+	var buf bytes.Buffer
+	for _, cert := range certs {
+		pem.Encode(&buf, &pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})
+	}
+	return buf.String()
+}
@@ -0,0 +1,32 @@
+package helpers
+
+import (
+	"crypto/x509"
+	"encoding/asn1"
+	"strings"
+	"testing"
+)
+
+func TestExtKeyUsageToStrings(t *testing.T) {
+	tests := []struct {
+		name string
+		cert *x509.Certificate
+		want string
+	}{
+		{"none", &x509.Certificate{}, ""},
+		{"known usages", &x509.Certificate{ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth}},
+			"Server Authentication, Client Authentication"},
+		{"unknown usages as OIDs", &x509.Certificate{
+			ExtKeyUsage:        []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+			UnknownExtKeyUsage: []asn1.ObjectIdentifier{{1, 3, 6, 1, 4, 1, 311, 10, 3, 12}, {2, 23, 133, 8, 1}},
+		}, "Server Authentication, 1.3.6.1.4.1.311.10.3.12, 2.23.133.8.1"},
+		{"usage without a name", &x509.Certificate{ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsage(99)}}, "ExtKeyUsage(99)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := strings.Join(ExtKeyUsageToStrings(tt.cert), ", "); got != tt.want {
+				t.Errorf("names = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package helpers
+
+import (
+	"crypto/x509"
+	"time"
+
+	"sentinel/models"
+)
+
+// Chain names
+const (
+	ChainPresented = "presented"
+	ChainVerified  = "verified"
+)
+
+// Convert x509 chain to chain certificate models
+func ChainCertificates(certs []*x509.Certificate, chain string) []models.Certificate {
+	var list []models.Certificate
+	for i, cert := range certs {
+		list = append(list, models.Certificate{
+			Chain:        chain,
+			Position:     i,
+			Role:         chainRole(i, cert),
+			Subject:      cert.Subject.String(),
+			Issuer:       cert.Issuer.String(),
+			CommonName:   cert.Subject.CommonName,
+			SerialNumber: cert.SerialNumber.String(),
+			IssuedOn:     cert.NotBefore,
+			ExpiresOn:    cert.NotAfter,
+			IsCA:         cert.IsCA,
+			DaysLeft:     DaysUntil(cert.NotAfter),
+		})
+	}
+	return list
+}
+
+// Verify the presented chain against the system roots and return the built chain
+func VerifyChain(certs []*x509.Certificate) ([]*x509.Certificate, error) {
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+
+	// DNSName is left empty, hostname mismatches must not hide the chain
+	chains, err := certs[0].Verify(x509.VerifyOptions{
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return chains[0], nil
+}
+
+// Find the chain element which expires first
+func EarliestExpiry(chain []models.Certificate) (models.Certificate, bool) {
+	var earliest models.Certificate
+	found := false
+	for _, c := range chain {
+		if !found || c.ExpiresOn.Before(earliest.ExpiresOn) {
+			earliest = c
+			found = true
+		}
+	}
+	return earliest, found
+}
+
+// Find an element of the chain by position, the returned pointer updates the chain
+func ChainElement(chain []models.Certificate, name string, position int) *models.Certificate {
+	for i := range chain {
+		if chain[i].Chain == name && chain[i].Position == position {
+			return &chain[i]
+		}
+	}
+	return &models.Certificate{Chain: name, Position: position}
+}
+
+// Days left until the given time
+func DaysUntil(t time.Time) int {
+	return int(time.Until(t).Hours() / 24)
+}
+
+func chainRole(position int, cert *x509.Certificate) string {
+	if position == 0 {
+		return "leaf"
+	}
+	if cert.IsCA && cert.Subject.String() == cert.Issuer.String() {
+		return "root"
+	}
+	return "intermediate"
+}
@@ -0,0 +1,122 @@
+package helpers
+
+import (
+	"crypto"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"sentinel/models"
+	"sentinel/pkg/certfile"
+)
+
+// Load the client certificate and key of the target from PEM files or a PKCS#12 bundle
+func LoadClientCertificate(c models.ClientCert) (tls.Certificate, error) {
+	if c.PKCS12File != "" {
+		if c.CertFile != "" || c.KeyFile != "" {
+			return tls.Certificate{}, errors.New("pkcs12_file cannot be combined with cert_file and key_file")
+		}
+		return loadPKCS12(c.PKCS12File, c.Password)
+	}
+	if c.CertFile == "" || c.KeyFile == "" {
+		return tls.Certificate{}, errors.New("client certificate needs cert_file and key_file or pkcs12_file")
+	}
+	cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	return cert, nil
+}
+
+// PKCS#12 bundles may carry the chain in any order, the certificate matching the key is the leaf
+func loadPKCS12(file string, password string) (tls.Certificate, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	certs, key, err := certfile.DecodePKCS12(data, password)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("%s: %w", file, err)
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return tls.Certificate{}, fmt.Errorf("%s: bundle has no private key", file)
+	}
+
+	for i, leaf := range certs {
+		if !certfile.KeyMatches(leaf, signer.Public()) {
+			continue
+		}
+		cert := tls.Certificate{PrivateKey: key, Leaf: leaf, Certificate: [][]byte{leaf.Raw}}
+		for j, intermediate := range certs {
+			if j != i {
+				cert.Certificate = append(cert.Certificate, intermediate.Raw)
+			}
+		}
+		return cert, nil
+	}
+	return tls.Certificate{}, fmt.Errorf("%s: no certificate in the bundle matches the private key", file)
+}
+
+// Check the expiry of the client certificate the target presents, the result is reported on its own
+func CheckClientCertificate(target models.Target, day int) (bool, *models.Log) {
+	host, portString, _ := net.SplitHostPort(target.Address)
+	port, _ := strconv.Atoi(portString)
+
+	if day <= 0 {
+		day = 30
+	}
+
+	// Intermediates shipped with the client certificate expire just the same
+	var certs []*x509.Certificate
+	clientCert, err := LoadClientCertificate(*target.ClientCert)
+	for _, raw := range clientCert.Certificate {
+		cert, parseErr := x509.ParseCertificate(raw)
+		if parseErr != nil {
+			err = parseErr
+			break
+		}
+		certs = append(certs, cert)
+	}
+	if err != nil {
+		data := &models.Log{Domain: host, Port: port, Source: "client", Tags: strings.Join(target.Tags, ", ")}
+		data.Message = "Client certificate could not be loaded: " + err.Error()
+		data.Status = 1
+		AddFinding(data, "client_certificate", models.SeverityCritical, data.Message)
+		return true, data
+	}
+
+	chain := ChainCertificates(certs, ChainPresented)
+	earliest, _ := EarliestExpiry(chain)
+	daysLeft := DaysUntil(earliest.ExpiresOn)
+
+	data := CertificateToLog(certs[0])
+	data.Domain = host
+	data.Port = port
+	data.Source = "client"
+	data.Tags = strings.Join(target.Tags, ", ")
+	data.ChainData = CertificatesToPEM(certs...)
+	data.Chain = chain
+	data.EffectiveExpiresOn = earliest.ExpiresOn
+	data.ExpiringElement = earliest.Label()
+
+	data.Message = fmt.Sprintf("Client certificate will expire in %d days.", daysLeft)
+	if earliest.Position != 0 {
+		data.Message = fmt.Sprintf("Client certificate chain will expire in %d days, %s expires first.", daysLeft, earliest.Label())
+	}
+
+	isExpired := daysLeft < day
+	if isExpired {
+		data.Status = 1
+	}
+	if earliest.ExpiresOn.Before(time.Now()) {
+		AddFinding(&data, "client_certificate", models.SeverityCritical, "Client certificate "+earliest.Label()+" has expired, servers will reject the handshake.")
+	}
+	return isExpired, &data
+}
@@ -0,0 +1,206 @@
+package helpers
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"sentinel/config"
+	"sentinel/logger"
+	"sentinel/models"
+	"sentinel/pkg/crawl"
+)
+
+// Tag of the targets derived from a crawl, policies and trust stores can select them with it
+const DependencyTag = "dependency"
+
+// Crawl the landing page of the target and check the certificate of every host it depends on.
+// Parent logs get a finding for each dependent host in trouble, the derived logs are returned.
+func CheckDependencies(target models.Target, day int, parents []models.Log) (bool, []models.Log) {
+	dial, err := TargetDialer(target)
+	if err != nil {
+		logger.CLogger.Error("Invalid egress configuration for "+target.Address+":", err)
+		return false, nil
+	}
+	if day <= 0 {
+		day = 30
+	}
+	timeout := time.Duration(config.C.Scan.HTTPTimeout) * time.Second
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	// The landing page is served from the connect IP of the target, the other hosts resolve through its DNS server
+	landing := withPort(TargetHostHeader(target), "443")
+	client := &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, network, address string) (net.Conn, error) {
+				hop := models.Target{Address: address, DNSServer: target.DNSServer}
+				if strings.EqualFold(address, landing) {
+					hop.ConnectIP = target.ConnectIP
+				}
+				dialAddress, err := DialAddress(hop)
+				if err != nil {
+					return nil, err
+				}
+				return dial(dialAddress)
+			},
+			// Certificates are checked separately, an invalid one must not stop the crawl
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		},
+	}
+	defer client.CloseIdleConnections()
+
+	start := "https://" + TargetHostHeader(target) + "/"
+	result, err := crawl.Crawl(client, start, config.C.Scan.CrawlMaxRedirects, int64(config.C.Scan.CrawlMaxSize)<<10)
+	crawlError := ""
+	if err != nil {
+		crawlError = err.Error()
+		logger.CLogger.Error("Failed to crawl "+start+":", err)
+	}
+
+	hosts := dependentHosts(target, result)
+	for i := range parents {
+		parents[i].RedirectChain = strings.Join(result.Redirects, " -> ")
+		parents[i].DependentHosts = strings.Join(hosts, ", ")
+		parents[i].CrawlError = crawlError
+	}
+
+	isReported := false
+	var derived []models.Log
+	for _, host := range hosts {
+		// Policies and trust stores of the parent are not meant for third party hosts
+		dependency := models.Target{
+			Address:   host,
+			Tags:      []string{DependencyTag},
+			DNSServer: target.DNSServer,
+			Egress:    target.Egress,
+			Parent:    target.Address,
+		}
+		isOK, logs := CheckTarget(dependency, day)
+		if len(logs) == 0 {
+			hostname, portString, _ := net.SplitHostPort(host)
+			port, _ := strconv.Atoi(portString)
+			logs = []models.Log{{
+				Domain:  hostname,
+				Port:    port,
+				Source:  "server",
+				Tags:    DependencyTag,
+				Message: "Connection to dependent host " + host + " failed.",
+				Status:  2,
+			}}
+		}
+
+		for _, data := range logs {
+			data.Parent = target.Address
+			severity, message := dependencyFinding(host, data, day)
+			if severity != "" {
+				for i := range parents {
+					AddFinding(&parents[i], "dependency", severity, message)
+				}
+			}
+			derived = append(derived, data)
+		}
+		isReported = isReported || isOK
+	}
+
+	// A dependency in trouble makes the parent reportable
+	for i := range parents {
+		if HasReportableFindings(&parents[i]) {
+			parents[i].Status = 1
+			isReported = true
+		}
+	}
+	return isReported, derived
+}
+
+// Redirect targets on other hosts and the resource hosts of the landing page, without the target itself
+func dependentHosts(target models.Target, result crawl.Result) []string {
+	// The target is known by its address, host header, server name and the host it finally redirects to
+	own := map[string]bool{strings.ToLower(target.Address): true}
+	own[withPort(strings.ToLower(TargetHostHeader(target)), "443")] = true
+	if _, port, err := SplitTarget(target); err == nil {
+		own[net.JoinHostPort(strings.ToLower(TargetServerName(target)), port)] = true
+	}
+	if len(result.Redirects) > 0 {
+		if host := httpsHost(result.Redirects[len(result.Redirects)-1]); host != "" {
+			own[host] = true
+		}
+	}
+
+	var hosts []string
+	add := func(host string) {
+		if own[host] || len(hosts) >= config.C.Scan.CrawlMaxHosts {
+			return
+		}
+		own[host] = true
+		hosts = append(hosts, host)
+	}
+
+	for _, redirect := range result.Redirects[1:] {
+		if host := httpsHost(redirect); host != "" {
+			add(host)
+		}
+	}
+	for _, host := range result.Hosts {
+		add(host)
+	}
+	return hosts
+}
+
+// host:port of an HTTPS URL, empty for other schemes
+func httpsHost(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.Scheme != "https" || u.Hostname() == "" {
+		return ""
+	}
+	return withPort(strings.ToLower(u.Hostname()), u.Port())
+}
+
+// Host with the port, 443 when none is given
+func withPort(host string, port string) string {
+	if _, _, err := net.SplitHostPort(host); err == nil {
+		return host
+	}
+	if port == "" {
+		port = "443"
+	}
+	return net.JoinHostPort(host, port)
+}
+
+// Finding added to the parent for the state of a dependent host, none when it is healthy.
+// The message carries the reportable findings of the host and its expiry when it is due.
+func dependencyFinding(host string, data models.Log, day int) (string, string) {
+	if data.Status == 2 {
+		return models.SeverityInfo, "Dependent host " + host + " could not be checked."
+	}
+
+	severity := ""
+	var messages []string
+	if !data.EffectiveExpiresOn.IsZero() && DaysUntil(data.EffectiveExpiresOn) < day {
+		severity = models.SeverityWarning
+		if data.EffectiveExpiresOn.Before(time.Now()) {
+			severity = models.SeverityCritical
+		}
+		messages = append(messages, data.Message)
+	}
+	for _, finding := range data.Findings {
+		if finding.Severity != models.SeverityWarning && finding.Severity != models.SeverityCritical {
+			continue
+		}
+		if severity != models.SeverityCritical {
+			severity = finding.Severity
+		}
+		messages = append(messages, finding.Message)
+	}
+	if len(messages) == 0 {
+		return "", ""
+	}
+	return severity, fmt.Sprintf("Dependent host %s: %s", host, strings.Join(messages, " "))
+}
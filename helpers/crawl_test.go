@@ -0,0 +1,48 @@
+package helpers
+
+import (
+	"strings"
+	"testing"
+
+	"sentinel/config"
+	"sentinel/models"
+	"sentinel/pkg/crawl"
+)
+
+func TestDependentHosts(t *testing.T) {
+	config.C.Scan.CrawlMaxHosts = 3
+	defer func() { config.C.Scan.CrawlMaxHosts = 0 }()
+
+	tests := []struct {
+		name   string
+		target models.Target
+		result crawl.Result
+		want   []string
+	}{
+		{"target hosts skipped",
+			models.Target{Address: "203.0.113.10:443", HostHeader: "WWW.example.com", ServerName: "edge.example.com"},
+			crawl.Result{Redirects: []string{"https://www.example.com/"}, Hosts: []string{"www.example.com:443", "edge.example.com:443", "cdn.example.net:443"}},
+			[]string{"cdn.example.net:443"}},
+		{"server name on the target port",
+			models.Target{Address: "example.com:8443"},
+			crawl.Result{Redirects: []string{"https://example.com:8443/"}, Hosts: []string{"example.com:8443", "example.com:443"}},
+			[]string{"example.com:443"}},
+		{"final redirect host skipped",
+			models.Target{Address: "example.com:443"},
+			crawl.Result{Redirects: []string{"https://example.com/", "https://login.example.com/", "https://www.example.com/home"},
+				Hosts: []string{"www.example.com:443", "cdn.example.net:443"}},
+			[]string{"login.example.com:443", "cdn.example.net:443"}},
+		{"limited to the configured count",
+			models.Target{Address: "example.com:443"},
+			crawl.Result{Redirects: []string{"https://example.com/"}, Hosts: []string{"a.example.net:443", "b.example.net:443", "c.example.net:443", "d.example.net:443"}},
+			[]string{"a.example.net:443", "b.example.net:443", "c.example.net:443"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := dependentHosts(tt.target, tt.result)
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("hosts = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package helpers
+
+import (
+	"crypto/x509"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"sentinel/logger"
+	"sentinel/models"
+	"sentinel/pkg/ct"
+	"sentinel/pkg/revocation"
+)
+
+// CT logs SCTs are verified against by log ID, loaded at startup
+var CTLogs map[[32]byte]ct.Log
+
+// Load the log list file and the configured logs
+func LoadCTLogs(listFile string, configured []models.CTLog) map[[32]byte]ct.Log {
+	logs := map[[32]byte]ct.Log{}
+	if listFile != "" {
+		data, err := os.ReadFile(listFile)
+		if err == nil {
+			var list []ct.Log
+			if list, err = ct.ParseLogList(data); err == nil {
+				for _, log := range list {
+					logs[log.ID] = log
+				}
+			}
+		}
+		if err != nil {
+			logger.CLogger.Error("INIT: Failed to load CT log list "+listFile+":", err)
+		}
+	}
+
+	for _, entry := range configured {
+		log, err := ctLog(entry)
+		if err != nil {
+			logger.CLogger.Error("INIT: Invalid CT log "+entry.Name+":", err)
+			continue
+		}
+		logs[log.ID] = log
+	}
+	if len(logs) == 0 {
+		logger.CLogger.Warn("INIT: No CT logs loaded, the CT check is skipped. Check ct.log_list and ct.logs.")
+		return logs
+	}
+	logger.CLogger.Info(fmt.Sprintf("INIT: %d CT logs loaded.", len(logs)))
+	return logs
+}
+
+func ctLog(entry models.CTLog) (ct.Log, error) {
+	log, err := ct.NewLog(entry.Name, entry.Operator, entry.Key)
+	if err != nil {
+		return log, err
+	}
+	for _, t := range []struct {
+		value  string
+		target *time.Time
+	}{{entry.Start, &log.Start}, {entry.End, &log.End}, {entry.Retired, &log.Retired}} {
+		if t.value == "" {
+			continue
+		}
+		if *t.target, err = time.Parse(time.RFC3339, t.value); err != nil {
+			return log, err
+		}
+	}
+	return log, nil
+}
+
+// Verify the SCTs of the leaf from the certificate, the TLS extension and the stapled OCSP response.
+// Only publicly trusted chains are held to the browser CT policy, private CAs do not log.
+func CheckCT(data *models.Log, certs []*x509.Certificate, verified []*x509.Certificate, tlsSCTs [][]byte, staple []byte) {
+	leaf := certs[0]
+	issuer := revocation.FindIssuer(leaf, verified, certs)
+
+	scts, err := ct.Collect(leaf, tlsSCTs, staple)
+	if err != nil {
+		AddFinding(data, "ct", models.SeverityInfo, "Some SCTs could not be parsed: "+err.Error())
+	}
+
+	var valid []ct.SCT
+	var summary []string
+	for _, sct := range scts {
+		name, severity, problem := verifySCT(sct, leaf, issuer)
+		if problem == "" {
+			valid = append(valid, sct)
+			summary = append(summary, fmt.Sprintf("%s %s %s: valid", name, sct.Source, sct.Timestamp.Format("2006-01-02")))
+			continue
+		}
+		AddFinding(data, "ct", severity, "SCT from "+name+" ("+sct.Source+") "+problem+".")
+		summary = append(summary, fmt.Sprintf("%s %s %s: %s", name, sct.Source, sct.Timestamp.Format("2006-01-02"), problem))
+	}
+	data.SCTs = strings.Join(summary, "; ")
+	data.SCTValid = len(valid)
+
+	data.CTError = ct.CheckPolicy(leaf, valid, CTLogs)
+	data.CTCompliant = data.CTError == ""
+	if !data.CTCompliant && verified != nil {
+		AddFinding(data, "ct", models.SeverityCritical, "Certificate does not meet the CT policy browsers enforce: "+data.CTError+".")
+	}
+}
+
+// Log name of the SCT, and the severity and reason when it does not count
+func verifySCT(sct ct.SCT, leaf *x509.Certificate, issuer *x509.Certificate) (string, string, string) {
+	log, known := CTLogs[sct.LogID]
+	if !known {
+		return fmt.Sprintf("%x", sct.LogID[:8]), models.SeverityInfo, "comes from an unknown log"
+	}
+	if err := sct.Verify(log.Key, leaf, issuer); err != nil {
+		return log.Name, models.SeverityWarning, "does not verify: " + err.Error()
+	}
+	if reason := log.Rejects(sct, leaf); reason != "" {
+		return log.Name, models.SeverityInfo, "does not count: " + reason
+	}
+	return log.Name, "", ""
+}
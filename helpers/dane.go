@@ -0,0 +1,80 @@
+package helpers
+
+import (
+	"crypto/x509"
+	"fmt"
+	"net"
+	"strings"
+
+	"sentinel/models"
+	"sentinel/pkg/dane"
+	"sentinel/pkg/dnsquery"
+)
+
+// Validate the presented chain against the TLSA records of _port._tcp.hostname (RFC 6698, 7671)
+func CheckDANE(data *models.Log, target models.Target, hostname string, port string, certs []*x509.Certificate, verified []*x509.Certificate, day int) {
+	if net.ParseIP(hostname) != nil {
+		return
+	}
+
+	name := "_" + port + "._tcp." + hostname
+	records, authenticated, err := dnsquery.LookupTLSA(target.DNSServer, hostname, port)
+	if err != nil {
+		data.TLSAError = err.Error()
+		AddFinding(data, "dane", models.SeverityInfo, "TLSA records of "+name+" could not be resolved: "+err.Error())
+		return
+	}
+	data.TLSADNSSEC = authenticated
+	var presentation []string
+	for _, record := range records {
+		presentation = append(presentation, record.String())
+	}
+	data.TLSARecords = strings.Join(presentation, ", ")
+
+	result := dane.Validate(records, certs, verified, hostname)
+	data.TLSAStatus = result.Status
+	if result.Status == dane.StatusNone && len(result.Unusable) == 0 {
+		return
+	}
+
+	// DANE clients only use records from a validated DNSSEC answer
+	if !authenticated {
+		AddFinding(data, "dane", models.SeverityWarning, "TLSA records of "+name+" are not DNSSEC validated, DANE clients ignore them.")
+	}
+	for _, record := range result.Unusable {
+		AddFinding(data, "dane", models.SeverityInfo, "TLSA record "+record.String()+" at "+name+" has unknown parameters and is ignored.")
+	}
+
+	switch result.Status {
+	case dane.StatusNone:
+		AddFinding(data, "dane", models.SeverityWarning, "TLSA records at "+name+" are all unusable, DANE clients treat the endpoint as unauthenticated.")
+	case dane.StatusMismatch:
+		AddFinding(data, "dane", models.SeverityCritical, "No TLSA record at "+name+" matches the presented chain, DANE clients will refuse the connection.")
+	case dane.StatusValid:
+		checkDANERollover(data, name, records, certs, day)
+	}
+}
+
+// A chain pinned only by end entity records breaks with the next certificate unless its key is published too
+func checkDANERollover(data *models.Log, name string, records []dnsquery.TLSA, certs []*x509.Certificate, day int) {
+	spki := false
+	for _, record := range records {
+		if record.Usage != dane.UsageDANEEE && record.Usage != dane.UsagePKIXEE {
+			return // trust anchor records survive a renewal from the same CA
+		}
+		if !dane.Matches(record, certs[0]) {
+			return // a record for the next key is already published
+		}
+		spki = spki || record.Selector == 1
+	}
+
+	daysLeft := DaysUntil(certs[0].NotAfter)
+	if daysLeft >= day {
+		return
+	}
+	message := fmt.Sprintf("Certificate expires in %d days and every TLSA record at %s pins it, publish a record for the next certificate before the renewal.", daysLeft, name)
+	if spki {
+		message = fmt.Sprintf("Certificate expires in %d days and every TLSA record at %s pins its key, the renewal must reuse the key or a record for the next key must be published first.", daysLeft, name)
+	}
+	AddFinding(data, "dane", models.SeverityWarning, message)
+}
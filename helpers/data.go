@@ -1,8 +1,22 @@
 package helpers
 
+import (
+	"sentinel/config"
+	"sentinel/models"
+)
+
 var DomainList = []string{
 	// domain list here ...
 	// "domain.com:port",
 	// "google.com:443",
 	"geekforgeeks.org:443",
 }
+
+// Targets from the config file followed by the domain list
+func Targets() []models.Target {
+	targets := append([]models.Target{}, config.C.Targets...)
+	for _, domain := range DomainList {
+		targets = append(targets, models.Target{Address: domain})
+	}
+	return targets
+}
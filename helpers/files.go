@@ -0,0 +1,252 @@
+package helpers
+
+import (
+	"crypto"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"sentinel/logger"
+	"sentinel/models"
+	"sentinel/pkg/certfile"
+)
+
+// Bigger files are not certificates or keys
+const maxCertFileSize = 4 << 20
+
+// Check every certificate stored under the file target and whether its private key matches
+func CheckFileTarget(target models.FileTarget, day int) (bool, []models.Log) {
+	if day <= 0 {
+		day = 30
+	}
+
+	bundles, logs := parseCertificateFiles(FileTargetFiles(target), os.ReadFile, FileTargetPasswords(target), target)
+	isReported := len(logs) > 0
+	for _, data := range checkCertificateFiles(bundles, target, day) {
+		isReported = isReported || data.Status == 1
+		logs = append(logs, data)
+	}
+	return isReported, logs
+}
+
+// Parse the files holding certificates or keys, a file that cannot be opened is reported on its own
+func parseCertificateFiles(files []string, read func(string) ([]byte, error), passwords []string, target models.FileTarget) (map[string]certfile.Bundle, []models.Log) {
+	bundles := map[string]certfile.Bundle{}
+	var logs []models.Log
+	for _, file := range files {
+		data, err := read(file)
+		if err != nil {
+			logger.CLogger.Error("Failed to read "+file+":", err)
+			continue
+		}
+		bundle, err := certfile.Parse(data, passwords)
+		if errors.Is(err, certfile.ErrUnrecognized) {
+			continue
+		}
+		if err != nil {
+			// Most likely a PKCS#12 bundle none of the passwords opened or a keystore entry we cannot read
+			failed := models.Log{
+				Source:  "file",
+				Path:    file,
+				Tags:    strings.Join(target.Tags, ", "),
+				Message: "File " + file + " could not be read: " + err.Error(),
+				Status:  1,
+			}
+			AddFinding(&failed, "file", models.SeverityWarning, failed.Message)
+			logs = append(logs, failed)
+			if len(bundle.Entries) == 0 {
+				continue
+			}
+		}
+		bundles[file] = bundle
+	}
+	return bundles, logs
+}
+
+// Logs of every certificate in the parsed files
+func checkCertificateFiles(bundles map[string]certfile.Bundle, target models.FileTarget, day int) []models.Log {
+	var logs []models.Log
+	for _, file := range sortedKeys(bundles) {
+		logs = append(logs, checkCertificateFile(file, bundles, target, day)...)
+	}
+	return logs
+}
+
+// Configured passwords followed by the ones read from password files
+func FileTargetPasswords(target models.FileTarget) []string {
+	passwords := append([]string{}, target.Passwords...)
+	for _, file := range target.PasswordFiles {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			logger.CLogger.Error("Failed to read password file "+file+":", err)
+			continue
+		}
+		passwords = append(passwords, strings.TrimRight(string(data), "\r\n"))
+	}
+	return passwords
+}
+
+// Files matched by the target path, directories are walked
+func FileTargetFiles(target models.FileTarget) []string {
+	return matchFiles(target, maxCertFileSize)
+}
+
+// Files matched by the target path up to maxSize bytes, no limit when maxSize is 0
+func matchFiles(target models.FileTarget, maxSize int64) []string {
+	matches, err := filepath.Glob(target.Path)
+	if err != nil {
+		logger.CLogger.Error("Invalid file pattern "+target.Path+":", err)
+		return nil
+	}
+
+	var files []string
+	for _, match := range matches {
+		root := match
+		filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				logger.CLogger.Error("Failed to read "+path+":", err)
+				return nil
+			}
+			if d.IsDir() {
+				if path != root && !target.Recursive {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !d.Type().IsRegular() {
+				return nil
+			}
+			if info, err := d.Info(); err != nil || (maxSize > 0 && info.Size() > maxSize) {
+				return nil
+			}
+			files = append(files, path)
+			return nil
+		})
+	}
+	return files
+}
+
+// One log per certificate in the file, the private key is looked up in the file and its siblings
+func checkCertificateFile(file string, bundles map[string]certfile.Bundle, target models.FileTarget, day int) []models.Log {
+	bundle := bundles[file]
+	if len(bundle.Certificates) == 0 {
+		return nil
+	}
+	if len(bundle.Entries) > 0 {
+		return checkKeystore(file, bundle, target, day)
+	}
+
+	// Keys in the same file or a file with the same name: server.crt and server.key
+	type keyFile struct {
+		file string
+		key  crypto.PublicKey
+	}
+	var keys []keyFile
+	locked := 0
+	stem := strings.TrimSuffix(file, filepath.Ext(file))
+	for _, other := range sortedKeys(bundles) {
+		if other != file && strings.TrimSuffix(other, filepath.Ext(other)) != stem {
+			continue
+		}
+		for _, key := range bundles[other].Keys {
+			keys = append(keys, keyFile{other, key})
+		}
+		locked += bundles[other].LockedKeys
+	}
+
+	matched, matchedFile := -1, ""
+	for i, cert := range bundle.Certificates {
+		for _, k := range keys {
+			if matched < 0 && certfile.KeyMatches(cert, k.key) {
+				matched, matchedFile = i, k.file
+			}
+		}
+	}
+
+	var logs []models.Log
+	for i, cert := range bundle.Certificates {
+		data := CertificateToLog(cert)
+		data.Domain = cert.Subject.CommonName
+		data.Source = "file"
+		data.Path = file
+		data.Tags = strings.Join(target.Tags, ", ")
+
+		// Only the first certificate of a file without a matching key is expected to have one
+		switch {
+		case i == matched:
+			data.KeyMatch = "matched"
+			data.KeyFile = matchedFile
+		case i == 0 && matched < 0 && len(keys) > 0:
+			data.KeyMatch = "mismatch"
+			data.KeyFile = keys[0].file
+			AddFinding(&data, "key_match", models.SeverityCritical, "Private key in "+keys[0].file+" does not match the certificate in "+file+".")
+		case i == 0 && matched < 0 && locked > 0:
+			data.KeyMatch = "locked"
+			AddFinding(&data, "key_match", models.SeverityInfo, "Private key next to "+file+" is encrypted and could not be compared.")
+		case i == 0 && matched < 0:
+			data.KeyMatch = "not_found"
+		}
+
+		daysLeft := DaysUntil(cert.NotAfter)
+		data.Message = fmt.Sprintf("Certificate in %s will expire in %d days.", file, daysLeft)
+		if len(bundle.Certificates) > 1 {
+			data.Message = fmt.Sprintf("Certificate #%d in %s will expire in %d days.", i, file, daysLeft)
+		}
+		if daysLeft < day || HasReportableFindings(&data) {
+			data.Status = 1
+		}
+		logs = append(logs, data)
+	}
+	return logs
+}
+
+// One log per keystore entry, a private key entry expires with the first certificate of its chain
+func checkKeystore(file string, bundle certfile.Bundle, target models.FileTarget, day int) []models.Log {
+	var logs []models.Log
+	for _, entry := range bundle.Entries {
+		chain := ChainCertificates(entry.Certificates, ChainPresented)
+		earliest, found := EarliestExpiry(chain)
+		if !found {
+			continue
+		}
+
+		data := CertificateToLog(entry.Certificates[0])
+		data.Domain = entry.Certificates[0].Subject.CommonName
+		data.Source = "file"
+		data.Path = file
+		data.Alias = entry.Alias
+		data.EntryType = entry.Type
+		data.Tags = strings.Join(target.Tags, ", ")
+		data.ChainData = CertificatesToPEM(entry.Certificates...)
+		data.Chain = chain
+		data.EffectiveExpiresOn = earliest.ExpiresOn
+		data.ExpiringElement = earliest.Label()
+
+		daysLeft := DaysUntil(earliest.ExpiresOn)
+		data.Message = fmt.Sprintf("Entry %q in %s will expire in %d days.", entry.Alias, file, daysLeft)
+		if earliest.Position != 0 {
+			data.Message = fmt.Sprintf("Entry %q in %s will expire in %d days, %s expires first.", entry.Alias, file, daysLeft, earliest.Label())
+		}
+		if bundle.Unverified {
+			AddFinding(&data, "keystore_integrity", models.SeverityInfo, "Integrity of "+file+" could not be verified with the configured passwords.")
+		}
+		if daysLeft < day || HasReportableFindings(&data) {
+			data.Status = 1
+		}
+		logs = append(logs, data)
+	}
+	return logs
+}
+
+func sortedKeys(bundles map[string]certfile.Bundle) []string {
+	var keys []string
+	for key := range bundles {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,24 @@
+package helpers
+
+import (
+	"sentinel/models"
+)
+
+// Add a finding to the log
+func AddFinding(data *models.Log, check string, severity string, message string) {
+	data.Findings = append(data.Findings, models.Finding{
+		Check:    check,
+		Severity: severity,
+		Message:  message,
+	})
+}
+
+// Warning and critical findings are reported like expiring certificates
+func HasReportableFindings(data *models.Log) bool {
+	for _, finding := range data.Findings {
+		if finding.Severity == models.SeverityWarning || finding.Severity == models.SeverityCritical {
+			return true
+		}
+	}
+	return false
+}
@@ -1,16 +1,15 @@
 package helpers
 
 import (
-	"bytes"
 	"crypto/tls"
 	"fmt"
-	"io"
 	"net"
 	"regexp"
 	"strconv"
 	"strings"
 	"time"
 
+	"sentinel/config"
 	"sentinel/logger"
 	"sentinel/models"
 
@@ -101,8 +100,25 @@ func UrlToOptions(url string) (string, string, string, string, string, string) {
 }
 
 // Check Domain Certificate
-func CheckDomainCertificate(domain string, day int) (bool, *models.Log) {
+func CheckDomainCertificate(target models.Target, day int) (bool, *models.Log) {
+	address, err := DialAddress(target)
+	if err != nil {
+		logger.CLogger.Error("Failed to resolve "+target.Address+":", err)
+		return false, nil
+	}
+	return checkCertificate(target, address, day)
+}
+
+// Check the certificate served on address, SNI and Host header come from the target
+func checkCertificate(target models.Target, address string, day int) (bool, *models.Log) {
 	status := 0
+	domain := target.Address
+	host, port, err := SplitTarget(target)
+	if err != nil {
+		logger.CLogger.Error("Invalid target address "+domain+":", err)
+		return false, nil
+	}
+	serverName := TargetServerName(target)
 
 	if day <= 0 {
 		day = 30
@@ -110,10 +126,15 @@ func CheckDomainCertificate(domain string, day int) (bool, *models.Log) {
 
 	// false: certificate will not expire in 30 days
 	// true: certificate will expire in 30 days
-	logger.CLogger.Info("INFO: Checking certificate for " + domain)
+	logger.CLogger.Info("INFO: Checking certificate for " + domain + " on " + address)
 
-	// TCP connection to domain
-	conn, err := net.DialTimeout("tcp", domain, 10*time.Second)
+	// TCP connection to domain through the target egress
+	dial, err := TargetDialer(target)
+	if err != nil {
+		logger.CLogger.Error("Invalid egress configuration for "+domain+":", err)
+		return false, nil
+	}
+	conn, err := dial(address)
 	if err != nil {
 		if netErr, ok := err.(*net.OpError); ok && netErr.Op == "dial" {
 			// DNS resolution error
@@ -126,71 +147,155 @@ func CheckDomainCertificate(domain string, day int) (bool, *models.Log) {
 	}
 	defer conn.Close()
 
+	// Mail servers talk plain text until STARTTLS
+	startTLS := TargetStartTLS(target)
+	if err := StartTLS(conn, startTLS, serverName); err != nil {
+		logger.CLogger.Error("STARTTLS failed for "+domain+":", err)
+		return false, nil
+	}
+
 	// TLS Handshake
 	// x509: certificate signed by unknown authority
-	tlsConn := tls.Client(conn, &tls.Config{
-		ServerName:         strings.Split(domain, ":")[0],
+	tlsConfig := &tls.Config{
+		ServerName:         serverName,
 		InsecureSkipVerify: true,
-	})
+	}
+
+	// Endpoints requiring mTLS abort the handshake without a client certificate
+	if target.ClientCert != nil {
+		clientCert, err := LoadClientCertificate(*target.ClientCert)
+		if err != nil {
+			logger.CLogger.Error("Failed to load client certificate for "+domain+":", err)
+			return false, nil
+		}
+		tlsConfig.Certificates = []tls.Certificate{clientCert}
+	}
 
+	tlsConn := tls.Client(conn, tlsConfig)
+	conn.SetDeadline(time.Now().Add(dialTimeout))
 	if err := tlsConn.Handshake(); err != nil {
 		logger.CLogger.Error("TLS Handshake failed:", err)
 		return false, nil
 	}
+	conn.SetDeadline(time.Time{})
 
-	// HTTP Request
-	req := "GET / HTTP/1.1\r\nHost: " + strings.Split(domain, ":")[0] + "\r\n\r\n"
-	if _, err := tlsConn.Write([]byte(req)); err != nil {
-		logger.CLogger.Error("Failed to write HTTP request:", err)
+	// Certification Info is here
+	state := tlsConn.ConnectionState()
+	certs := state.PeerCertificates
+	if len(certs) == 0 {
+		logger.CLogger.Error("No peer certificate presented by " + domain)
 		return false, nil
 	}
+	cert := certs[0]
+	tempPort, _ := strconv.Atoi(port)
 
-	// HTTP Response
-	var responseBuffer bytes.Buffer
-	buf := make([]byte, 1024)
-	for {
-		n, err := tlsConn.Read(buf)
-		if err != nil {
-			if err != io.EOF {
-				logger.CLogger.Error("Failed to read HTTP response:", err)
-			}
-			break
-		}
-		responseBuffer.Write(buf[:n])
+	// Every element of the presented and verified chain counts, the earliest one is the effective expiry
+	chain := ChainCertificates(certs, ChainPresented)
+	chainError := ""
+	verified, err := VerifyChain(certs)
+	if err != nil {
+		chainError = err.Error()
+	} else {
+		chain = append(chain, ChainCertificates(verified, ChainVerified)...)
 	}
+	earliest, _ := EarliestExpiry(chain)
 
-	// Certification Info is here
-	cert := tlsConn.ConnectionState().PeerCertificates[0]
-	tempPort, _ := strconv.Atoi(strings.Split(domain, ":")[1])
-	tempOrganization := cert.Subject.Organization
-	daysUntilExpiration := int(time.Until(cert.NotAfter).Hours() / 24) // Optimized line
+	daysUntilExpiration := DaysUntil(earliest.ExpiresOn)
 	isExpired := daysUntilExpiration < day
 	if isExpired {
 		status = 1
 	}
 
-	// if certifcate time gonna expire in 30 days add to logs
-	return isExpired, &models.Log{
-		Version:            cert.Version,
-		SerialNumber:       cert.SerialNumber.String(),
-		Subject:            cert.Subject.String(),
-		IssuerSubject:      cert.Issuer.String(),
-		Domain:             strings.Split(domain, ":")[0],
-		Port:               tempPort,
-		CommonName:         cert.Subject.CommonName,
-		Organization:       ArrayToString(tempOrganization),
-		IssuedOn:           cert.NotBefore,
-		ExpiresOn:          cert.NotAfter,
-		CertificateData:    string(cert.Raw),
-		SignatureAlgorithm: cert.SignatureAlgorithm.String(),
-		SubjectKeyID:       string(cert.SubjectKeyId),
-		AuthorityKeyID:     string(cert.AuthorityKeyId),
-		IsCA:               cert.IsCA,
-		Issuer:             cert.Issuer.CommonName,
-		IsExpired:          cert.NotAfter.Before(cert.NotBefore),
-		Message:            fmt.Sprintf("Certificate will expire in %d days.", daysUntilExpiration),
-		Status:             status,
+	message := fmt.Sprintf("Certificate will expire in %d days.", daysUntilExpiration)
+	if earliest.Position != 0 {
+		message = fmt.Sprintf("Certificate chain will expire in %d days, %s expires first.", daysUntilExpiration, earliest.Label())
+	}
+
+	data := CertificateToLog(cert)
+	data.Domain = host
+	data.Port = tempPort
+	data.Source = "server"
+	data.RemoteIP = remoteIP(conn, address, target)
+	data.ChainData = CertificatesToPEM(certs...)
+	data.Chain = chain
+	data.ChainError = chainError
+	data.EffectiveExpiresOn = earliest.ExpiresOn
+	data.ExpiringElement = earliest.Label()
+
+	// Only the handshake is needed for the certificate, the HTTP response is opt-in
+	if (config.C.Scan.HTTP || target.HTTP) && startTLS == StartTLSNone {
+		CheckHTTP(&data, tlsConn, TargetHostHeader(target))
 	}
+
+	// A revoked certificate is reported regardless of its expiry
+	if revoked := CheckRevocation(&data, certs, verified, state.OCSPResponse); revoked != "" {
+		isExpired = true
+		status = 1
+		message = "Certificate " + revoked + " is revoked. " + message
+	}
+
+	// SCTs from the certificate, the TLS extension and the staple against the CT log list,
+	// without any log every SCT would be unknown and every public certificate a violation
+	if config.C.CT.Enabled && len(CTLogs) > 0 {
+		CheckCT(&data, certs, verified, state.SignedCertificateTimestamps, state.OCSPResponse)
+	}
+
+	// Policy violations become findings
+	data.Tags = strings.Join(target.Tags, ", ")
+	CheckPolicies(&data, target, serverName, certs)
+
+	// Trust matrix across the configured root stores
+	if len(RootStores) > 0 {
+		CheckTrustMatrix(&data, target, certs, day)
+	}
+
+	// CAA records that would block a renewal through the current issuer
+	if config.C.CAA.Enabled {
+		CheckCAA(&data, target, serverName, cert)
+	}
+
+	// TLSA records the chain has to match for DANE clients
+	if config.C.DANE.Enabled || target.DANE {
+		CheckDANE(&data, target, serverName, port, certs, verified, day)
+	}
+
+	// Baseline requirements lints
+	if config.C.Lint.Enabled {
+		CheckLints(&data, target, certs)
+	}
+
+	// Fresh connections for the raw handshake probes, upgraded like the first one
+	probeDial := func() (net.Conn, error) {
+		conn, err := dial(address)
+		if err != nil {
+			return nil, err
+		}
+		if err := StartTLS(conn, startTLS, serverName); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		return conn, nil
+	}
+
+	// Deep scan enumerates protocol versions, cipher suites, ALPN and groups with extra connections
+	if config.C.Scan.Deep {
+		CheckInventory(&data, probeDial, serverName)
+	}
+
+	// Which clients would get through the handshake
+	if config.C.Simulation.Enabled {
+		CheckClientSimulation(&data, probeDial, serverName, certs)
+	}
+
+	if HasReportableFindings(&data) {
+		isExpired = true
+		status = 1
+	}
+
+	data.Message = message
+	data.Status = status
+
+	return isExpired, &data
 }
 
 // Excel File Creation Function
@@ -264,6 +369,91 @@ func SetChangesToExcel(changes []models.Log) *excelize.File {
 	f.SetCellValue("Logs", "P1", "Issuer")
 	f.SetCellValue("Logs", "Q1", "Is Expired")
 	f.SetCellValue("Logs", "R1", "Message")
+	f.SetCellValue("Logs", "S1", "Effective Expires On")
+	f.SetCellValue("Logs", "T1", "Expiring Element")
+	f.SetCellValue("Logs", "U1", "Chain Error")
+	f.SetCellValue("Logs", "V1", "DNS Names")
+	f.SetCellValue("Logs", "W1", "IP Addresses")
+	f.SetCellValue("Logs", "X1", "Email Addresses")
+	f.SetCellValue("Logs", "Y1", "Public Key Algorithm")
+	f.SetCellValue("Logs", "Z1", "Public Key Size")
+	f.SetCellValue("Logs", "AA1", "SHA-1 Fingerprint")
+	f.SetCellValue("Logs", "AB1", "SHA-256 Fingerprint")
+	f.SetCellValue("Logs", "AC1", "SPKI SHA-256")
+	f.SetCellValue("Logs", "AD1", "Key Usage")
+	f.SetCellValue("Logs", "AE1", "Extended Key Usage")
+	f.SetCellValue("Logs", "AF1", "CA Issuers")
+	f.SetCellValue("Logs", "AG1", "OCSP Servers")
+	f.SetCellValue("Logs", "AH1", "CRL Distribution Points")
+	f.SetCellValue("Logs", "AI1", "Policy OIDs")
+	f.SetCellValue("Logs", "AJ1", "Must Staple")
+	f.SetCellValue("Logs", "AK1", "Chain Data")
+	f.SetCellValue("Logs", "AL1", "OCSP Status")
+	f.SetCellValue("Logs", "AM1", "OCSP Responder")
+	f.SetCellValue("Logs", "AN1", "OCSP Error")
+	f.SetCellValue("Logs", "AO1", "Revoked At")
+	f.SetCellValue("Logs", "AP1", "OCSP Stapled")
+	f.SetCellValue("Logs", "AQ1", "OCSP Staple Fresh")
+	f.SetCellValue("Logs", "AR1", "OCSP Staple Status")
+	f.SetCellValue("Logs", "AS1", "CRL Status")
+	f.SetCellValue("Logs", "AT1", "CRL URL")
+	f.SetCellValue("Logs", "AU1", "CRL Error")
+	f.SetCellValue("Logs", "AV1", "TLS Versions")
+	f.SetCellValue("Logs", "AW1", "Cipher Suites")
+	f.SetCellValue("Logs", "AX1", "ALPN Protocols")
+	f.SetCellValue("Logs", "AY1", "Key Exchange Groups")
+	f.SetCellValue("Logs", "AZ1", "Preferred Group")
+	f.SetCellValue("Logs", "BA1", "Tags")
+	f.SetCellValue("Logs", "BB1", "Remote IP")
+	f.SetCellValue("Logs", "BC1", "Source")
+	f.SetCellValue("Logs", "BD1", "HTTP Status")
+	f.SetCellValue("Logs", "BE1", "HTTP Server")
+	f.SetCellValue("Logs", "BF1", "HSTS")
+	f.SetCellValue("Logs", "BG1", "HTTP Redirect")
+	f.SetCellValue("Logs", "BH1", "HTTP Error")
+	f.SetCellValue("Logs", "BI1", "Parent")
+	f.SetCellValue("Logs", "BJ1", "Redirect Chain")
+	f.SetCellValue("Logs", "BK1", "Dependent Hosts")
+	f.SetCellValue("Logs", "BL1", "Crawl Error")
+	f.SetCellValue("Logs", "BM1", "Path")
+	f.SetCellValue("Logs", "BN1", "Key File")
+	f.SetCellValue("Logs", "BO1", "Key Match")
+	f.SetCellValue("Logs", "BP1", "Alias")
+	f.SetCellValue("Logs", "BQ1", "Entry Type")
+	f.SetCellValue("Logs", "BR1", "Kube Context")
+	f.SetCellValue("Logs", "BS1", "Kube Cluster")
+	f.SetCellValue("Logs", "BT1", "Kube User")
+	f.SetCellValue("Logs", "BU1", "Kube Namespace")
+	f.SetCellValue("Logs", "BV1", "Kube Secret")
+	f.SetCellValue("Logs", "BW1", "Kube Field")
+	f.SetCellValue("Logs", "BX1", "Image")
+	f.SetCellValue("Logs", "BY1", "Layer Digest")
+	f.SetCellValue("Logs", "BZ1", "SSH Cert Type")
+	f.SetCellValue("Logs", "CA1", "SSH Key ID")
+	f.SetCellValue("Logs", "CB1", "SSH Principals")
+	f.SetCellValue("Logs", "CC1", "SSH Signing CA")
+	f.SetCellValue("Logs", "CD1", "Registered Domain")
+	f.SetCellValue("Logs", "CE1", "Registrar")
+	f.SetCellValue("Logs", "CF1", "Domain Expires On")
+	f.SetCellValue("Logs", "CG1", "Domain Status")
+	f.SetCellValue("Logs", "CH1", "Registration Error")
+	f.SetCellValue("Logs", "CI1", "CAA Domain")
+	f.SetCellValue("Logs", "CJ1", "CAA Records")
+	f.SetCellValue("Logs", "CK1", "CAA Error")
+	f.SetCellValue("Logs", "CL1", "TLSA Records")
+	f.SetCellValue("Logs", "CM1", "TLSA Status")
+	f.SetCellValue("Logs", "CN1", "TLSA DNSSEC")
+	f.SetCellValue("Logs", "CO1", "TLSA Error")
+	f.SetCellValue("Logs", "CP1", "MTA-STS ID")
+	f.SetCellValue("Logs", "CQ1", "MTA-STS Mode")
+	f.SetCellValue("Logs", "CR1", "MTA-STS Max Age")
+	f.SetCellValue("Logs", "CS1", "MTA-STS MX")
+	f.SetCellValue("Logs", "CT1", "MTA-STS Error")
+	f.SetCellValue("Logs", "CU1", "TLS-RPT")
+	f.SetCellValue("Logs", "CV1", "SCTs")
+	f.SetCellValue("Logs", "CW1", "SCT Valid")
+	f.SetCellValue("Logs", "CX1", "CT Compliant")
+	f.SetCellValue("Logs", "CY1", "CT Error")
 
 	// Set value of a cell.
 	index := 2
@@ -286,16 +476,221 @@ func SetChangesToExcel(changes []models.Log) *excelize.File {
 		f.SetCellValue("Logs", "P"+strconv.Itoa(index), change.Issuer)
 		f.SetCellValue("Logs", "Q"+strconv.Itoa(index), change.IsExpired)
 		f.SetCellValue("Logs", "R"+strconv.Itoa(index), change.Message)
+		f.SetCellValue("Logs", "S"+strconv.Itoa(index), change.EffectiveExpiresOn)
+		f.SetCellValue("Logs", "T"+strconv.Itoa(index), change.ExpiringElement)
+		f.SetCellValue("Logs", "U"+strconv.Itoa(index), change.ChainError)
+		f.SetCellValue("Logs", "V"+strconv.Itoa(index), change.DNSNames)
+		f.SetCellValue("Logs", "W"+strconv.Itoa(index), change.IPAddresses)
+		f.SetCellValue("Logs", "X"+strconv.Itoa(index), change.EmailAddresses)
+		f.SetCellValue("Logs", "Y"+strconv.Itoa(index), change.PublicKeyAlgorithm)
+		f.SetCellValue("Logs", "Z"+strconv.Itoa(index), change.PublicKeySize)
+		f.SetCellValue("Logs", "AA"+strconv.Itoa(index), change.FingerprintSHA1)
+		f.SetCellValue("Logs", "AB"+strconv.Itoa(index), change.FingerprintSHA256)
+		f.SetCellValue("Logs", "AC"+strconv.Itoa(index), change.SPKISHA256)
+		f.SetCellValue("Logs", "AD"+strconv.Itoa(index), change.KeyUsage)
+		f.SetCellValue("Logs", "AE"+strconv.Itoa(index), change.ExtKeyUsage)
+		f.SetCellValue("Logs", "AF"+strconv.Itoa(index), change.IssuingCertURLs)
+		f.SetCellValue("Logs", "AG"+strconv.Itoa(index), change.OCSPServers)
+		f.SetCellValue("Logs", "AH"+strconv.Itoa(index), change.CRLDistribution)
+		f.SetCellValue("Logs", "AI"+strconv.Itoa(index), change.PolicyOIDs)
+		f.SetCellValue("Logs", "AJ"+strconv.Itoa(index), change.MustStaple)
+		f.SetCellValue("Logs", "AK"+strconv.Itoa(index), change.ChainData)
+		f.SetCellValue("Logs", "AL"+strconv.Itoa(index), change.OCSPStatus)
+		f.SetCellValue("Logs", "AM"+strconv.Itoa(index), change.OCSPResponder)
+		f.SetCellValue("Logs", "AN"+strconv.Itoa(index), change.OCSPError)
+		f.SetCellValue("Logs", "AO"+strconv.Itoa(index), change.RevokedAt)
+		f.SetCellValue("Logs", "AP"+strconv.Itoa(index), change.OCSPStapled)
+		f.SetCellValue("Logs", "AQ"+strconv.Itoa(index), change.OCSPStapleFresh)
+		f.SetCellValue("Logs", "AR"+strconv.Itoa(index), change.OCSPStapleStatus)
+		f.SetCellValue("Logs", "AS"+strconv.Itoa(index), change.CRLStatus)
+		f.SetCellValue("Logs", "AT"+strconv.Itoa(index), change.CRLURL)
+		f.SetCellValue("Logs", "AU"+strconv.Itoa(index), change.CRLError)
+		f.SetCellValue("Logs", "AV"+strconv.Itoa(index), change.TLSVersions)
+		f.SetCellValue("Logs", "AW"+strconv.Itoa(index), change.CipherSuites)
+		f.SetCellValue("Logs", "AX"+strconv.Itoa(index), change.ALPNProtocols)
+		f.SetCellValue("Logs", "AY"+strconv.Itoa(index), change.KeyExchangeGroups)
+		f.SetCellValue("Logs", "AZ"+strconv.Itoa(index), change.PreferredGroup)
+		f.SetCellValue("Logs", "BA"+strconv.Itoa(index), change.Tags)
+		f.SetCellValue("Logs", "BB"+strconv.Itoa(index), change.RemoteIP)
+		f.SetCellValue("Logs", "BC"+strconv.Itoa(index), change.Source)
+		f.SetCellValue("Logs", "BD"+strconv.Itoa(index), change.HTTPStatus)
+		f.SetCellValue("Logs", "BE"+strconv.Itoa(index), change.HTTPServer)
+		f.SetCellValue("Logs", "BF"+strconv.Itoa(index), change.HSTS)
+		f.SetCellValue("Logs", "BG"+strconv.Itoa(index), change.HTTPRedirect)
+		f.SetCellValue("Logs", "BH"+strconv.Itoa(index), change.HTTPError)
+		f.SetCellValue("Logs", "BI"+strconv.Itoa(index), change.Parent)
+		f.SetCellValue("Logs", "BJ"+strconv.Itoa(index), change.RedirectChain)
+		f.SetCellValue("Logs", "BK"+strconv.Itoa(index), change.DependentHosts)
+		f.SetCellValue("Logs", "BL"+strconv.Itoa(index), change.CrawlError)
+		f.SetCellValue("Logs", "BM"+strconv.Itoa(index), change.Path)
+		f.SetCellValue("Logs", "BN"+strconv.Itoa(index), change.KeyFile)
+		f.SetCellValue("Logs", "BO"+strconv.Itoa(index), change.KeyMatch)
+		f.SetCellValue("Logs", "BP"+strconv.Itoa(index), change.Alias)
+		f.SetCellValue("Logs", "BQ"+strconv.Itoa(index), change.EntryType)
+		f.SetCellValue("Logs", "BR"+strconv.Itoa(index), change.KubeContext)
+		f.SetCellValue("Logs", "BS"+strconv.Itoa(index), change.KubeCluster)
+		f.SetCellValue("Logs", "BT"+strconv.Itoa(index), change.KubeUser)
+		f.SetCellValue("Logs", "BU"+strconv.Itoa(index), change.KubeNamespace)
+		f.SetCellValue("Logs", "BV"+strconv.Itoa(index), change.KubeSecret)
+		f.SetCellValue("Logs", "BW"+strconv.Itoa(index), change.KubeField)
+		f.SetCellValue("Logs", "BX"+strconv.Itoa(index), change.Image)
+		f.SetCellValue("Logs", "BY"+strconv.Itoa(index), change.LayerDigest)
+		f.SetCellValue("Logs", "BZ"+strconv.Itoa(index), change.SSHCertType)
+		f.SetCellValue("Logs", "CA"+strconv.Itoa(index), change.SSHKeyID)
+		f.SetCellValue("Logs", "CB"+strconv.Itoa(index), change.SSHPrincipals)
+		f.SetCellValue("Logs", "CC"+strconv.Itoa(index), change.SSHSigningCA)
+		f.SetCellValue("Logs", "CD"+strconv.Itoa(index), change.RegisteredDomain)
+		f.SetCellValue("Logs", "CE"+strconv.Itoa(index), change.Registrar)
+		f.SetCellValue("Logs", "CF"+strconv.Itoa(index), change.DomainExpiresOn)
+		f.SetCellValue("Logs", "CG"+strconv.Itoa(index), change.DomainStatus)
+		f.SetCellValue("Logs", "CH"+strconv.Itoa(index), change.RegistrationError)
+		f.SetCellValue("Logs", "CI"+strconv.Itoa(index), change.CAADomain)
+		f.SetCellValue("Logs", "CJ"+strconv.Itoa(index), change.CAARecords)
+		f.SetCellValue("Logs", "CK"+strconv.Itoa(index), change.CAAError)
+		f.SetCellValue("Logs", "CL"+strconv.Itoa(index), change.TLSARecords)
+		f.SetCellValue("Logs", "CM"+strconv.Itoa(index), change.TLSAStatus)
+		f.SetCellValue("Logs", "CN"+strconv.Itoa(index), change.TLSADNSSEC)
+		f.SetCellValue("Logs", "CO"+strconv.Itoa(index), change.TLSAError)
+		f.SetCellValue("Logs", "CP"+strconv.Itoa(index), change.MTASTSID)
+		f.SetCellValue("Logs", "CQ"+strconv.Itoa(index), change.MTASTSMode)
+		f.SetCellValue("Logs", "CR"+strconv.Itoa(index), change.MTASTSMaxAge)
+		f.SetCellValue("Logs", "CS"+strconv.Itoa(index), change.MTASTSMX)
+		f.SetCellValue("Logs", "CT"+strconv.Itoa(index), change.MTASTSError)
+		f.SetCellValue("Logs", "CU"+strconv.Itoa(index), change.TLSRPT)
+		f.SetCellValue("Logs", "CV"+strconv.Itoa(index), change.SCTs)
+		f.SetCellValue("Logs", "CW"+strconv.Itoa(index), change.SCTValid)
+		f.SetCellValue("Logs", "CX"+strconv.Itoa(index), change.CTCompliant)
+		f.SetCellValue("Logs", "CY"+strconv.Itoa(index), change.CTError)
 		if change.Status == 1 {
-			f.SetCellStyle("Logs", "A"+strconv.Itoa(index), "R"+strconv.Itoa(index), styleExpire)
+			f.SetCellStyle("Logs", "A"+strconv.Itoa(index), "CY"+strconv.Itoa(index), styleExpire)
 		} else if change.Status == 0 {
-			f.SetCellStyle("Logs", "A"+strconv.Itoa(index), "R"+strconv.Itoa(index), styleNotExpire)
+			f.SetCellStyle("Logs", "A"+strconv.Itoa(index), "CY"+strconv.Itoa(index), styleNotExpire)
 		} else {
-			f.SetCellStyle("Logs", "A"+strconv.Itoa(index), "R"+strconv.Itoa(index), styleTimeOut)
+			f.SetCellStyle("Logs", "A"+strconv.Itoa(index), "CY"+strconv.Itoa(index), styleTimeOut)
 		}
 		index++
 	}
 
+	// Chain sheet, one row per chain element
+	f.NewSheet("Chain")
+	f.SetCellValue("Chain", "A1", "Domain")
+	f.SetCellValue("Chain", "B1", "Port")
+	f.SetCellValue("Chain", "C1", "Chain")
+	f.SetCellValue("Chain", "D1", "Position")
+	f.SetCellValue("Chain", "E1", "Role")
+	f.SetCellValue("Chain", "F1", "Subject")
+	f.SetCellValue("Chain", "G1", "Issuer")
+	f.SetCellValue("Chain", "H1", "Serial Number")
+	f.SetCellValue("Chain", "I1", "Issued On")
+	f.SetCellValue("Chain", "J1", "Expires On")
+	f.SetCellValue("Chain", "K1", "Days Left")
+	f.SetCellValue("Chain", "L1", "CRL Status")
+	f.SetCellValue("Chain", "M1", "Failed Lints")
+
+	chainIndex := 2
+	for _, change := range changes {
+		for _, c := range change.Chain {
+			f.SetCellValue("Chain", "A"+strconv.Itoa(chainIndex), change.Domain)
+			f.SetCellValue("Chain", "B"+strconv.Itoa(chainIndex), change.Port)
+			f.SetCellValue("Chain", "C"+strconv.Itoa(chainIndex), c.Chain)
+			f.SetCellValue("Chain", "D"+strconv.Itoa(chainIndex), c.Position)
+			f.SetCellValue("Chain", "E"+strconv.Itoa(chainIndex), c.Role)
+			f.SetCellValue("Chain", "F"+strconv.Itoa(chainIndex), c.Subject)
+			f.SetCellValue("Chain", "G"+strconv.Itoa(chainIndex), c.Issuer)
+			f.SetCellValue("Chain", "H"+strconv.Itoa(chainIndex), c.SerialNumber)
+			f.SetCellValue("Chain", "I"+strconv.Itoa(chainIndex), c.IssuedOn)
+			f.SetCellValue("Chain", "J"+strconv.Itoa(chainIndex), c.ExpiresOn)
+			f.SetCellValue("Chain", "K"+strconv.Itoa(chainIndex), c.DaysLeft)
+			f.SetCellValue("Chain", "L"+strconv.Itoa(chainIndex), c.CRLStatus)
+			f.SetCellValue("Chain", "M"+strconv.Itoa(chainIndex), strings.Join(c.Lints, ", "))
+			// Highlight the element that defines the effective expiry
+			if c.Label() == change.ExpiringElement {
+				f.SetCellStyle("Chain", "A"+strconv.Itoa(chainIndex), "M"+strconv.Itoa(chainIndex), styleExpire)
+			}
+			chainIndex++
+		}
+	}
+
+	// Trust sheet, one row per endpoint and trust store
+	f.NewSheet("Trust")
+	f.SetCellValue("Trust", "A1", "Domain")
+	f.SetCellValue("Trust", "B1", "Port")
+	f.SetCellValue("Trust", "C1", "Trust Store")
+	f.SetCellValue("Trust", "D1", "Trusted")
+	f.SetCellValue("Trust", "E1", "Root")
+	f.SetCellValue("Trust", "F1", "Root Expires On")
+	f.SetCellValue("Trust", "G1", "Error")
+
+	trustIndex := 2
+	for _, change := range changes {
+		for _, t := range change.Trust {
+			f.SetCellValue("Trust", "A"+strconv.Itoa(trustIndex), change.Domain)
+			f.SetCellValue("Trust", "B"+strconv.Itoa(trustIndex), change.Port)
+			f.SetCellValue("Trust", "C"+strconv.Itoa(trustIndex), t.Store)
+			f.SetCellValue("Trust", "D"+strconv.Itoa(trustIndex), t.Trusted)
+			f.SetCellValue("Trust", "E"+strconv.Itoa(trustIndex), t.Root)
+			f.SetCellValue("Trust", "G"+strconv.Itoa(trustIndex), t.Error)
+			if t.Trusted {
+				f.SetCellValue("Trust", "F"+strconv.Itoa(trustIndex), t.RootExpiresOn)
+			} else {
+				f.SetCellStyle("Trust", "A"+strconv.Itoa(trustIndex), "G"+strconv.Itoa(trustIndex), styleExpire)
+			}
+			trustIndex++
+		}
+	}
+
+	// Clients sheet, one row per endpoint and simulated client
+	f.NewSheet("Clients")
+	f.SetCellValue("Clients", "A1", "Domain")
+	f.SetCellValue("Clients", "B1", "Port")
+	f.SetCellValue("Clients", "C1", "Client")
+	f.SetCellValue("Clients", "D1", "Success")
+	f.SetCellValue("Clients", "E1", "Version")
+	f.SetCellValue("Clients", "F1", "Cipher Suite")
+	f.SetCellValue("Clients", "G1", "Reason")
+
+	clientIndex := 2
+	for _, change := range changes {
+		for _, c := range change.ClientSimulations {
+			f.SetCellValue("Clients", "A"+strconv.Itoa(clientIndex), change.Domain)
+			f.SetCellValue("Clients", "B"+strconv.Itoa(clientIndex), change.Port)
+			f.SetCellValue("Clients", "C"+strconv.Itoa(clientIndex), c.Client)
+			f.SetCellValue("Clients", "D"+strconv.Itoa(clientIndex), c.Success)
+			f.SetCellValue("Clients", "E"+strconv.Itoa(clientIndex), c.Version)
+			f.SetCellValue("Clients", "F"+strconv.Itoa(clientIndex), c.CipherSuite)
+			f.SetCellValue("Clients", "G"+strconv.Itoa(clientIndex), c.Reason)
+			if !c.Success {
+				f.SetCellStyle("Clients", "A"+strconv.Itoa(clientIndex), "G"+strconv.Itoa(clientIndex), styleExpire)
+			}
+			clientIndex++
+		}
+	}
+
+	// Findings sheet, one row per finding
+	f.NewSheet("Findings")
+	f.SetCellValue("Findings", "A1", "Domain")
+	f.SetCellValue("Findings", "B1", "Port")
+	f.SetCellValue("Findings", "C1", "Check")
+	f.SetCellValue("Findings", "D1", "Severity")
+	f.SetCellValue("Findings", "E1", "Message")
+
+	findingIndex := 2
+	for _, change := range changes {
+		for _, finding := range change.Findings {
+			f.SetCellValue("Findings", "A"+strconv.Itoa(findingIndex), change.Domain)
+			f.SetCellValue("Findings", "B"+strconv.Itoa(findingIndex), change.Port)
+			f.SetCellValue("Findings", "C"+strconv.Itoa(findingIndex), finding.Check)
+			f.SetCellValue("Findings", "D"+strconv.Itoa(findingIndex), finding.Severity)
+			f.SetCellValue("Findings", "E"+strconv.Itoa(findingIndex), finding.Message)
+			if finding.Severity == models.SeverityCritical {
+				f.SetCellStyle("Findings", "A"+strconv.Itoa(findingIndex), "E"+strconv.Itoa(findingIndex), styleExpire)
+			} else if finding.Severity == models.SeverityWarning {
+				f.SetCellStyle("Findings", "A"+strconv.Itoa(findingIndex), "E"+strconv.Itoa(findingIndex), styleTimeOut)
+			}
+			findingIndex++
+		}
+	}
+
 	// Set active sheet of the workbook.
 	f.SetActiveSheet(index)
 
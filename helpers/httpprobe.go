@@ -0,0 +1,60 @@
+package helpers
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"sentinel/config"
+	"sentinel/models"
+)
+
+// Send GET / on the established TLS connection and record the interesting parts of the response
+func CheckHTTP(data *models.Log, conn net.Conn, hostHeader string) {
+	timeout := time.Duration(config.C.Scan.HTTPTimeout) * time.Second
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	maxSize := int64(config.C.Scan.HTTPMaxSize) << 10
+	if maxSize <= 0 {
+		maxSize = 64 << 10
+	}
+
+	// Keep-alive servers never close the connection, the deadline and size cap bound the read
+	conn.SetDeadline(time.Now().Add(timeout))
+	defer conn.SetDeadline(time.Time{})
+
+	req := "GET / HTTP/1.1\r\nHost: " + hostHeader + "\r\nUser-Agent: sentinel\r\nAccept: */*\r\nConnection: close\r\n\r\n"
+	if _, err := io.WriteString(conn, req); err != nil {
+		data.HTTPError = "Failed to write HTTP request: " + err.Error()
+		return
+	}
+
+	resp, err := http.ReadResponse(bufio.NewReader(io.LimitReader(conn, maxSize)), nil)
+	if err != nil {
+		data.HTTPError = "Failed to read HTTP response: " + err.Error()
+		return
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	data.HTTPStatus = resp.StatusCode
+	data.HTTPServer = resp.Header.Get("Server")
+	data.HSTS = resp.Header.Get("Strict-Transport-Security")
+	if location := resp.Header.Get("Location"); location != "" && resp.StatusCode >= 300 && resp.StatusCode < 400 {
+		data.HTTPRedirect = resolveLocation(hostHeader, location)
+	}
+}
+
+// Relative redirects are resolved against the requested page
+func resolveLocation(hostHeader string, location string) string {
+	base := &url.URL{Scheme: "https", Host: hostHeader, Path: "/"}
+	ref, err := url.Parse(location)
+	if err != nil {
+		return location
+	}
+	return base.ResolveReference(ref).String()
+}
@@ -0,0 +1,127 @@
+package helpers
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"sentinel/models"
+	"sentinel/pkg/certfile"
+	"sentinel/pkg/image"
+)
+
+// Certificate files kept from every image tarball, reading a tarball takes two passes over all its layers
+var imageCache = struct {
+	sync.Mutex
+	entries map[string]imageCacheEntry
+}{entries: make(map[string]imageCacheEntry)}
+
+type imageCacheEntry struct {
+	size    int64
+	modTime time.Time
+	files   []image.File
+}
+
+// Files of the image tarball, read again only when its size or modification time changed
+func imageFiles(archive string, filter image.Filter) ([]image.File, error) {
+	info, err := os.Stat(archive)
+	if err != nil {
+		imageCache.Lock()
+		delete(imageCache.entries, archive)
+		imageCache.Unlock()
+		return nil, err
+	}
+
+	imageCache.Lock()
+	entry, ok := imageCache.entries[archive]
+	imageCache.Unlock()
+	if ok && entry.size == info.Size() && entry.modTime.Equal(info.ModTime()) {
+		return entry.files, nil
+	}
+
+	// Failed reads are not cached, the archive may still be written
+	files, err := image.Files(archive, filter)
+	imageCache.Lock()
+	defer imageCache.Unlock()
+	if err != nil {
+		delete(imageCache.entries, archive)
+		return files, err
+	}
+	imageCache.entries[archive] = imageCacheEntry{size: info.Size(), modTime: info.ModTime(), files: files}
+	return files, nil
+}
+
+// Check every certificate in the final filesystem of the image tarballs of the target, fully offline
+func CheckImageTarget(target models.FileTarget, day int) (bool, []models.Log) {
+	if day <= 0 {
+		day = 30
+	}
+
+	passwords := FileTargetPasswords(target)
+	isReported := false
+	var logs []models.Log
+	// Image tarballs are far bigger than certificate files
+	for _, archive := range matchFiles(target, 0) {
+		files, err := imageFiles(archive, image.Filter{
+			MaxSize: maxCertFileSize,
+			// Recognition needs no password, encrypted content is opened when the kept files are parsed
+			Keep: func(path string, data []byte) bool {
+				_, err := certfile.Parse(data, nil)
+				return !errors.Is(err, certfile.ErrUnrecognized)
+			},
+		})
+		if err != nil {
+			failed := models.Log{
+				Source:  "image",
+				Image:   archive,
+				Tags:    strings.Join(target.Tags, ", "),
+				Message: "Image " + archive + " could not be read: " + err.Error(),
+				Status:  1,
+			}
+			AddFinding(&failed, "image", models.SeverityWarning, failed.Message)
+			logs = append(logs, failed)
+			isReported = true
+			continue
+		}
+
+		// Paths are only unique within one image of the tarball
+		images := map[string]map[string]image.File{}
+		var order []string
+		for _, file := range files {
+			if images[file.Image] == nil {
+				images[file.Image] = map[string]image.File{}
+				order = append(order, file.Image)
+			}
+			images[file.Image][file.Path] = file
+		}
+
+		for _, name := range order {
+			contents := images[name]
+			var paths []string
+			for path := range contents {
+				paths = append(paths, path)
+			}
+			read := func(path string) ([]byte, error) {
+				return contents[path].Data, nil
+			}
+
+			bundles, imageLogs := parseCertificateFiles(paths, read, passwords, target)
+			imageLogs = append(imageLogs, checkCertificateFiles(bundles, target, day)...)
+			for _, data := range imageLogs {
+				data.Source = "image"
+				data.Image = archive
+				if name != "" {
+					data.Image += " (" + name + ")"
+				}
+				data.LayerDigest = contents[data.Path].Layer
+				// Messages name the path inside the image, prefix it with the image
+				data.Message = strings.Replace(data.Message, data.Path, data.Image+":"+data.Path, 1)
+				isReported = isReported || data.Status == 1
+				logs = append(logs, data)
+			}
+		}
+	}
+	return isReported, logs
+}
@@ -0,0 +1,57 @@
+package helpers
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+
+	"sentinel/logger"
+	"sentinel/models"
+	"sentinel/pkg/tlsscan"
+)
+
+// Last inventory fingerprint per endpoint, used to flag configuration changes
+var inventoryState = struct {
+	sync.Mutex
+	last map[string]string
+}{last: make(map[string]string)}
+
+// Deep scan the endpoint and add findings for weak or changed TLS configuration
+func CheckInventory(data *models.Log, dial tlsscan.DialFunc, serverName string) {
+	inv, err := tlsscan.Scan(dial, serverName)
+	if err != nil {
+		// An incomplete inventory would be taken for a configuration change
+		logger.CLogger.Error("Deep scan of "+data.Domain+" "+data.RemoteIP+" incomplete:", err)
+		return
+	}
+	if len(inv.Versions) == 0 {
+		logger.CLogger.Error("Deep scan of " + data.Domain + " " + data.RemoteIP + " found no protocol version")
+		return
+	}
+
+	data.TLSVersions = strings.Join(inv.Versions, ", ")
+	data.CipherSuites = strings.Join(inv.SuiteList(), ", ")
+	data.ALPNProtocols = strings.Join(inv.ALPN, ", ")
+	data.KeyExchangeGroups = strings.Join(inv.Groups, ", ")
+	data.PreferredGroup = inv.PreferredGroup
+
+	if len(inv.WeakVersions) > 0 {
+		AddFinding(data, "tls_inventory", models.SeverityWarning, "Deprecated protocol versions accepted: "+strings.Join(inv.WeakVersions, ", "))
+	}
+	if len(inv.WeakSuites) > 0 {
+		AddFinding(data, "tls_inventory", models.SeverityWarning, "Weak cipher suites accepted: "+strings.Join(inv.WeakSuites, ", "))
+	}
+
+	// The first scan of an endpoint is the baseline, every address behind a name has its own
+	key := data.Domain + ":" + strconv.Itoa(data.Port) + "@" + data.RemoteIP
+	fingerprint := inv.Fingerprint()
+
+	inventoryState.Lock()
+	previous, seen := inventoryState.last[key]
+	inventoryState.last[key] = fingerprint
+	inventoryState.Unlock()
+
+	if seen && previous != fingerprint {
+		AddFinding(data, "tls_inventory", models.SeverityWarning, "TLS configuration changed since the last scan: "+fingerprint)
+	}
+}
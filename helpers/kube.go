@@ -0,0 +1,95 @@
+package helpers
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"sentinel/logger"
+	"sentinel/models"
+	"sentinel/pkg/kube"
+)
+
+// Check every certificate embedded in the kubeconfig files and manifests of the target
+func CheckKubeTarget(target models.FileTarget, day int) (bool, []models.Log) {
+	if day <= 0 {
+		day = 30
+	}
+
+	isReported := false
+	var logs []models.Log
+	for _, file := range FileTargetFiles(target) {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			logger.CLogger.Error("Failed to read "+file+":", err)
+			continue
+		}
+		// Files that are not YAML are skipped, a broken document still keeps the ones before it
+		items, _ := kube.Parse(content)
+		for _, item := range items {
+			data := kubeItemToLog(file, item, target, day)
+			isReported = isReported || data.Status == 1
+			logs = append(logs, data)
+		}
+	}
+	return isReported, logs
+}
+
+func kubeItemToLog(file string, item kube.Item, target models.FileTarget, day int) models.Log {
+	data := models.Log{}
+	if len(item.Certificates) > 0 {
+		data = CertificateToLog(item.Certificates[0])
+		data.Domain = item.Certificates[0].Subject.CommonName
+	}
+	data.Source = "kubernetes"
+	data.Path = file
+	data.Tags = strings.Join(target.Tags, ", ")
+	data.KubeContext = strings.Join(item.Contexts, ", ")
+	data.KubeCluster = item.Cluster
+	data.KubeUser = item.User
+	data.KubeNamespace = item.Namespace
+	data.KubeSecret = item.Name
+	data.KubeField = item.Field
+	data.KeyMatch = item.KeyMatch
+
+	where := item.Field + " of " + kubeItemName(item) + " in " + file
+	if len(item.Certificates) == 0 {
+		data.Message = "No certificate could be decoded from " + where + "."
+		data.Status = 1
+		AddFinding(&data, "kubernetes", models.SeverityWarning, data.Message)
+		return data
+	}
+
+	chain := ChainCertificates(item.Certificates, ChainPresented)
+	earliest, _ := EarliestExpiry(chain)
+	data.ChainData = CertificatesToPEM(item.Certificates...)
+	data.Chain = chain
+	data.EffectiveExpiresOn = earliest.ExpiresOn
+	data.ExpiringElement = earliest.Label()
+
+	daysLeft := DaysUntil(earliest.ExpiresOn)
+	data.Message = fmt.Sprintf("Certificate in %s will expire in %d days.", where, daysLeft)
+	if earliest.Position != 0 {
+		data.Message = fmt.Sprintf("Certificate in %s will expire in %d days, %s expires first.", where, daysLeft, earliest.Label())
+	}
+	if item.KeyMatch == "mismatch" {
+		AddFinding(&data, "key_match", models.SeverityCritical, "Private key does not match the certificate in "+where+".")
+	}
+	if daysLeft < day || HasReportableFindings(&data) {
+		data.Status = 1
+	}
+	return data
+}
+
+// Secret namespace/name or the kubeconfig user or cluster
+func kubeItemName(item kube.Item) string {
+	switch {
+	case item.Kind == kube.KindSecret && item.Namespace != "":
+		return "secret " + item.Namespace + "/" + item.Name
+	case item.Kind == kube.KindSecret:
+		return "secret " + item.Name
+	case item.User != "":
+		return "user " + item.User
+	}
+	return "cluster " + item.Cluster
+}
@@ -0,0 +1,24 @@
+package helpers
+
+import (
+	"crypto/x509"
+
+	"sentinel/config"
+	"sentinel/models"
+	"sentinel/pkg/lint"
+)
+
+// Lint every presented certificate against the baseline requirements, results are reported per certificate
+func CheckLints(data *models.Log, target models.Target, certs []*x509.Certificate) {
+	suppressed := append(append([]string{}, config.C.Lint.Suppress...), target.SuppressLints...)
+
+	for i, cert := range certs {
+		element := ChainElement(data.Chain, ChainPresented, i)
+		for _, result := range lint.Run(cert, i > 0, suppressed) {
+			AddFinding(data, "lint:"+result.Lint, result.Severity, element.Label()+": "+result.Message)
+			if n := len(element.Lints); n == 0 || element.Lints[n-1] != result.Lint {
+				element.Lints = append(element.Lints, result.Lint)
+			}
+		}
+	}
+}
@@ -0,0 +1,238 @@
+package helpers
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"sentinel/logger"
+	"sentinel/models"
+	"sentinel/pkg/dnsquery"
+	"sentinel/pkg/mtasts"
+)
+
+// Shortest max_age worth having, RFC 8461 recommends weeks
+const minMTASTSMaxAge = 86400
+
+// Check the MTA-STS policy and TLS-RPT record of the mail domain and the certificate every MX serves
+// over STARTTLS. The first log describes the domain, one log per MX follows.
+func CheckMailDomain(domain models.MailDomain, day int) (bool, []models.Log) {
+	name := strings.ToLower(strings.TrimSuffix(domain.Domain, "."))
+	if day <= 0 {
+		day = 30
+	}
+	logger.CLogger.Info("INFO: Checking MTA-STS policy of " + name)
+
+	data := models.Log{Domain: name, Source: "mail", Tags: strings.Join(domain.Tags, ", ")}
+	checkTLSRPT(&data, domain.DNSServer, name)
+
+	hasRecord := checkSTSRecord(&data, domain.DNSServer, name)
+	policy, err := fetchMTASTSPolicy(domain, name)
+	switch {
+	case err != nil && !hasRecord:
+		data.Message = "Mail domain " + name + " does not publish an MTA-STS policy."
+		AddFinding(&data, "mta_sts", models.SeverityInfo, data.Message)
+		return finishMailDomain(data, nil)
+	case err != nil:
+		data.MTASTSError = err.Error()
+		data.Message = "MTA-STS policy of " + name + " could not be fetched."
+		AddFinding(&data, "mta_sts", models.SeverityCritical, "MTA-STS policy "+mtasts.PolicyURL(name)+" could not be fetched: "+err.Error())
+		return finishMailDomain(data, nil)
+	case !hasRecord:
+		AddFinding(&data, "mta_sts", models.SeverityWarning, "MTA-STS policy of "+name+" is served but no valid _mta-sts TXT record announces it, senders never fetch it.")
+	}
+
+	data.MTASTSMode = policy.Mode
+	data.MTASTSMaxAge = policy.MaxAge
+	data.MTASTSMX = strings.Join(policy.MX, ", ")
+	data.Message = fmt.Sprintf("MTA-STS policy of %s is in %s mode with max_age %d.", name, policy.Mode, policy.MaxAge)
+	switch policy.Mode {
+	case mtasts.ModeTesting:
+		AddFinding(&data, "mta_sts", models.SeverityInfo, "MTA-STS policy of "+name+" is in testing mode, senders report failures but still deliver.")
+	case mtasts.ModeNone:
+		AddFinding(&data, "mta_sts", models.SeverityInfo, "MTA-STS policy of "+name+" is in none mode, senders drop the policy.")
+		return finishMailDomain(data, nil)
+	}
+	switch {
+	case policy.MaxAge > mtasts.MaxAgeLimit:
+		AddFinding(&data, "mta_sts", models.SeverityWarning, fmt.Sprintf("MTA-STS max_age %d is above the one year limit of %d seconds.", policy.MaxAge, mtasts.MaxAgeLimit))
+	case policy.MaxAge < minMTASTSMaxAge:
+		AddFinding(&data, "mta_sts", models.SeverityWarning, fmt.Sprintf("MTA-STS max_age %d is shorter than a day, senders forget the policy between deliveries.", policy.MaxAge))
+	}
+
+	return finishMailDomain(data, checkMXCertificates(&data, domain, name, policy, day))
+}
+
+// The domain log is reported with its findings, each MX log with its own status
+func finishMailDomain(data models.Log, mx []models.Log) (bool, []models.Log) {
+	if HasReportableFindings(&data) {
+		data.Status = 1
+	}
+	isReported := data.Status != 0
+	for _, v := range mx {
+		isReported = isReported || v.Status != 0
+	}
+	return isReported, append([]models.Log{data}, mx...)
+}
+
+// Whether exactly one STSv1 record is published at _mta-sts, multiple ones disable MTA-STS (RFC 8461, 3.1)
+func checkSTSRecord(data *models.Log, server string, name string) bool {
+	records, err := dnsquery.LookupTXT(server, "_mta-sts."+name)
+	if err != nil {
+		data.MTASTSError = err.Error()
+		AddFinding(data, "mta_sts", models.SeverityInfo, "_mta-sts TXT record of "+name+" could not be resolved: "+err.Error())
+		return false
+	}
+
+	var ids []string
+	for _, record := range records {
+		if fields := mtasts.ParseRecord(record); fields["v"] == "STSv1" {
+			ids = append(ids, fields["id"])
+		}
+	}
+	switch {
+	case len(ids) > 1:
+		AddFinding(data, "mta_sts", models.SeverityWarning, "Mail domain "+name+" publishes "+strconv.Itoa(len(ids))+" _mta-sts TXT records, senders ignore all of them.")
+		return false
+	case len(ids) == 1 && ids[0] == "":
+		AddFinding(data, "mta_sts", models.SeverityWarning, "_mta-sts TXT record of "+name+" has no id, senders cannot tell when the policy changes.")
+	}
+	if len(ids) == 1 {
+		data.MTASTSID = ids[0]
+	}
+	return len(ids) == 1
+}
+
+// TLS-RPT record at _smtp._tls (RFC 8460), failures go unnoticed without one
+func checkTLSRPT(data *models.Log, server string, name string) {
+	records, err := dnsquery.LookupTXT(server, "_smtp._tls."+name)
+	if err != nil {
+		AddFinding(data, "tls_rpt", models.SeverityInfo, "_smtp._tls TXT record of "+name+" could not be resolved: "+err.Error())
+		return
+	}
+	for _, record := range records {
+		fields := mtasts.ParseRecord(record)
+		if fields["v"] != "TLSRPTv1" {
+			continue
+		}
+		data.TLSRPT = fields["rua"]
+		if fields["rua"] == "" {
+			AddFinding(data, "tls_rpt", models.SeverityWarning, "TLS-RPT record of "+name+" has no rua, reports have nowhere to go.")
+		}
+		return
+	}
+	AddFinding(data, "tls_rpt", models.SeverityInfo, "Mail domain "+name+" publishes no TLS-RPT record, delivery failures are not reported.")
+}
+
+// Fetch the policy through the domain egress, the policy host certificate must be valid and redirects are not followed
+func fetchMTASTSPolicy(domain models.MailDomain, name string) (mtasts.Policy, error) {
+	target := models.Target{Address: "mta-sts." + name + ":443", DNSServer: domain.DNSServer, Egress: domain.Egress}
+	dial, err := TargetDialer(target)
+	if err != nil {
+		return mtasts.Policy{}, err
+	}
+	client := &http.Client{
+		Timeout: dialTimeout,
+		Transport: &http.Transport{
+			// The policy host is resolved through the domain DNS server, SNI stays the host name
+			DialContext: func(ctx context.Context, network, _ string) (net.Conn, error) {
+				address, err := DialAddress(target)
+				if err != nil {
+					return nil, err
+				}
+				return dial(address)
+			},
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	defer client.CloseIdleConnections()
+	return mtasts.Fetch(client, name)
+}
+
+// Every MX has to be listed in the policy and serve a certificate valid for its name
+func checkMXCertificates(data *models.Log, domain models.MailDomain, name string, policy mtasts.Policy, day int) []models.Log {
+	severity := models.SeverityCritical
+	if policy.Mode == mtasts.ModeTesting {
+		severity = models.SeverityWarning
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), resolveTimeout)
+	defer cancel()
+	records, err := TargetResolver(models.Target{DNSServer: domain.DNSServer}).LookupMX(ctx, name)
+	if err != nil {
+		AddFinding(data, "mta_sts", models.SeverityInfo, "MX records of "+name+" could not be resolved: "+err.Error())
+		return nil
+	}
+
+	var logs []models.Log
+	for _, record := range records {
+		host := strings.ToLower(strings.TrimSuffix(record.Host, "."))
+		if host == "" {
+			continue // null MX, the domain accepts no mail
+		}
+		if !policy.Matches(host) {
+			AddFinding(data, "mta_sts", severity, "MX "+host+" is not listed in the MTA-STS policy of "+name+", senders applying it will not deliver there.")
+		}
+
+		target := models.Target{
+			Address:   net.JoinHostPort(host, "25"),
+			Tags:      domain.Tags,
+			DNSServer: domain.DNSServer,
+			Egress:    domain.Egress,
+			StartTLS:  StartTLSSMTP,
+			Parent:    name,
+		}
+		_, mx := CheckDomainCertificate(target, day)
+		if mx == nil {
+			AddFinding(data, "mta_sts", severity, "MX "+host+" of "+name+" could not be checked over STARTTLS.")
+			logs = append(logs, models.Log{
+				Domain:  host,
+				Port:    25,
+				Source:  "server",
+				Parent:  name,
+				Tags:    strings.Join(domain.Tags, ", "),
+				Message: "Connection to MX " + host + " failed.",
+				Status:  2,
+			})
+			continue
+		}
+
+		mx.Parent = name
+		if problem := mxCertificateProblem(mx, host); problem != "" {
+			message := "MX " + host + " of " + name + " serves a certificate senders applying MTA-STS reject: " + problem
+			AddFinding(mx, "mta_sts", severity, message)
+			AddFinding(data, "mta_sts", severity, message)
+		}
+		if HasReportableFindings(mx) {
+			mx.Status = 1
+		}
+		logs = append(logs, *mx)
+	}
+	return logs
+}
+
+// Why the certificate of the MX fails MTA-STS validation (RFC 8461, 4.2), empty when it passes
+func mxCertificateProblem(mx *models.Log, host string) string {
+	if mx.ChainError != "" {
+		return mx.ChainError
+	}
+	block, _ := pem.Decode([]byte(mx.CertificateData))
+	if block == nil {
+		return "no certificate"
+	}
+	leaf, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return err.Error()
+	}
+	if err := leaf.VerifyHostname(host); err != nil {
+		return err.Error()
+	}
+	return ""
+}
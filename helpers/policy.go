@@ -0,0 +1,16 @@
+package helpers
+
+import (
+	"crypto/x509"
+
+	"sentinel/config"
+	"sentinel/models"
+	"sentinel/pkg/policy"
+)
+
+// Evaluate the configured policies against the probed hostname and add each violation as a finding
+func CheckPolicies(data *models.Log, target models.Target, hostname string, certs []*x509.Certificate) {
+	for _, v := range policy.Evaluate(config.C.Policies, target, hostname, certs) {
+		AddFinding(data, "policy:"+v.Policy, v.Severity, v.Message)
+	}
+}
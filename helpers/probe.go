@@ -0,0 +1,135 @@
+package helpers
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"sentinel/config"
+	"sentinel/models"
+	"sentinel/pkg/egress"
+)
+
+// Timeout of the A/AAAA lookup
+const resolveTimeout = 10 * time.Second
+
+// Timeout of a probe connection including the proxy handshake
+const dialTimeout = 10 * time.Second
+
+// Split the target address into domain and port
+func SplitTarget(target models.Target) (string, string, error) {
+	return net.SplitHostPort(target.Address)
+}
+
+// SNI sent in the handshake, the domain unless overridden
+func TargetServerName(target models.Target) string {
+	if target.ServerName != "" {
+		return target.ServerName
+	}
+	host, _, _ := SplitTarget(target)
+	return host
+}
+
+// HTTP Host header, the SNI unless overridden, with the port when it is not 443
+func TargetHostHeader(target models.Target) string {
+	if target.HostHeader != "" {
+		return target.HostHeader
+	}
+	if _, port, err := SplitTarget(target); err == nil && port != "443" {
+		return net.JoinHostPort(TargetServerName(target), port)
+	}
+	return TargetServerName(target)
+}
+
+// Resolver of the target, queries the custom DNS server when one is set
+func TargetResolver(target models.Target) *net.Resolver {
+	if target.DNSServer == "" {
+		return net.DefaultResolver
+	}
+
+	server := target.DNSServer
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		server = net.JoinHostPort(server, "53")
+	}
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, server)
+		},
+	}
+}
+
+// Addresses to probe, a fixed connect IP skips the lookup
+func ResolveTarget(target models.Target) ([]net.IP, error) {
+	if target.ConnectIP != "" {
+		ip := net.ParseIP(target.ConnectIP)
+		if ip == nil {
+			return nil, &net.ParseError{Type: "IP address", Text: target.ConnectIP}
+		}
+		return []net.IP{ip}, nil
+	}
+
+	host, _, err := SplitTarget(target)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), resolveTimeout)
+	defer cancel()
+	addrs, err := TargetResolver(target).LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	var ips []net.IP
+	for _, addr := range addrs {
+		ips = append(ips, addr.IP)
+	}
+	return ips, nil
+}
+
+// Address the single probe connects to, the system resolver handles plain targets at dial time
+func DialAddress(target models.Target) (string, error) {
+	if target.ConnectIP == "" && target.DNSServer == "" {
+		return target.Address, nil
+	}
+
+	_, port, err := SplitTarget(target)
+	if err != nil {
+		return "", err
+	}
+	ips, err := ResolveTarget(target)
+	if err != nil {
+		return "", err
+	}
+	return net.JoinHostPort(ips[0].String(), port), nil
+}
+
+// Egress settings of the target, the global egress unless the target sets its own
+func TargetEgress(target models.Target) models.Egress {
+	if target.Egress != nil {
+		return *target.Egress
+	}
+	return config.C.Egress
+}
+
+// Dial function of the target egress
+func TargetDialer(target models.Target) (egress.DialFunc, error) {
+	return egress.Dialer(TargetEgress(target), dialTimeout)
+}
+
+// IP address the certificate was served from, unknown when a proxy resolved the domain
+func remoteIP(conn net.Conn, address string, target models.Target) string {
+	if host, _, err := net.SplitHostPort(address); err == nil && net.ParseIP(host) != nil {
+		return host
+	}
+	e := TargetEgress(target)
+	if e.HTTPProxy != "" || e.SOCKS5Proxy != "" {
+		return ""
+	}
+	if addr, ok := conn.RemoteAddr().(*net.TCPAddr); ok {
+		return addr.IP.String()
+	}
+	return ""
+}
@@ -0,0 +1,73 @@
+package helpers
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"sentinel/models"
+	"sentinel/pkg/rdap"
+)
+
+// RDAP statuses of a domain that does not resolve or is about to be deleted
+var registrationHoldStatuses = []string{"client hold", "server hold", "inactive", "redemption period", "pending restore", "pending delete"}
+
+// Look up the registration of the registrable domain of the target over RDAP and add it to the logs
+func CheckRegistration(target models.Target, logs []models.Log, day int) bool {
+	host, _, err := SplitTarget(target)
+	if err != nil || net.ParseIP(host) != nil {
+		return false
+	}
+	domain, ok := rdap.RegistrableDomain(host)
+	if !ok {
+		return false
+	}
+	if day <= 0 {
+		day = 30
+	}
+
+	registration, err := rdap.Default.Lookup(domain)
+	isReported := false
+	for i := range logs {
+		data := &logs[i]
+		data.RegisteredDomain = domain
+		if err != nil {
+			data.RegistrationError = err.Error()
+			// Internal TLDs have no RDAP server, only a missing registration is worth a finding
+			if errors.Is(err, rdap.ErrNotFound) {
+				AddFinding(data, "domain_registration", models.SeverityWarning, "Domain "+domain+" is not registered.")
+			}
+		} else {
+			data.Registrar = registration.Registrar
+			data.DomainExpiresOn = registration.Expiration
+			data.DomainStatus = strings.Join(registration.Status, ", ")
+			addRegistrationFindings(data, registration, day)
+		}
+
+		if HasReportableFindings(data) {
+			data.Status = 1
+			isReported = true
+		}
+	}
+	return isReported
+}
+
+func addRegistrationFindings(data *models.Log, registration rdap.Registration, day int) {
+	if !registration.Expiration.IsZero() {
+		daysLeft := DaysUntil(registration.Expiration)
+		switch {
+		case registration.Expiration.Before(time.Now()):
+			AddFinding(data, "domain_registration", models.SeverityCritical, fmt.Sprintf("Domain registration of %s expired on %s.", registration.Domain, TimeFormatter(registration.Expiration)))
+		case daysLeft < day:
+			AddFinding(data, "domain_registration", models.SeverityWarning, fmt.Sprintf("Domain registration of %s will expire in %d days.", registration.Domain, daysLeft))
+		}
+	}
+
+	for _, status := range registration.Status {
+		if containsString(registrationHoldStatuses, strings.ToLower(status)) {
+			AddFinding(data, "domain_status", models.SeverityCritical, "Domain "+registration.Domain+" has status "+status+".")
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package helpers
+
+import (
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"sentinel/config"
+	"sentinel/logger"
+	"sentinel/models"
+	"sentinel/pkg/egress"
+)
+
+// Connection attempts per address when probing every address of a name
+const addressAttempts = 3
+
+// Pause between two attempts on the failed addresses
+const addressRetryDelay = 2 * time.Second
+
+// Check the target, every resolved address is probed on its own when resolve_all is set
+func CheckTarget(target models.Target, day int) (bool, []models.Log) {
+	var isReported bool
+	var logs []models.Log
+	if !target.ResolveAll && !config.C.Scan.ResolveAll {
+		isOK, data := CheckDomainCertificate(target, day)
+		if data != nil {
+			isReported, logs = isOK, []models.Log{*data}
+		}
+	} else {
+		isReported, logs = CheckAllAddresses(target, day)
+	}
+
+	// Domain registration expiry takes down every certificate on the domain
+	if config.C.RDAP.Enabled && len(logs) > 0 && CheckRegistration(target, logs, day) {
+		isReported = true
+	}
+
+	// Hosts found on the landing page are not crawled again
+	if (config.C.Scan.Crawl || target.Crawl) && target.Parent == "" && len(logs) > 0 {
+		isOK, derived := CheckDependencies(target, day, logs)
+		isReported = isReported || isOK
+		logs = append(logs, derived...)
+	}
+
+	// The client certificate is reported with the server result, a failed probe is left to the retries
+	if target.ClientCert != nil && len(logs) > 0 {
+		isOK, data := CheckClientCertificate(target, day)
+		isReported = isReported || isOK
+		logs = append(logs, *data)
+	}
+	return isReported, logs
+}
+
+// Probe every IPv4 and IPv6 address of the domain with the SNI of the target
+func CheckAllAddresses(target models.Target, day int) (bool, []models.Log) {
+	host, portString, err := net.SplitHostPort(target.Address)
+	if err != nil {
+		logger.CLogger.Error("Invalid target address "+target.Address+":", err)
+		return false, nil
+	}
+	port, _ := strconv.Atoi(portString)
+
+	resolved, err := ResolveTarget(target)
+	if err != nil {
+		logger.CLogger.Error("Failed to resolve "+host+":", err)
+		return false, nil
+	}
+
+	// An IPv4-only host cannot tell a dead AAAA address from a missing route
+	var ips []net.IP
+	for _, ip := range resolved {
+		if egress.Routable(TargetEgress(target), ip) {
+			ips = append(ips, ip)
+		} else {
+			logger.CLogger.Info("INFO: Skipping " + ip.String() + " of " + host + ", no route to its address family")
+		}
+	}
+
+	// Failed addresses are retried while the others answer, a name without any answer is retried by the caller
+	results := map[string]*models.Log{}
+	reported := map[string]bool{}
+	pending := ips
+	for attempt := 1; attempt <= addressAttempts && len(pending) > 0; attempt++ {
+		if attempt > 1 {
+			time.Sleep(addressRetryDelay)
+		}
+		var failed []net.IP
+		for _, ip := range pending {
+			isOK, data := checkCertificate(target, net.JoinHostPort(ip.String(), portString), day)
+			if data == nil {
+				logger.CLogger.Error("ERROR: ", host+" "+ip.String()+" - Connection Error Attempt: "+strconv.Itoa(attempt)+"/"+strconv.Itoa(addressAttempts))
+				failed = append(failed, ip)
+				continue
+			}
+			results[ip.String()], reported[ip.String()] = data, isOK
+		}
+		if len(results) == 0 {
+			return false, nil
+		}
+		pending = failed
+	}
+
+	isReported := false
+	var logs []models.Log
+	for _, ip := range ips {
+		data, ok := results[ip.String()]
+		if !ok {
+			if !config.C.Scan.ReportUnreachable {
+				continue
+			}
+			// An unreachable node behind an otherwise answering name
+			data = &models.Log{
+				Domain:   host,
+				Port:     port,
+				RemoteIP: ip.String(),
+				Source:   "server",
+				Tags:     strings.Join(target.Tags, ", "),
+				Message:  "Connection to " + ip.String() + " failed.",
+				Status:   2,
+			}
+			reported[ip.String()] = true
+		}
+		isReported = isReported || reported[ip.String()]
+		logs = append(logs, *data)
+	}
+
+	if CheckAddressConsistency(logs) {
+		isReported = true
+	}
+	return isReported, logs
+}
+
+// Add a finding to every address log when the addresses serve different certificates
+func CheckAddressConsistency(logs []models.Log) bool {
+	served := map[string][]string{}
+	for _, data := range logs {
+		if data.FingerprintSHA256 != "" {
+			served[data.FingerprintSHA256] = append(served[data.FingerprintSHA256], data.RemoteIP)
+		}
+	}
+	if len(served) < 2 {
+		return false
+	}
+
+	var groups []string
+	for fingerprint, ips := range served {
+		groups = append(groups, strings.Join(ips, ", ")+" serve serial "+serialOf(logs, fingerprint))
+	}
+	sort.Strings(groups)
+	message := "Addresses serve different certificates: " + strings.Join(groups, "; ")
+
+	for i := range logs {
+		if logs[i].FingerprintSHA256 == "" {
+			continue
+		}
+		AddFinding(&logs[i], "address_consistency", models.SeverityWarning, message)
+		logs[i].Status = 1
+	}
+	return true
+}
+
+func serialOf(logs []models.Log, fingerprint string) string {
+	for _, data := range logs {
+		if data.FingerprintSHA256 == fingerprint {
+			return data.SerialNumber
+		}
+	}
+	return ""
+}
@@ -0,0 +1,65 @@
+package helpers
+
+import (
+	"crypto/x509"
+
+	"sentinel/config"
+	"sentinel/models"
+	"sentinel/pkg/revocation"
+)
+
+// Check revocation over OCSP, the stapled response and CRLs, returns the label of the first revoked element
+func CheckRevocation(data *models.Log, certs []*x509.Certificate, verified []*x509.Certificate, staple []byte) string {
+	revoked := ""
+	leaf := certs[0]
+	issuer := revocation.FindIssuer(leaf, verified, certs)
+
+	// Stapled response is always inspected, it costs no request
+	stapled := revocation.CheckStaple(staple, leaf, issuer)
+	data.OCSPStapled = stapled.Stapled
+	data.OCSPStapleFresh = stapled.Fresh
+	data.OCSPStapleStatus = stapled.Status
+	if stapled.Status == revocation.StatusRevoked {
+		revoked = "leaf"
+	}
+
+	data.OCSPStatus = revocation.StatusNotChecked
+	if config.C.Revocation.OCSP {
+		result := revocation.CheckOCSP(leaf, issuer)
+		data.OCSPStatus = result.Status
+		data.OCSPResponder = result.Responder
+		data.OCSPError = result.Error
+		data.RevokedAt = result.RevokedAt
+		if result.Status == revocation.StatusRevoked {
+			revoked = "leaf"
+		}
+	}
+
+	// Serial lookup for every presented certificate, issuers come from the rest of the chain
+	data.CRLStatus = revocation.StatusNotChecked
+	if config.C.Revocation.CRL {
+		for i, cert := range certs {
+			if i > 0 && cert.Subject.String() == cert.Issuer.String() {
+				continue // self-signed roots are not revocable by CRL
+			}
+
+			result := revocation.CheckCRL(cert, revocation.FindIssuer(cert, verified, certs))
+			element := ChainElement(data.Chain, ChainPresented, i)
+			element.CRLStatus = result.Status
+
+			if i == 0 {
+				data.CRLStatus = result.Status
+				data.CRLURL = result.URL
+				data.CRLError = result.Error
+				if result.Status == revocation.StatusRevoked && data.RevokedAt.IsZero() {
+					data.RevokedAt = result.RevokedAt
+				}
+			}
+			if result.Status == revocation.StatusRevoked && revoked == "" {
+				revoked = element.Label()
+			}
+		}
+	}
+
+	return revoked
+}
@@ -0,0 +1,103 @@
+package helpers
+
+import (
+	"crypto/x509"
+	"errors"
+
+	"sentinel/config"
+	"sentinel/logger"
+	"sentinel/models"
+	"sentinel/pkg/tlsscan"
+)
+
+// Simulate the handshake of every configured client and add a finding for each one that fails
+func CheckClientSimulation(data *models.Log, dial tlsscan.DialFunc, serverName string, certs []*x509.Certificate) {
+	configured := config.C.Simulation.Profiles
+	if len(configured) == 0 {
+		for _, profile := range tlsscan.Profiles {
+			configured = append(configured, models.ClientProfile{Name: profile.Name})
+		}
+	}
+
+	for _, client := range configured {
+		profile, err := ClientProfile(client)
+		if err != nil {
+			logger.CLogger.Error("Invalid client profile "+client.Name+":", err)
+			continue
+		}
+
+		simulation, err := tlsscan.Simulate(dial, serverName, profile, certs)
+		if err != nil {
+			// Without an answer of the server there is nothing to say about the client
+			logger.CLogger.Error("Client simulation of "+profile.Name+" against "+data.Domain+" failed:", err)
+			continue
+		}
+		data.ClientSimulations = append(data.ClientSimulations, models.ClientSimulation{
+			Client:      simulation.Client,
+			Success:     simulation.Success,
+			Version:     simulation.Version,
+			CipherSuite: simulation.Suite,
+			Reason:      simulation.Reason,
+		})
+		if !simulation.Success {
+			severity := models.SeverityInfo
+			if client.Required {
+				severity = models.SeverityWarning
+			}
+			AddFinding(data, "client_simulation", severity, profile.Name+" cannot connect: "+simulation.Reason)
+		}
+	}
+}
+
+// Handshake profile of the configured client, a built-in client unless cipher suites are given
+func ClientProfile(client models.ClientProfile) (tlsscan.Profile, error) {
+	if profile, ok := tlsscan.ProfileByName(client.Name); ok && len(client.CipherSuites) == 0 {
+		return profile, nil
+	}
+	if len(client.CipherSuites) == 0 {
+		return tlsscan.Profile{}, errors.New("no cipher suites and no built-in client of that name")
+	}
+
+	profile := tlsscan.Profile{
+		Name:       client.Name,
+		MinVersion: tlsscan.VersionTLS10,
+		MaxVersion: tlsscan.VersionTLS13,
+		Groups:     tlsscan.Groups,
+		SNI:        client.SNI == nil || *client.SNI,
+	}
+	var err error
+	if client.MinVersion != "" {
+		if profile.MinVersion, err = tlsscan.VersionByName(client.MinVersion); err != nil {
+			return profile, err
+		}
+	}
+	if client.MaxVersion != "" {
+		if profile.MaxVersion, err = tlsscan.VersionByName(client.MaxVersion); err != nil {
+			return profile, err
+		}
+	}
+	if profile.Suites, err = idsByName(client.CipherSuites, tlsscan.SuiteByName); err != nil {
+		return profile, err
+	}
+	if len(client.Groups) > 0 {
+		if profile.Groups, err = idsByName(client.Groups, tlsscan.GroupByName); err != nil {
+			return profile, err
+		}
+	}
+	if profile.SignatureAlgorithms, err = idsByName(client.SignatureAlgorithms, tlsscan.SignatureAlgorithmByName); err != nil {
+		return profile, err
+	}
+	return profile, nil
+}
+
+func idsByName(names []string, lookup func(string) (uint16, error)) ([]uint16, error) {
+	var ids []uint16
+	for _, name := range names {
+		id, err := lookup(name)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
@@ -0,0 +1,77 @@
+package helpers
+
+import (
+	"testing"
+
+	"sentinel/models"
+	"sentinel/pkg/tlsscan"
+)
+
+func TestClientProfile(t *testing.T) {
+	noSNI := false
+	java8, _ := tlsscan.ProfileByName("Java 8")
+	tests := []struct {
+		name    string
+		client  models.ClientProfile
+		want    tlsscan.Profile
+		wantErr bool
+	}{
+		{"built-in client", models.ClientProfile{Name: "Java 8"}, java8, false},
+		{"custom client", models.ClientProfile{
+			Name:                "Legacy app",
+			MinVersion:          "tls 1.2",
+			MaxVersion:          "TLS 1.2",
+			CipherSuites:        []string{"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256", " TLS_RSA_WITH_AES_128_CBC_SHA "},
+			Groups:              []string{"secp256r1", "X25519"},
+			SignatureAlgorithms: []string{"rsa_pkcs1_sha256"},
+			SNI:                 &noSNI,
+		}, tlsscan.Profile{
+			Name:                "Legacy app",
+			MinVersion:          tlsscan.VersionTLS12,
+			MaxVersion:          tlsscan.VersionTLS12,
+			Suites:              []uint16{0xc02f, 0x002f},
+			Groups:              []uint16{tlsscan.GroupSecp256r1, tlsscan.GroupX25519},
+			SignatureAlgorithms: []uint16{0x0401},
+		}, false},
+		{"defaults of a custom client", models.ClientProfile{Name: "Java 8", CipherSuites: []string{"TLS_AES_128_GCM_SHA256"}}, tlsscan.Profile{
+			Name:       "Java 8",
+			MinVersion: tlsscan.VersionTLS10,
+			MaxVersion: tlsscan.VersionTLS13,
+			Suites:     []uint16{0x1301},
+			Groups:     tlsscan.Groups,
+			SNI:        true,
+		}, false},
+		{"unknown client", models.ClientProfile{Name: "Netscape 4"}, tlsscan.Profile{}, true},
+		{"unknown suite", models.ClientProfile{Name: "app", CipherSuites: []string{"TLS_RSA_WITH_RC5"}}, tlsscan.Profile{}, true},
+		{"unknown version", models.ClientProfile{Name: "app", MinVersion: "TLS 2.0", CipherSuites: []string{"TLS_AES_128_GCM_SHA256"}}, tlsscan.Profile{}, true},
+		{"unknown group", models.ClientProfile{Name: "app", CipherSuites: []string{"TLS_AES_128_GCM_SHA256"}, Groups: []string{"curve25519"}}, tlsscan.Profile{}, true},
+		{"unknown signature algorithm", models.ClientProfile{Name: "app", CipherSuites: []string{"TLS_AES_128_GCM_SHA256"}, SignatureAlgorithms: []string{"dsa_sha1"}}, tlsscan.Profile{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			profile, err := ClientProfile(tt.client)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if profile.Name != tt.want.Name || profile.MinVersion != tt.want.MinVersion || profile.MaxVersion != tt.want.MaxVersion || profile.SNI != tt.want.SNI ||
+				!equalIDs(profile.Suites, tt.want.Suites) || !equalIDs(profile.Groups, tt.want.Groups) || !equalIDs(profile.SignatureAlgorithms, tt.want.SignatureAlgorithms) {
+				t.Errorf("profile = %+v, want %+v", profile, tt.want)
+			}
+		})
+	}
+}
+
+func equalIDs(a, b []uint16) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
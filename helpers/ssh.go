@@ -0,0 +1,192 @@
+package helpers
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"sentinel/config"
+	"sentinel/logger"
+	"sentinel/models"
+	"sentinel/pkg/sshcert"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// Check every OpenSSH certificate file of the target
+func CheckSSHFileTarget(target models.FileTarget, day int) (bool, []models.Log) {
+	isReported := false
+	var logs []models.Log
+	for _, file := range FileTargetFiles(target) {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			logger.CLogger.Error("Failed to read "+file+":", err)
+			continue
+		}
+		cert, err := sshcert.Parse(content)
+		if err != nil {
+			// Plain public keys next to the certificates are expected
+			continue
+		}
+
+		data := SSHCertificateToLog(cert, file, day)
+		data.Path = file
+		data.Tags = strings.Join(target.Tags, ", ")
+		isReported = isReported || data.Status == 1
+		logs = append(logs, data)
+	}
+	return isReported, logs
+}
+
+// Read the host certificate of an SSH server during the key exchange
+func CheckSSHHost(target models.Target, day int) (bool, *models.Log) {
+	host, portString, err := SplitTarget(target)
+	if err != nil {
+		logger.CLogger.Error("Invalid target address "+target.Address+":", err)
+		return false, nil
+	}
+	port, _ := strconv.Atoi(portString)
+
+	var address string
+	var conn net.Conn
+	var key ssh.PublicKey
+	for attempt := 1; attempt <= addressAttempts; attempt++ {
+		if attempt > 1 {
+			time.Sleep(addressRetryDelay)
+		}
+		address, conn, key, err = fetchSSHHostKey(target)
+		if err == nil {
+			break
+		}
+		logger.CLogger.Error("ERROR: ", target.Address+" - SSH key exchange failed: "+err.Error()+" Attempt: "+strconv.Itoa(attempt)+"/"+strconv.Itoa(addressAttempts))
+	}
+	if err != nil {
+		// A server that stays down is only logged, like a failed TLS probe
+		if !config.C.Scan.ReportUnreachable {
+			return false, nil
+		}
+		return true, &models.Log{
+			Domain:  host,
+			Port:    port,
+			Source:  "ssh",
+			Tags:    strings.Join(target.Tags, ", "),
+			Message: "SSH key exchange with " + target.Address + " failed: " + err.Error(),
+			Status:  2,
+		}
+	}
+	defer conn.Close()
+	return sshHostKeyToLog(target, host, port, address, conn, key, day)
+}
+
+func fetchSSHHostKey(target models.Target) (string, net.Conn, ssh.PublicKey, error) {
+	address, err := DialAddress(target)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	dial, err := TargetDialer(target)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	conn, err := dial(address)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	key, err := sshcert.FetchHostKey(conn, address, dialTimeout)
+	if err != nil {
+		conn.Close()
+		return "", nil, nil, err
+	}
+	return address, conn, key, nil
+}
+
+func sshHostKeyToLog(target models.Target, host string, port int, address string, conn net.Conn, key ssh.PublicKey, day int) (bool, *models.Log) {
+	cert, ok := key.(*ssh.Certificate)
+	if !ok {
+		data := &models.Log{
+			Domain:             host,
+			Port:               port,
+			Source:             "ssh",
+			PublicKeyAlgorithm: key.Type(),
+			FingerprintSHA256:  ssh.FingerprintSHA256(key),
+			Tags:               strings.Join(target.Tags, ", "),
+			Message:            "Server presents a plain host key, no host certificate.",
+		}
+		AddFinding(data, "ssh_host_certificate", models.SeverityInfo, data.Message)
+		return false, data
+	}
+
+	data := SSHCertificateToLog(cert, target.Address, day)
+	data.Domain = host
+	data.Port = port
+	data.RemoteIP = remoteIP(conn, address, target)
+	data.Tags = strings.Join(target.Tags, ", ")
+
+	// Clients reject a host certificate that does not name the host
+	if len(cert.ValidPrincipals) > 0 && !containsString(cert.ValidPrincipals, host) {
+		AddFinding(&data, "ssh_principals", models.SeverityWarning, "Host certificate principals "+data.SSHPrincipals+" do not include "+host+".")
+	}
+	if cert.CertType != ssh.HostCert {
+		AddFinding(&data, "ssh_host_certificate", models.SeverityWarning, "Server presents a user certificate as host key.")
+	}
+	if HasReportableFindings(&data) {
+		data.Status = 1
+	}
+	return data.Status == 1, &data
+}
+
+// Convert an OpenSSH certificate found at where to a log, expiry is judged like X.509 certificates
+func SSHCertificateToLog(cert *ssh.Certificate, where string, day int) models.Log {
+	if day <= 0 {
+		day = 30
+	}
+	validAfter, validBefore := sshcert.Validity(cert)
+	signingCA := ssh.FingerprintSHA256(cert.SignatureKey)
+
+	data := models.Log{
+		Source:             "ssh",
+		SerialNumber:       strconv.FormatUint(cert.Serial, 10),
+		Subject:            cert.KeyId,
+		CommonName:         cert.KeyId,
+		IssuerSubject:      signingCA,
+		Issuer:             cert.SignatureKey.Type() + " " + signingCA,
+		IssuedOn:           validAfter,
+		ExpiresOn:          validBefore,
+		EffectiveExpiresOn: validBefore,
+		PublicKeyAlgorithm: cert.Key.Type(),
+		FingerprintSHA256:  ssh.FingerprintSHA256(cert.Key),
+		SignatureAlgorithm: cert.Signature.Format,
+		SSHCertType:        sshcert.CertType(cert),
+		SSHKeyID:           cert.KeyId,
+		SSHPrincipals:      strings.Join(cert.ValidPrincipals, ", "),
+		SSHSigningCA:       signingCA,
+	}
+
+	if validBefore.IsZero() {
+		data.Message = fmt.Sprintf("SSH %s certificate %q of %s never expires.", data.SSHCertType, cert.KeyId, where)
+	} else {
+		daysLeft := DaysUntil(validBefore)
+		data.Message = fmt.Sprintf("SSH %s certificate %q of %s will expire in %d days.", data.SSHCertType, cert.KeyId, where, daysLeft)
+		if daysLeft < day {
+			data.Status = 1
+		}
+	}
+	if validAfter.After(time.Now()) {
+		AddFinding(&data, "ssh_validity", models.SeverityWarning, "SSH certificate is not valid before "+TimeFormatter(validAfter)+".")
+	}
+	if HasReportableFindings(&data) {
+		data.Status = 1
+	}
+	return data
+}
+
+func containsString(list []string, v string) bool {
+	for _, item := range list {
+		if item == v {
+			return true
+		}
+	}
+	return false
+}
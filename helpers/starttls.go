@@ -0,0 +1,79 @@
+package helpers
+
+import (
+	"fmt"
+	"net"
+	"net/textproto"
+	"strings"
+	"time"
+
+	"sentinel/models"
+)
+
+// STARTTLS protocols
+const (
+	StartTLSNone = "none"
+	StartTLSSMTP = "smtp"
+)
+
+// STARTTLS protocol of the target, SMTP submission and relay ports upgrade by default
+func TargetStartTLS(target models.Target) string {
+	if target.StartTLS != "" {
+		return strings.ToLower(target.StartTLS)
+	}
+	if _, port, err := SplitTarget(target); err == nil && (port == "25" || port == "587") {
+		return StartTLSSMTP
+	}
+	return StartTLSNone
+}
+
+// Upgrade a plain connection with STARTTLS, the TLS handshake is left to the caller
+func StartTLS(conn net.Conn, protocol string, hostname string) error {
+	switch protocol {
+	case "", StartTLSNone:
+		return nil
+	case StartTLSSMTP:
+		conn.SetDeadline(time.Now().Add(dialTimeout))
+		defer conn.SetDeadline(time.Time{})
+		return startSMTP(textproto.NewConn(conn), hostname)
+	}
+	return fmt.Errorf("unsupported STARTTLS protocol %q", protocol)
+}
+
+// Greeting, EHLO and STARTTLS (RFC 3207), the server talks TLS after the 220 reply
+func startSMTP(text *textproto.Conn, hostname string) error {
+	if _, _, err := text.ReadResponse(220); err != nil {
+		return err
+	}
+	id, err := text.Cmd("EHLO %s", hostname)
+	if err != nil {
+		return err
+	}
+	text.StartResponse(id)
+	_, extensions, err := text.ReadResponse(250)
+	text.EndResponse(id)
+	if err != nil {
+		return err
+	}
+	if !hasSMTPExtension(extensions, "STARTTLS") {
+		return fmt.Errorf("SMTP server does not offer STARTTLS")
+	}
+
+	id, err = text.Cmd("STARTTLS")
+	if err != nil {
+		return err
+	}
+	text.StartResponse(id)
+	defer text.EndResponse(id)
+	_, _, err = text.ReadResponse(220)
+	return err
+}
+
+func hasSMTPExtension(extensions string, name string) bool {
+	for _, line := range strings.Split(extensions, "\n") {
+		if fields := strings.Fields(line); len(fields) > 0 && strings.EqualFold(fields[0], name) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,125 @@
+package helpers
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"sentinel/logger"
+	"sentinel/models"
+	"sentinel/pkg/certfile"
+)
+
+// Root store loaded from the configured bundle files
+type RootStore struct {
+	Name  string
+	Tags  []string
+	Roots []*x509.Certificate
+	pool  *x509.CertPool
+}
+
+// Root stores every scanned chain is verified against, loaded at startup
+var RootStores []RootStore
+
+// Load the configured trust stores, roots expiring within day days are logged with their store
+func LoadRootStores(stores []models.TrustStore, day int) []RootStore {
+	if day <= 0 {
+		day = 30
+	}
+
+	var loaded []RootStore
+	for _, store := range stores {
+		files := FileTargetFiles(models.FileTarget{Path: store.Path, Recursive: true})
+		passwords := FileTargetPasswords(models.FileTarget{Passwords: store.Passwords, PasswordFiles: store.PasswordFiles})
+
+		root := RootStore{Name: store.Name, Tags: store.Tags, pool: x509.NewCertPool()}
+		seen := map[string]bool{}
+		for _, file := range files {
+			data, err := os.ReadFile(file)
+			if err != nil {
+				logger.CLogger.Error("Failed to read trust store file "+file+":", err)
+				continue
+			}
+			bundle, err := certfile.Parse(data, passwords)
+			if errors.Is(err, certfile.ErrUnrecognized) {
+				continue
+			}
+			if err != nil && len(bundle.Certificates) == 0 {
+				logger.CLogger.Error("Failed to parse trust store file "+file+":", err)
+				continue
+			}
+			for _, cert := range bundle.Certificates {
+				if !seen[string(cert.Raw)] {
+					seen[string(cert.Raw)] = true
+					root.Roots = append(root.Roots, cert)
+					root.pool.AddCert(cert)
+				}
+			}
+		}
+		if len(root.Roots) == 0 {
+			logger.CLogger.Error("INIT: Trust store " + store.Name + " has no certificates in " + store.Path)
+			continue
+		}
+
+		logger.CLogger.Info(fmt.Sprintf("INIT: Trust store %s loaded with %d roots.", store.Name, len(root.Roots)))
+		for _, cert := range root.Roots {
+			if daysLeft := DaysUntil(cert.NotAfter); daysLeft < day {
+				logger.CLogger.Warn(fmt.Sprintf("INIT: Root %s in trust store %s expires in %d days.", cert.Subject.String(), store.Name, daysLeft))
+			}
+		}
+		loaded = append(loaded, root)
+	}
+	return loaded
+}
+
+// Verify the presented chain against every root store applying to the target.
+// A store that does not trust the chain or anchors it in a root about to expire raises a finding.
+func CheckTrustMatrix(data *models.Log, target models.Target, certs []*x509.Certificate, day int) {
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+
+	for _, store := range RootStores {
+		if len(store.Tags) > 0 && !target.HasAnyTag(store.Tags) {
+			continue
+		}
+
+		result := models.TrustResult{Store: store.Name}
+		chains, err := certs[0].Verify(x509.VerifyOptions{
+			Roots:         store.pool,
+			Intermediates: intermediates,
+			KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+		})
+		if err != nil {
+			result.Error = err.Error()
+			AddFinding(data, "trust_store", models.SeverityWarning, "Chain is not trusted by the "+store.Name+" trust store: "+err.Error())
+			data.Trust = append(data.Trust, result)
+			continue
+		}
+
+		// Clients pick any path they can build, the one with the longest lived root is what the endpoint depends on
+		root := chains[0][len(chains[0])-1]
+		for _, chain := range chains[1:] {
+			if candidate := chain[len(chain)-1]; candidate.NotAfter.After(root.NotAfter) {
+				root = candidate
+			}
+		}
+		result.Trusted = true
+		result.Root = root.Subject.String()
+		result.RootExpiresOn = root.NotAfter
+		if daysLeft := DaysUntil(root.NotAfter); daysLeft < day {
+			AddFinding(data, "trust_store", models.SeverityWarning, fmt.Sprintf("Chain depends on root %s in the %s trust store, which expires in %d days.", rootName(root), store.Name, daysLeft))
+		}
+		data.Trust = append(data.Trust, result)
+	}
+}
+
+func rootName(cert *x509.Certificate) string {
+	if cert.Subject.CommonName != "" {
+		return cert.Subject.CommonName
+	}
+	return strings.TrimSpace(cert.Subject.String())
+}
@@ -12,6 +12,8 @@ import (
 	"sentinel/logger"
 	"sentinel/mail"
 	"sentinel/models"
+	"sentinel/pkg/rdap"
+	"sentinel/pkg/revocation"
 
 	_ "github.com/lib/pq"
 	"github.com/roylee0704/gron"
@@ -113,6 +115,19 @@ func configureApplication() bool {
 		return false
 	} else {
 		config.ReadConfig(dir)
+		revocation.Client.Timeout = time.Duration(config.C.Revocation.Timeout) * time.Second
+		revocation.Cache = revocation.NewCRLCache(int64(config.C.Revocation.CRLCacheSize) << 20)
+		rdap.Default = rdap.NewClient(rdap.Options{
+			Bootstrap: config.C.RDAP.Bootstrap,
+			Servers:   config.C.RDAP.Servers,
+			CacheTTL:  time.Duration(config.C.RDAP.CacheTTL) * time.Hour,
+			Interval:  time.Minute / time.Duration(max(config.C.RDAP.RateLimit, 1)),
+			Timeout:   time.Duration(config.C.RDAP.Timeout) * time.Second,
+		})
+		helpers.RootStores = helpers.LoadRootStores(config.C.TrustStores, config.C.App.ExpireDay)
+		if config.C.CT.Enabled {
+			helpers.CTLogs = helpers.LoadCTLogs(config.C.CT.LogList, config.C.CT.Logs)
+		}
 		logger.CLogger.Info("INIT: Application configuration file read success.")
 		return true
 	}
@@ -145,18 +160,21 @@ func dbConnection() *gorm.DB {
 func getChanges() ([]models.Log, error) {
 	var logs []models.Log
 
-	for _, domain := range helpers.DomainList {
+	for _, target := range helpers.Targets() {
+		domain := target.Address
 		const maxRetries = 3
 		for i := 0; i < maxRetries; i++ {
-			isOK, data := helpers.CheckDomainCertificate(domain, config.C.App.ExpireDay)
-			if isOK && data != nil {
+			isOK, data := helpers.CheckTarget(target, config.C.App.ExpireDay)
+			if isOK && len(data) > 0 {
 				// Successfully retrieved certificate, break out of the loop
-				logs = append(logs, *data)
+				logs = append(logs, data...)
 				break
 			} else {
-				if data != nil {
+				if len(data) > 0 {
 					// Certificate will not expired in 30 days
-					logger.CLogger.Info("INFO: ", domain+" - "+data.Message)
+					for _, v := range data {
+						logger.CLogger.Info("INFO: ", domain+" "+v.RemoteIP+" - "+v.Message)
+					}
 					break
 				} else {
 					// Connection Error
@@ -168,6 +186,48 @@ func getChanges() ([]models.Log, error) {
 		}
 	}
 
+	for _, domain := range config.C.MailDomains {
+		isReported, data := helpers.CheckMailDomain(domain, config.C.App.ExpireDay)
+		if isReported {
+			logs = append(logs, data...)
+			continue
+		}
+		for _, v := range data {
+			logger.CLogger.Info("INFO: ", v.Domain+" - "+v.Message)
+		}
+	}
+
+	// Certificates on disk, only the ones to report are kept since directories can hold whole trust stores
+	var stored []models.Log
+	for _, files := range config.C.Files {
+		_, data := helpers.CheckFileTarget(files, config.C.App.ExpireDay)
+		stored = append(stored, data...)
+	}
+	for _, files := range config.C.Kubernetes {
+		_, data := helpers.CheckKubeTarget(files, config.C.App.ExpireDay)
+		stored = append(stored, data...)
+	}
+	for _, files := range config.C.Images {
+		_, data := helpers.CheckImageTarget(files, config.C.App.ExpireDay)
+		stored = append(stored, data...)
+	}
+	for _, files := range config.C.SSH.Files {
+		_, data := helpers.CheckSSHFileTarget(files, config.C.App.ExpireDay)
+		stored = append(stored, data...)
+	}
+	for _, target := range config.C.SSH.Hosts {
+		if _, data := helpers.CheckSSHHost(target, config.C.App.ExpireDay); data != nil {
+			stored = append(stored, *data)
+		}
+	}
+	for _, v := range stored {
+		if v.Status != 0 {
+			logs = append(logs, v)
+		} else {
+			logger.CLogger.Info("INFO: ", v.Path+" - "+v.Message)
+		}
+	}
+
 	return logs, nil
 }
 
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// Chain Certificate Model
+type Certificate struct {
+	Chain        string    `json:"chain"`    // presented, verified
+	Position     int       `json:"position"` // 0: Leaf
+	Role         string    `json:"role"`     // leaf, intermediate, root
+	Subject      string    `json:"subject"`
+	Issuer       string    `json:"issuer"`
+	CommonName   string    `json:"common_name"`
+	SerialNumber string    `json:"serial_number"`
+	IssuedOn     time.Time `json:"issued_on"`
+	ExpiresOn    time.Time `json:"expires_on"`
+	IsCA         bool      `json:"is_ca"`
+	DaysLeft     int       `json:"days_left"`
+	CRLStatus    string    `json:"crl_status"`
+	Lints        []string  `json:"lints"` // failed baseline requirement lints
+}
+
+// Label returns a short name of the chain element like "presented #1 intermediate (R3)"
+func (c Certificate) Label() string {
+	return fmt.Sprintf("%s #%d %s (%s)", c.Chain, c.Position, c.Role, c.CommonName)
+}
@@ -0,0 +1,9 @@
+package models
+
+// ClientCert Model, mTLS client certificate presented during the handshake
+type ClientCert struct {
+	CertFile   string `json:"cert_file" mapstructure:"cert_file"`     // PEM certificate, intermediates may follow the leaf
+	KeyFile    string `json:"key_file" mapstructure:"key_file"`       // PEM private key
+	PKCS12File string `json:"pkcs12_file" mapstructure:"pkcs12_file"` // .p12/.pfx instead of cert_file and key_file
+	Password   string `json:"password" mapstructure:"password"`       // PKCS#12 password
+}
@@ -0,0 +1,23 @@
+package models
+
+// ClientProfile Model, a client whose handshake is simulated against every target.
+// A profile naming a built-in client without cipher suites uses the built-in definition.
+type ClientProfile struct {
+	Name                string   `json:"name" mapstructure:"name"`
+	MinVersion          string   `json:"min_version" mapstructure:"min_version"` // like "TLS 1.0"
+	MaxVersion          string   `json:"max_version" mapstructure:"max_version"`
+	CipherSuites        []string `json:"cipher_suites" mapstructure:"cipher_suites"`               // IANA names in preference order
+	Groups              []string `json:"groups" mapstructure:"groups"`                             // like x25519, secp256r1
+	SignatureAlgorithms []string `json:"signature_algorithms" mapstructure:"signature_algorithms"` // like rsa_pkcs1_sha256, empty: not restricted
+	SNI                 *bool    `json:"sni" mapstructure:"sni"`                                   // default: true
+	Required            bool     `json:"required" mapstructure:"required"`                         // a failing handshake is a warning instead of info
+}
+
+// ClientSimulation Model, outcome of a simulated handshake
+type ClientSimulation struct {
+	Client      string `json:"client"`
+	Success     bool   `json:"success"`
+	Version     string `json:"version"`
+	CipherSuite string `json:"cipher_suite"`
+	Reason      string `json:"reason"` // why the client fails
+}
@@ -0,0 +1,11 @@
+package models
+
+// CTLog Model, a Certificate Transparency log SCTs are verified against
+type CTLog struct {
+	Name     string `json:"name" mapstructure:"name"`
+	Operator string `json:"operator" mapstructure:"operator"` // SCTs have to come from 2 different operators
+	Key      string `json:"key" mapstructure:"key"`           // base64 DER public key
+	Start    string `json:"start" mapstructure:"start"`       // temporal interval of the certificate expiry, RFC 3339
+	End      string `json:"end" mapstructure:"end"`
+	Retired  string `json:"retired" mapstructure:"retired"` // SCTs issued later do not count, RFC 3339
+}
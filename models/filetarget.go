@@ -0,0 +1,10 @@
+package models
+
+// FileTarget Model, certificates and keys stored on disk
+type FileTarget struct {
+	Path          string   `json:"path" mapstructure:"path"`                     // file, directory or glob pattern
+	Recursive     bool     `json:"recursive" mapstructure:"recursive"`           // descend into subdirectories
+	Passwords     []string `json:"passwords" mapstructure:"passwords"`           // tried on PKCS#12 bundles, keystores and encrypted keys
+	PasswordFiles []string `json:"password_files" mapstructure:"password_files"` // files holding one password each, like mounted secrets
+	Tags          []string `json:"tags" mapstructure:"tags"`
+}
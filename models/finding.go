@@ -0,0 +1,24 @@
+package models
+
+// Finding severities
+const (
+	SeverityInfo     = "info"
+	SeverityWarning  = "warning"
+	SeverityCritical = "critical"
+)
+
+// Check that severity is one of the known finding severities
+func ValidSeverity(severity string) bool {
+	switch severity {
+	case SeverityInfo, SeverityWarning, SeverityCritical:
+		return true
+	}
+	return false
+}
+
+// Finding Model, a problem found on the endpoint besides the expiry
+type Finding struct {
+	Check    string `json:"check"`    // check that raised the finding like "tls_inventory"
+	Severity string `json:"severity"` // info, warning, critical
+	Message  string `json:"message"`
+}
@@ -11,11 +11,12 @@ type Log struct {
 	IssuerSubject      string    `json:"issuer_subject" gorm:"issuer_subject"`
 	Domain             string    `json:"domain" gorm:"domain"`
 	Port               int       `json:"port" gorm:"port"`
+	RemoteIP           string    `json:"remote_ip" gorm:"remote_ip"` // address the certificate was served from
 	CommonName         string    `json:"common_name" gorm:"common_name"`
 	Organization       string    `json:"organization" gorm:"organization"`
 	IssuedOn           time.Time `json:"issued_on" gorm:"issued_on"`
 	ExpiresOn          time.Time `json:"expires_on" gorm:"expires_on"`
-	CertificateData    string    `json:"certificate_data" gorm:"certificate_data"`
+	CertificateData    string    `json:"certificate_data" gorm:"certificate_data"` // leaf as PEM
 	SignatureAlgorithm string    `json:"signature_algorithm" gorm:"signature_algorithm"`
 	SubjectKeyID       string    `json:"subject_key_id" gorm:"subject_key_id"`
 	AuthorityKeyID     string    `json:"authority_key_id" gorm:"authority_key_id"`
@@ -24,4 +25,120 @@ type Log struct {
 	IsExpired          bool      `json:"is_expired" gorm:"is_expired"`
 	Message            string    `json:"message" gorm:"message"`
 	Status             int       `json:"status" gorm:"status"` // 0: Not Expired, 1: Expired 2: Time Out
+	Source             string    `json:"source" gorm:"source"` // server, client (mTLS client certificate of the target), mail, file, kubernetes, image, ssh
+	Path               string    `json:"path" gorm:"path"`     // file the certificate was read from
+	Image              string    `json:"image" gorm:"image"`   // image tarball and tags the file belongs to
+	LayerDigest        string    `json:"layer_digest" gorm:"layer_digest"`
+	KeyFile            string    `json:"key_file" gorm:"key_file"`
+	KeyMatch           string    `json:"key_match" gorm:"key_match"`   // matched, mismatch, locked, not_found
+	Alias              string    `json:"alias" gorm:"alias"`           // keystore alias
+	EntryType          string    `json:"entry_type" gorm:"entry_type"` // keystore entry: private_key, trusted_cert
+
+	// CAA
+	CAADomain  string `json:"caa_domain" gorm:"caa_domain"`   // name the relevant CAA records were found at
+	CAARecords string `json:"caa_records" gorm:"caa_records"` // like 0 issue "letsencrypt.org"
+	CAAError   string `json:"caa_error" gorm:"caa_error"`
+
+	// DANE
+	TLSARecords string `json:"tlsa_records" gorm:"tlsa_records"` // like 3 1 1 0a1b...
+	TLSAStatus  string `json:"tlsa_status" gorm:"tlsa_status"`   // valid, mismatch or none
+	TLSADNSSEC  bool   `json:"tlsa_dnssec" gorm:"tlsa_dnssec"`   // resolver set the AD bit on the TLSA answer
+	TLSAError   string `json:"tlsa_error" gorm:"tlsa_error"`
+
+	// Certificate Transparency
+	SCTs        string `json:"scts" gorm:"scts"`                 // log, source and verification result of every SCT
+	SCTValid    int    `json:"sct_valid" gorm:"sct_valid"`       // SCTs with a valid signature from a known log
+	CTCompliant bool   `json:"ct_compliant" gorm:"ct_compliant"` // enough valid SCTs for the browser CT policy
+	CTError     string `json:"ct_error" gorm:"ct_error"`         // why the policy is not met
+
+	// MTA-STS and TLS-RPT of a mail domain
+	MTASTSID     string `json:"mta_sts_id" gorm:"mta_sts_id"`           // id of the _mta-sts TXT record
+	MTASTSMode   string `json:"mta_sts_mode" gorm:"mta_sts_mode"`       // enforce, testing or none
+	MTASTSMaxAge int    `json:"mta_sts_max_age" gorm:"mta_sts_max_age"` // seconds
+	MTASTSMX     string `json:"mta_sts_mx" gorm:"mta_sts_mx"`           // mx patterns of the policy
+	MTASTSError  string `json:"mta_sts_error" gorm:"mta_sts_error"`
+	TLSRPT       string `json:"tls_rpt" gorm:"tls_rpt"` // rua of the _smtp._tls TXT record
+
+	// Domain Registration (RDAP)
+	RegisteredDomain  string    `json:"registered_domain" gorm:"registered_domain"`
+	Registrar         string    `json:"registrar" gorm:"registrar"`
+	DomainExpiresOn   time.Time `json:"domain_expires_on" gorm:"domain_expires_on"`
+	DomainStatus      string    `json:"domain_status" gorm:"domain_status"`
+	RegistrationError string    `json:"registration_error" gorm:"registration_error"`
+
+	// OpenSSH
+	SSHCertType   string `json:"ssh_cert_type" gorm:"ssh_cert_type"` // user, host
+	SSHKeyID      string `json:"ssh_key_id" gorm:"ssh_key_id"`
+	SSHPrincipals string `json:"ssh_principals" gorm:"ssh_principals"`
+	SSHSigningCA  string `json:"ssh_signing_ca" gorm:"ssh_signing_ca"` // SHA256 fingerprint of the CA key
+
+	// Kubernetes
+	KubeContext   string `json:"kube_context" gorm:"kube_context"` // kubeconfig contexts using the cluster or user
+	KubeCluster   string `json:"kube_cluster" gorm:"kube_cluster"`
+	KubeUser      string `json:"kube_user" gorm:"kube_user"`
+	KubeNamespace string `json:"kube_namespace" gorm:"kube_namespace"`
+	KubeSecret    string `json:"kube_secret" gorm:"kube_secret"`
+	KubeField     string `json:"kube_field" gorm:"kube_field"` // client-certificate-data, certificate-authority-data, tls.crt, ca.crt
+
+	// Certificate Details
+	DNSNames           string `json:"dns_names" gorm:"dns_names"`
+	IPAddresses        string `json:"ip_addresses" gorm:"ip_addresses"`
+	EmailAddresses     string `json:"email_addresses" gorm:"email_addresses"`
+	PublicKeyAlgorithm string `json:"public_key_algorithm" gorm:"public_key_algorithm"`
+	PublicKeySize      int    `json:"public_key_size" gorm:"public_key_size"`
+	FingerprintSHA1    string `json:"fingerprint_sha1" gorm:"fingerprint_sha1"`
+	FingerprintSHA256  string `json:"fingerprint_sha256" gorm:"fingerprint_sha256"`
+	SPKISHA256         string `json:"spki_sha256" gorm:"spki_sha256"`
+	KeyUsage           string `json:"key_usage" gorm:"key_usage"`
+	ExtKeyUsage        string `json:"ext_key_usage" gorm:"ext_key_usage"`
+	IssuingCertURLs    string `json:"issuing_cert_urls" gorm:"issuing_cert_urls"` // AIA CA Issuers
+	OCSPServers        string `json:"ocsp_servers" gorm:"ocsp_servers"`
+	CRLDistribution    string `json:"crl_distribution" gorm:"crl_distribution"`
+	PolicyOIDs         string `json:"policy_oids" gorm:"policy_oids"`
+	MustStaple         bool   `json:"must_staple" gorm:"must_staple"`
+	ChainData          string `json:"chain_data" gorm:"chain_data"` // presented chain as PEM
+
+	// Revocation
+	OCSPStatus       string    `json:"ocsp_status" gorm:"ocsp_status"` // good, revoked, unknown, unreachable, error, not_checked
+	OCSPResponder    string    `json:"ocsp_responder" gorm:"ocsp_responder"`
+	OCSPError        string    `json:"ocsp_error" gorm:"ocsp_error"`
+	RevokedAt        time.Time `json:"revoked_at" gorm:"revoked_at"`
+	OCSPStapled      bool      `json:"ocsp_stapled" gorm:"ocsp_stapled"`
+	OCSPStapleFresh  bool      `json:"ocsp_staple_fresh" gorm:"ocsp_staple_fresh"`
+	OCSPStapleStatus string    `json:"ocsp_staple_status" gorm:"ocsp_staple_status"`
+	CRLStatus        string    `json:"crl_status" gorm:"crl_status"` // good, revoked, unknown, unreachable, not_checked
+	CRLURL           string    `json:"crl_url" gorm:"crl_url"`
+	CRLError         string    `json:"crl_error" gorm:"crl_error"`
+
+	// TLS Inventory (deep scan)
+	TLSVersions       string `json:"tls_versions" gorm:"tls_versions"`
+	CipherSuites      string `json:"cipher_suites" gorm:"cipher_suites"`
+	ALPNProtocols     string `json:"alpn_protocols" gorm:"alpn_protocols"`
+	KeyExchangeGroups string `json:"key_exchange_groups" gorm:"key_exchange_groups"`
+	PreferredGroup    string `json:"preferred_group" gorm:"preferred_group"`
+
+	// HTTP Response (http mode)
+	HTTPStatus   int    `json:"http_status" gorm:"http_status"`
+	HTTPServer   string `json:"http_server" gorm:"http_server"` // Server header
+	HSTS         string `json:"hsts" gorm:"hsts"`               // Strict-Transport-Security header
+	HTTPRedirect string `json:"http_redirect" gorm:"http_redirect"`
+	HTTPError    string `json:"http_error" gorm:"http_error"`
+
+	// Crawl
+	Parent         string `json:"parent" gorm:"parent"`                   // crawled target the host was discovered on
+	RedirectChain  string `json:"redirect_chain" gorm:"redirect_chain"`   // URLs followed to the landing page
+	DependentHosts string `json:"dependent_hosts" gorm:"dependent_hosts"` // hosts the landing page loads resources from
+	CrawlError     string `json:"crawl_error" gorm:"crawl_error"`
+
+	// Findings
+	Tags     string    `json:"tags" gorm:"tags"`
+	Findings []Finding `json:"findings" gorm:"findings;serializer:json"`
+
+	// Chain
+	Chain              []Certificate      `json:"chain" gorm:"chain;serializer:json"`                           // presented and verified chain elements
+	Trust              []TrustResult      `json:"trust" gorm:"trust;serializer:json"`                           // verification against each configured trust store
+	ClientSimulations  []ClientSimulation `json:"client_simulations" gorm:"client_simulations;serializer:json"` // handshakes of the simulated clients
+	ChainError         string             `json:"chain_error" gorm:"chain_error"`                               // verification error of the presented chain
+	EffectiveExpiresOn time.Time          `json:"effective_expires_on" gorm:"effective_expires_on"`
+	ExpiringElement    string             `json:"expiring_element" gorm:"expiring_element"` // chain element that expires first
 }
@@ -0,0 +1,9 @@
+package models
+
+// MailDomain Model, a domain whose MTA-STS policy and MX certificates are monitored
+type MailDomain struct {
+	Domain    string   `json:"domain" mapstructure:"domain"`
+	Tags      []string `json:"tags" mapstructure:"tags"`
+	DNSServer string   `json:"dns_server" mapstructure:"dns_server"` // resolver for the TXT and MX lookups (ip[:port])
+	Egress    *Egress  `json:"egress" mapstructure:"egress"`         // policy fetch and MX connections, default: global egress
+}
@@ -0,0 +1,15 @@
+package models
+
+// Policy Model, declarative certificate requirements evaluated on every scan
+type Policy struct {
+	Name     string   `json:"name" mapstructure:"name"`
+	Severity string   `json:"severity" mapstructure:"severity"` // info, warning, critical (default: warning)
+	Tags     []string `json:"tags" mapstructure:"tags"`         // applies to targets with any of these tags, empty: all targets
+
+	MinRSABits           int      `json:"min_rsa_bits" mapstructure:"min_rsa_bits"`
+	ForbidSHA1           bool     `json:"forbid_sha1" mapstructure:"forbid_sha1"`
+	MaxValidityDays      int      `json:"max_validity_days" mapstructure:"max_validity_days"`
+	AllowedIssuers       []string `json:"allowed_issuers" mapstructure:"allowed_issuers"` // issuer CN, organization or DN
+	RequireHostnameInSAN bool     `json:"require_hostname_in_san" mapstructure:"require_hostname_in_san"`
+	ForbidWildcard       bool     `json:"forbid_wildcard" mapstructure:"forbid_wildcard"`
+}
@@ -0,0 +1,33 @@
+package models
+
+// Target Model, an endpoint to monitor
+type Target struct {
+	Address string   `json:"address" mapstructure:"address"` // domain:port
+	Tags    []string `json:"tags" mapstructure:"tags"`
+
+	DNSServer     string      `json:"dns_server" mapstructure:"dns_server"`         // resolver to query instead of the system one (ip[:port])
+	ConnectIP     string      `json:"connect_ip" mapstructure:"connect_ip"`         // connect here instead of resolving the domain
+	ServerName    string      `json:"server_name" mapstructure:"server_name"`       // SNI, default: domain
+	HostHeader    string      `json:"host_header" mapstructure:"host_header"`       // HTTP Host header, default: SNI
+	Egress        *Egress     `json:"egress" mapstructure:"egress"`                 // proxy and source address, default: global egress
+	ClientCert    *ClientCert `json:"client_cert" mapstructure:"client_cert"`       // mTLS client certificate
+	StartTLS      string      `json:"starttls" mapstructure:"starttls"`             // smtp or none, default: smtp on ports 25 and 587
+	ResolveAll    bool        `json:"resolve_all" mapstructure:"resolve_all"`       // probe every A/AAAA address of the domain
+	HTTP          bool        `json:"http" mapstructure:"http"`                     // request GET / after the handshake
+	Crawl         bool        `json:"crawl" mapstructure:"crawl"`                   // check the hosts the landing page loads resources from
+	DANE          bool        `json:"dane" mapstructure:"dane"`                     // validate the chain against the TLSA records
+	Parent        string      `json:"parent" mapstructure:"-"`                      // address of the crawled target a derived target was found on
+	SuppressLints []string    `json:"suppress_lints" mapstructure:"suppress_lints"` // lint names ignored for this target
+}
+
+// Check whether the target has any of the given tags
+func (t Target) HasAnyTag(tags []string) bool {
+	for _, tag := range tags {
+		for _, v := range t.Tags {
+			if v == tag {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// TrustStore Model, a named set of root certificates chains are verified against
+type TrustStore struct {
+	Name          string   `json:"name" mapstructure:"name"`
+	Path          string   `json:"path" mapstructure:"path"`                     // bundle file, keystore, directory or glob pattern
+	Passwords     []string `json:"passwords" mapstructure:"passwords"`           // tried on keystores, cacerts opens with "changeit"
+	PasswordFiles []string `json:"password_files" mapstructure:"password_files"` // files holding one password each
+	Tags          []string `json:"tags" mapstructure:"tags"`                     // applies to targets with any of these tags, empty: all targets
+}
+
+// TrustResult Model, verification of an endpoint chain against one trust store
+type TrustResult struct {
+	Store         string    `json:"store"`
+	Trusted       bool      `json:"trusted"`
+	Root          string    `json:"root"` // subject of the root the chain was built to
+	RootExpiresOn time.Time `json:"root_expires_on"`
+	Error         string    `json:"error"`
+}
@@ -0,0 +1,188 @@
+package certfile
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/pem"
+	"errors"
+	"strings"
+
+	"software.sslmate.com/src/go-pkcs12"
+)
+
+// Formats a file can be recognized as
+const (
+	FormatPEM    = "pem"
+	FormatDER    = "der"
+	FormatPKCS7  = "pkcs7"
+	FormatPKCS12 = "pkcs12"
+)
+
+// Content is not a certificate or key in any supported format
+var ErrUnrecognized = errors.New("no certificate or key found")
+
+// Certificates and private keys found in a file
+type Bundle struct {
+	Format       string
+	Certificates []*x509.Certificate
+	Keys         []crypto.PublicKey // public half of every private key that could be read
+	LockedKeys   int                // private keys none of the passwords opened
+	Entries      []Entry            // keystore entries, Certificates holds their certificates as well
+	Unverified   bool               // keystore integrity could not be checked with any password
+}
+
+// Parse PEM, DER, PKCS#7, PKCS#12, JKS or JCEKS content, passwords are tried on encrypted content in order
+func Parse(data []byte, passwords []string) (Bundle, error) {
+	if bytes.Contains(data, []byte("-----BEGIN ")) {
+		return parsePEM(data, passwords)
+	}
+
+	if isKeystore(data) {
+		return parseKeystore(data, passwords)
+	}
+
+	// Binary content, the certificate is the most common one
+	if certs, err := x509.ParseCertificates(data); err == nil && len(certs) > 0 {
+		return Bundle{Format: FormatDER, Certificates: certs}, nil
+	}
+	if certs, err := ParsePKCS7(data); err == nil {
+		return Bundle{Format: FormatPKCS7, Certificates: certs}, nil
+	}
+	if key, err := parsePrivateKey(data); err == nil {
+		return Bundle{Format: FormatDER, Keys: []crypto.PublicKey{key}}, nil
+	}
+	if isPKCS12(data) {
+		return parsePKCS12(data, passwords)
+	}
+	return Bundle{}, ErrUnrecognized
+}
+
+func parsePEM(data []byte, passwords []string) (Bundle, error) {
+	bundle := Bundle{Format: FormatPEM}
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+
+		switch {
+		case block.Type == "CERTIFICATE" || block.Type == "TRUSTED CERTIFICATE":
+			if cert, err := x509.ParseCertificate(block.Bytes); err == nil {
+				bundle.Certificates = append(bundle.Certificates, cert)
+			}
+		case block.Type == "PKCS7":
+			if certs, err := ParsePKCS7(block.Bytes); err == nil {
+				bundle.Certificates = append(bundle.Certificates, certs...)
+			}
+		case block.Type == "ENCRYPTED PRIVATE KEY":
+			// The standard library cannot decrypt PKCS#8, PBES2 is handled here
+			der := decryptPKCS8Block(block.Bytes, passwords)
+			if der == nil {
+				bundle.LockedKeys++
+				continue
+			}
+			if key, err := parsePrivateKey(der); err == nil {
+				bundle.Keys = append(bundle.Keys, key)
+			}
+		case strings.HasSuffix(block.Type, "PRIVATE KEY"):
+			der := block.Bytes
+			// Legacy OpenSSL encryption, still common on disk
+			if x509.IsEncryptedPEMBlock(block) {
+				der = decryptPEMBlock(block, passwords)
+				if der == nil {
+					bundle.LockedKeys++
+					continue
+				}
+			}
+			if key, err := parsePrivateKey(der); err == nil {
+				bundle.Keys = append(bundle.Keys, key)
+			}
+		}
+	}
+
+	if len(bundle.Certificates) == 0 && len(bundle.Keys) == 0 && bundle.LockedKeys == 0 {
+		return Bundle{}, ErrUnrecognized
+	}
+	return bundle, nil
+}
+
+func decryptPEMBlock(block *pem.Block, passwords []string) []byte {
+	for _, password := range passwords {
+		if der, err := x509.DecryptPEMBlock(block, []byte(password)); err == nil {
+			return der
+		}
+	}
+	return nil
+}
+
+// Decode a PKCS#12 bundle into its certificates and private key, the key is nil for trust stores.
+// PBES2/AES bundles with a SHA-256 MAC, the default of OpenSSL 3 and recent JDKs, are supported.
+func DecodePKCS12(data []byte, password string) ([]*x509.Certificate, crypto.PrivateKey, error) {
+	key, cert, caCerts, err := pkcs12.DecodeChain(data, password)
+	if err == nil {
+		return append([]*x509.Certificate{cert}, caCerts...), key, nil
+	}
+
+	// Java trust stores carry certificates only
+	if certs, trustErr := pkcs12.DecodeTrustStore(data, password); trustErr == nil {
+		return certs, nil, nil
+	}
+	return nil, nil, err
+}
+
+func parsePKCS12(data []byte, passwords []string) (Bundle, error) {
+	var lastErr error
+	for _, password := range append([]string{""}, passwords...) {
+		certs, key, err := DecodePKCS12(data, password)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		bundle := Bundle{Format: FormatPKCS12, Certificates: certs}
+		if signer, ok := key.(crypto.Signer); ok {
+			bundle.Keys = append(bundle.Keys, signer.Public())
+		}
+		return bundle, nil
+	}
+	return Bundle{Format: FormatPKCS12}, lastErr
+}
+
+// PFX is a SEQUENCE starting with version 3
+func isPKCS12(data []byte) bool {
+	var pfx struct {
+		Version  int
+		AuthSafe asn1.RawValue
+		MacData  asn1.RawValue `asn1:"optional"`
+	}
+	_, err := asn1.Unmarshal(data, &pfx)
+	return err == nil && pfx.Version == 3
+}
+
+func parsePrivateKey(der []byte) (crypto.PublicKey, error) {
+	var key interface{}
+	var err error
+	if key, err = x509.ParsePKCS8PrivateKey(der); err != nil {
+		if key, err = x509.ParsePKCS1PrivateKey(der); err != nil {
+			if key, err = x509.ParseECPrivateKey(der); err != nil {
+				return nil, err
+			}
+		}
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, errors.New("unsupported private key type")
+	}
+	return signer.Public(), nil
+}
+
+// Check whether the private key belongs to the certificate
+func KeyMatches(cert *x509.Certificate, key crypto.PublicKey) bool {
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		return false
+	}
+	return bytes.Equal(der, cert.RawSubjectPublicKeyInfo)
+}
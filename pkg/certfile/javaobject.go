@@ -0,0 +1,285 @@
+package certfile
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Java serialization stream constants (Java Object Serialization Specification, 6.4)
+const (
+	javaStreamMagic   = 0xaced
+	javaStreamVersion = 5
+
+	tcNull          = 0x70
+	tcReference     = 0x71
+	tcClassDesc     = 0x72
+	tcObject        = 0x73
+	tcString        = 0x74
+	tcArray         = 0x75
+	tcBlockData     = 0x77
+	tcEndBlockData  = 0x78
+	tcBlockDataLong = 0x7a
+	tcLongString    = 0x7c
+
+	scWriteMethod    = 0x01
+	scSerializable   = 0x02
+	scExternalizable = 0x04
+	scBlockData      = 0x08
+
+	baseWireHandle = 0x7e0000
+)
+
+// Nesting depth of a serialized object we follow
+const maxJavaDepth = 32
+
+// Bytes of the primitive field and array element type codes
+var javaPrimitiveSizes = map[byte]int64{'B': 1, 'Z': 1, 'C': 2, 'S': 2, 'I': 4, 'F': 4, 'J': 8, 'D': 8}
+
+type javaClass struct {
+	name   string
+	flags  byte
+	fields []byte // type codes in stream order
+	super  *javaClass
+}
+
+// Reader of a serialized object that only keeps what it needs to find the end of it
+type javaStream struct {
+	r       *bytes.Reader
+	handles []*javaClass // class descriptors by handle, nil for other objects
+	depth   int
+}
+
+// Skip one serialized Java object like the sealed key of a JCEKS secret key entry
+func skipJavaObject(r *bytes.Reader) error {
+	var header struct{ Magic, Version uint16 }
+	if err := binary.Read(r, binary.BigEndian, &header); err != nil {
+		return err
+	}
+	if header.Magic != javaStreamMagic || header.Version != javaStreamVersion {
+		return errors.New("not a Java serialization stream")
+	}
+	s := &javaStream{r: r}
+	return s.content()
+}
+
+func (s *javaStream) content() error {
+	if s.depth++; s.depth > maxJavaDepth {
+		return errors.New("serialized object nested too deep")
+	}
+	defer func() { s.depth-- }()
+
+	tc, err := s.r.ReadByte()
+	if err != nil {
+		return err
+	}
+	switch tc {
+	case tcNull:
+		return nil
+	case tcReference:
+		_, err := s.handle()
+		return err
+	case tcString:
+		s.handles = append(s.handles, nil)
+		return s.skipUTF()
+	case tcLongString:
+		s.handles = append(s.handles, nil)
+		var length uint64
+		if err := binary.Read(s.r, binary.BigEndian, &length); err != nil {
+			return err
+		}
+		return s.skip(int64(length))
+	case tcBlockData:
+		length, err := s.r.ReadByte()
+		if err != nil {
+			return err
+		}
+		return s.skip(int64(length))
+	case tcBlockDataLong:
+		var length uint32
+		if err := binary.Read(s.r, binary.BigEndian, &length); err != nil {
+			return err
+		}
+		return s.skip(int64(length))
+	case tcClassDesc:
+		s.r.UnreadByte()
+		_, err := s.classDesc()
+		return err
+	case tcObject:
+		class, err := s.classDesc()
+		if err != nil {
+			return err
+		}
+		s.handles = append(s.handles, nil)
+		return s.classData(class)
+	case tcArray:
+		class, err := s.classDesc()
+		if err != nil {
+			return err
+		}
+		s.handles = append(s.handles, nil)
+		return s.arrayData(class)
+	}
+	return fmt.Errorf("unsupported serialization type code %#x", tc)
+}
+
+func (s *javaStream) classDesc() (*javaClass, error) {
+	tc, err := s.r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	switch tc {
+	case tcNull:
+		return nil, nil
+	case tcReference:
+		return s.handle()
+	case tcClassDesc:
+	default:
+		return nil, fmt.Errorf("unsupported class descriptor type code %#x", tc)
+	}
+
+	name, err := readUTF(s.r)
+	if err != nil {
+		return nil, err
+	}
+	class := &javaClass{name: name}
+	if err := s.skip(8); err != nil { // serialVersionUID
+		return nil, err
+	}
+	s.handles = append(s.handles, class)
+	if class.flags, err = s.r.ReadByte(); err != nil {
+		return nil, err
+	}
+	var count uint16
+	if err := binary.Read(s.r, binary.BigEndian, &count); err != nil {
+		return nil, err
+	}
+	for i := uint16(0); i < count; i++ {
+		typeCode, err := s.r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		if err := s.skipUTF(); err != nil { // field name
+			return nil, err
+		}
+		if _, ok := javaPrimitiveSizes[typeCode]; !ok {
+			if err := s.content(); err != nil { // class name of an object field
+				return nil, err
+			}
+		}
+		class.fields = append(class.fields, typeCode)
+	}
+	if err := s.annotation(); err != nil {
+		return nil, err
+	}
+	if class.super, err = s.classDesc(); err != nil {
+		return nil, err
+	}
+	return class, nil
+}
+
+// Field values of every class in the hierarchy, the topmost superclass first
+func (s *javaStream) classData(class *javaClass) error {
+	var hierarchy []*javaClass
+	for c := class; c != nil; c = c.super {
+		hierarchy = append([]*javaClass{c}, hierarchy...)
+	}
+	for _, c := range hierarchy {
+		switch {
+		case c.flags&scExternalizable != 0:
+			if c.flags&scBlockData == 0 {
+				return errors.New("externalizable objects without block data are not supported")
+			}
+			if err := s.annotation(); err != nil {
+				return err
+			}
+		case c.flags&scSerializable != 0:
+			for _, typeCode := range c.fields {
+				var err error
+				if size, ok := javaPrimitiveSizes[typeCode]; ok {
+					err = s.skip(size)
+				} else {
+					err = s.content()
+				}
+				if err != nil {
+					return err
+				}
+			}
+			if c.flags&scWriteMethod != 0 {
+				if err := s.annotation(); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (s *javaStream) arrayData(class *javaClass) error {
+	if class == nil || len(class.name) < 2 || class.name[0] != '[' {
+		return errors.New("array without an array class")
+	}
+	var length int32
+	if err := binary.Read(s.r, binary.BigEndian, &length); err != nil {
+		return err
+	}
+	if length < 0 {
+		return errors.New("negative array length")
+	}
+	if size, ok := javaPrimitiveSizes[class.name[1]]; ok {
+		return s.skip(size * int64(length))
+	}
+	for i := int32(0); i < length; i++ {
+		if err := s.content(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Contents written by a class up to the end marker
+func (s *javaStream) annotation() error {
+	for {
+		tc, err := s.r.ReadByte()
+		if err != nil {
+			return err
+		}
+		if tc == tcEndBlockData {
+			return nil
+		}
+		s.r.UnreadByte()
+		if err := s.content(); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *javaStream) handle() (*javaClass, error) {
+	var handle uint32
+	if err := binary.Read(s.r, binary.BigEndian, &handle); err != nil {
+		return nil, err
+	}
+	i := int64(handle) - baseWireHandle
+	if i < 0 || i >= int64(len(s.handles)) {
+		return nil, fmt.Errorf("invalid serialization handle %#x", handle)
+	}
+	return s.handles[i], nil
+}
+
+func (s *javaStream) skipUTF() error {
+	var length uint16
+	if err := binary.Read(s.r, binary.BigEndian, &length); err != nil {
+		return err
+	}
+	return s.skip(int64(length))
+}
+
+func (s *javaStream) skip(n int64) error {
+	if n > int64(s.r.Len()) {
+		return io.ErrUnexpectedEOF
+	}
+	_, err := s.r.Seek(n, io.SeekCurrent)
+	return err
+}
@@ -0,0 +1,203 @@
+package certfile
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"crypto/x509"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+	"unicode/utf16"
+)
+
+// Keystore formats
+const (
+	FormatJKS   = "jks"
+	FormatJCEKS = "jceks"
+)
+
+// Keystore entry types
+const (
+	EntryPrivateKey  = "private_key"
+	EntryTrustedCert = "trusted_cert"
+	EntrySecretKey   = "secret_key"
+)
+
+const (
+	magicJKS   = 0xfeedfeed
+	magicJCEKS = 0xcececece
+
+	tagPrivateKey  = 1
+	tagTrustedCert = 2
+	tagSecretKey   = 3
+)
+
+// Passwords of the JDK truststores, tried after the configured ones
+var defaultKeystorePasswords = []string{"changeit", ""}
+
+// Keystore entry, a private key with its certificate chain, a trusted certificate or a secret key
+type Entry struct {
+	Alias        string
+	Type         string
+	Created      time.Time
+	Certificates []*x509.Certificate // chain of a private key entry, leaf first
+}
+
+func isKeystore(data []byte) bool {
+	if len(data) < 4 {
+		return false
+	}
+	magic := binary.BigEndian.Uint32(data)
+	return magic == magicJKS || magic == magicJCEKS
+}
+
+// Parse a JKS or JCEKS keystore. Certificates are stored in the clear, the password
+// only proves the integrity of the file and the private keys are never decrypted.
+func parseKeystore(data []byte, passwords []string) (Bundle, error) {
+	if len(data) < 12+sha1.Size {
+		return Bundle{}, errors.New("keystore is truncated")
+	}
+	bundle := Bundle{Format: FormatJKS}
+	if binary.BigEndian.Uint32(data) == magicJCEKS {
+		bundle.Format = FormatJCEKS
+	}
+
+	body, digest := data[:len(data)-sha1.Size], data[len(data)-sha1.Size:]
+	bundle.Unverified = true
+	for _, password := range append(append([]string{}, passwords...), defaultKeystorePasswords...) {
+		if bytes.Equal(keystoreDigest(body, password), digest) {
+			bundle.Unverified = false
+			break
+		}
+	}
+
+	r := bytes.NewReader(body[4:])
+	var version, count uint32
+	binary.Read(r, binary.BigEndian, &version)
+	binary.Read(r, binary.BigEndian, &count)
+	if version != 1 && version != 2 {
+		return Bundle{}, fmt.Errorf("unsupported keystore version %d", version)
+	}
+
+	for i := uint32(0); i < count; i++ {
+		entry, err := readEntry(r, version)
+		if err != nil {
+			// Entries have no length prefix, nothing after a broken one can be read
+			return bundle, fmt.Errorf("keystore entry %d: %w", i, err)
+		}
+		bundle.Entries = append(bundle.Entries, entry)
+		bundle.Certificates = append(bundle.Certificates, entry.Certificates...)
+	}
+	return bundle, nil
+}
+
+func readEntry(r *bytes.Reader, version uint32) (Entry, error) {
+	var tag uint32
+	if err := binary.Read(r, binary.BigEndian, &tag); err != nil {
+		return Entry{}, err
+	}
+	alias, err := readUTF(r)
+	if err != nil {
+		return Entry{}, err
+	}
+	var created int64
+	if err := binary.Read(r, binary.BigEndian, &created); err != nil {
+		return Entry{}, err
+	}
+	entry := Entry{Alias: alias, Created: time.UnixMilli(created)}
+
+	switch tag {
+	case tagPrivateKey:
+		entry.Type = EntryPrivateKey
+		if _, err := readBlock(r); err != nil { // protected key
+			return entry, err
+		}
+		var chainLength uint32
+		if err := binary.Read(r, binary.BigEndian, &chainLength); err != nil {
+			return entry, err
+		}
+		for j := uint32(0); j < chainLength; j++ {
+			cert, err := readCertificate(r, version)
+			if err != nil {
+				return entry, err
+			}
+			entry.Certificates = append(entry.Certificates, cert)
+		}
+
+	case tagTrustedCert:
+		entry.Type = EntryTrustedCert
+		cert, err := readCertificate(r, version)
+		if err != nil {
+			return entry, err
+		}
+		entry.Certificates = []*x509.Certificate{cert}
+
+	case tagSecretKey:
+		// JCEKS secret keys are a serialized SealedObject without a length, it is skipped to reach the next entry
+		entry.Type = EntrySecretKey
+		if err := skipJavaObject(r); err != nil {
+			return entry, fmt.Errorf("secret key %s: %w", alias, err)
+		}
+
+	default:
+		return entry, fmt.Errorf("unknown entry tag %d", tag)
+	}
+	return entry, nil
+}
+
+func readCertificate(r *bytes.Reader, version uint32) (*x509.Certificate, error) {
+	if version == 2 {
+		certType, err := readUTF(r)
+		if err != nil {
+			return nil, err
+		}
+		if certType != "X.509" {
+			return nil, fmt.Errorf("unsupported certificate type %s", certType)
+		}
+	}
+	der, err := readBlock(r)
+	if err != nil {
+		return nil, err
+	}
+	return x509.ParseCertificate(der)
+}
+
+func readBlock(r *bytes.Reader) ([]byte, error) {
+	var length uint32
+	if err := binary.Read(r, binary.BigEndian, &length); err != nil {
+		return nil, err
+	}
+	if int64(length) > int64(r.Len()) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	b := make([]byte, length)
+	_, err := io.ReadFull(r, b)
+	return b, err
+}
+
+// Java modified UTF-8, identical to UTF-8 for the aliases seen in practice
+func readUTF(r *bytes.Reader) (string, error) {
+	var length uint16
+	if err := binary.Read(r, binary.BigEndian, &length); err != nil {
+		return "", err
+	}
+	if int(length) > r.Len() {
+		return "", io.ErrUnexpectedEOF
+	}
+	b := make([]byte, length)
+	_, err := io.ReadFull(r, b)
+	return string(b), err
+}
+
+// SHA-1 over the UTF-16 password, the "Mighty Aphrodite" salt and the keystore body
+func keystoreDigest(body []byte, password string) []byte {
+	h := sha1.New()
+	for _, c := range utf16.Encode([]rune(password)) {
+		h.Write([]byte{byte(c >> 8), byte(c)})
+	}
+	h.Write([]byte("Mighty Aphrodite"))
+	h.Write(body)
+	return h.Sum(nil)
+}
@@ -0,0 +1,225 @@
+package certfile
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/binary"
+	"testing"
+	"time"
+)
+
+// Keystore writer following the layout of sun.security.provider.JavaKeyStore
+type keystoreWriter struct {
+	bytes.Buffer
+	version uint32
+}
+
+func newKeystore(magic, version, count uint32) *keystoreWriter {
+	w := &keystoreWriter{version: version}
+	binary.Write(w, binary.BigEndian, magic)
+	binary.Write(w, binary.BigEndian, version)
+	binary.Write(w, binary.BigEndian, count)
+	return w
+}
+
+func (w *keystoreWriter) utf(s string) {
+	binary.Write(w, binary.BigEndian, uint16(len(s)))
+	w.WriteString(s)
+}
+
+func (w *keystoreWriter) block(b []byte) {
+	binary.Write(w, binary.BigEndian, uint32(len(b)))
+	w.Write(b)
+}
+
+func (w *keystoreWriter) header(tag uint32, alias string, created time.Time) {
+	binary.Write(w, binary.BigEndian, tag)
+	w.utf(alias)
+	binary.Write(w, binary.BigEndian, created.UnixMilli())
+}
+
+func (w *keystoreWriter) certificate(cert *x509.Certificate) {
+	if w.version == 2 {
+		w.utf("X.509")
+	}
+	w.block(cert.Raw)
+}
+
+func (w *keystoreWriter) privateKey(alias string, created time.Time, chain ...*x509.Certificate) {
+	w.header(tagPrivateKey, alias, created)
+	w.block([]byte("protected key"))
+	binary.Write(w, binary.BigEndian, uint32(len(chain)))
+	for _, cert := range chain {
+		w.certificate(cert)
+	}
+}
+
+func (w *keystoreWriter) trustedCert(alias string, created time.Time, cert *x509.Certificate) {
+	w.header(tagTrustedCert, alias, created)
+	w.certificate(cert)
+}
+
+// JCEKS secret key entry, the key sealed in a serialized
+// com.sun.crypto.provider.SealedObjectForKeyProtector like JceKeyStore writes it
+func (w *keystoreWriter) secretKey(alias string, created time.Time) {
+	w.header(tagSecretKey, alias, created)
+	const handle = baseWireHandle
+	binary.Write(w, binary.BigEndian, uint16(javaStreamMagic))
+	binary.Write(w, binary.BigEndian, uint16(javaStreamVersion))
+	classDesc := func(name string, suid uint64, fields func()) {
+		w.WriteByte(tcClassDesc)
+		w.utf(name)
+		binary.Write(w, binary.BigEndian, suid)
+		w.WriteByte(scSerializable)
+		fields()
+		w.WriteByte(tcEndBlockData)
+	}
+	reference := func(h uint32) {
+		w.WriteByte(tcReference)
+		binary.Write(w, binary.BigEndian, h)
+	}
+
+	w.WriteByte(tcObject)
+	classDesc("com.sun.crypto.provider.SealedObjectForKeyProtector", 0xcd57ca59e730bb53, func() { // handle 0
+		binary.Write(w, binary.BigEndian, uint16(0))
+	})
+	classDesc("javax.crypto.SealedObject", 0x3e363da6c3b75470, func() { // handle 1
+		binary.Write(w, binary.BigEndian, uint16(4))
+		w.WriteByte('[')
+		w.utf("encodedParams")
+		w.WriteByte(tcString) // handle 2
+		w.utf("[B")
+		w.WriteByte('[')
+		w.utf("encryptedContent")
+		reference(handle + 2)
+		w.WriteByte('L')
+		w.utf("paramsAlg")
+		w.WriteByte(tcString) // handle 3
+		w.utf("Ljava/lang/String;")
+		w.WriteByte('L')
+		w.utf("sealAlg")
+		reference(handle + 3)
+	})
+	w.WriteByte(tcNull) // SealedObject has no serializable superclass, the object is handle 4
+
+	w.WriteByte(tcArray)
+	classDesc("[B", 0xacf317f8060854e0, func() { // handle 5
+		binary.Write(w, binary.BigEndian, uint16(0))
+	})
+	w.WriteByte(tcNull)
+	w.block([]byte("salt and iteration count")) // handle 6
+	w.WriteByte(tcArray)
+	reference(handle + 5)
+	w.block(bytes.Repeat([]byte{0xa5}, 40)) // handle 7
+	w.WriteByte(tcString)
+	w.utf("PBEWithMD5AndTripleDES")
+	reference(handle + 8)
+}
+
+// Keystore bytes with the integrity digest of the password
+func (w *keystoreWriter) seal(password string) []byte {
+	body := append([]byte{}, w.Bytes()...)
+	return append(body, keystoreDigest(body, password)...)
+}
+
+func TestParseKeystore(t *testing.T) {
+	leaf := testCertificate(t, "leaf")
+	ca := testCertificate(t, "ca")
+	created := time.UnixMilli(1700000000000)
+
+	jks := newKeystore(magicJKS, 2, 2)
+	jks.privateKey("server", created, leaf, ca)
+	jks.trustedCert("root", created, ca)
+
+	jceks := newKeystore(magicJCEKS, 2, 1)
+	jceks.trustedCert("root", created, ca)
+
+	v1 := newKeystore(magicJKS, 1, 1)
+	v1.trustedCert("root", created, ca)
+
+	secret := newKeystore(magicJCEKS, 2, 3)
+	secret.secretKey("aes", created)
+	secret.trustedCert("root", created, ca)
+	secret.secretKey("hmac", created)
+
+	broken := newKeystore(magicJCEKS, 2, 2)
+	broken.header(tagSecretKey, "aes", created)
+	broken.WriteString("not serialized")
+	broken.trustedCert("root", created, ca)
+
+	tests := []struct {
+		name       string
+		data       []byte
+		passwords  []string
+		format     string
+		entries    []string
+		certs      int
+		unverified bool
+		wantErr    bool
+	}{
+		{"JKS with configured password", jks.seal("s3cret"), []string{"s3cret"}, FormatJKS, []string{"server", "root"}, 3, false, false},
+		{"JCEKS with the JDK default password", jceks.seal("changeit"), nil, FormatJCEKS, []string{"root"}, 1, false, false},
+		{"version 1 without certificate types", v1.seal(""), nil, FormatJKS, []string{"root"}, 1, false, false},
+		{"unknown password", jks.seal("other"), []string{"s3cret"}, FormatJKS, []string{"server", "root"}, 3, true, false},
+		{"secret keys are skipped", secret.seal("changeit"), nil, FormatJCEKS, []string{"aes", "root", "hmac"}, 1, false, false},
+		{"unreadable secret key stops the parse", broken.seal("changeit"), nil, FormatJCEKS, nil, 0, false, true},
+		{"truncated", jks.seal("s3cret")[:200], nil, FormatJKS, nil, 0, true, true},
+		{"unsupported version", newKeystore(magicJKS, 3, 0).seal("changeit"), nil, "", nil, 0, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bundle, err := Parse(tt.data, tt.passwords)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if bundle.Format != tt.format {
+				t.Errorf("format = %q, want %q", bundle.Format, tt.format)
+			}
+			if len(bundle.Entries) != len(tt.entries) || len(bundle.Certificates) != tt.certs {
+				t.Fatalf("got %d entries and %d certificates, want %d and %d", len(bundle.Entries), len(bundle.Certificates), len(tt.entries), tt.certs)
+			}
+			for i, alias := range tt.entries {
+				if bundle.Entries[i].Alias != alias || !bundle.Entries[i].Created.Equal(created) {
+					t.Errorf("entry %d = %s created %s, want %s", i, bundle.Entries[i].Alias, bundle.Entries[i].Created, alias)
+				}
+			}
+			if err == nil && bundle.Unverified != tt.unverified {
+				t.Errorf("unverified = %v, want %v", bundle.Unverified, tt.unverified)
+			}
+		})
+	}
+}
+
+func TestKeystoreEntries(t *testing.T) {
+	leaf := testCertificate(t, "leaf")
+	ca := testCertificate(t, "ca")
+	ks := newKeystore(magicJKS, 2, 2)
+	ks.privateKey("server", time.Now(), leaf, ca)
+	ks.trustedCert("root", time.Now(), ca)
+
+	bundle, err := Parse(ks.seal("changeit"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server, root := bundle.Entries[0], bundle.Entries[1]
+	if server.Type != EntryPrivateKey || len(server.Certificates) != 2 || !server.Certificates[0].Equal(leaf) || !server.Certificates[1].Equal(ca) {
+		t.Errorf("private key entry = %+v, want the chain leaf first", server)
+	}
+	if root.Type != EntryTrustedCert || len(root.Certificates) != 1 || !root.Certificates[0].Equal(ca) {
+		t.Errorf("trusted certificate entry = %+v", root)
+	}
+	// Private keys stay encrypted, they are not reported as keys of the bundle
+	if len(bundle.Keys) != 0 || bundle.LockedKeys != 0 {
+		t.Errorf("keys = %d, locked = %d, want none", len(bundle.Keys), bundle.LockedKeys)
+	}
+
+	jceks := newKeystore(magicJCEKS, 2, 1)
+	jceks.secretKey("aes", time.Now())
+	bundle, err = Parse(jceks.seal("changeit"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if secret := bundle.Entries[0]; secret.Type != EntrySecretKey || len(secret.Certificates) != 0 {
+		t.Errorf("secret key entry = %+v", secret)
+	}
+}
@@ -0,0 +1,44 @@
+package certfile
+
+import (
+	"crypto/x509"
+	"encoding/asn1"
+	"errors"
+)
+
+// PKCS#7 signedData content type
+var oidSignedData = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}
+
+type contentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue `asn1:"explicit,optional,tag:0"`
+}
+
+type signedData struct {
+	Version          int
+	DigestAlgorithms asn1.RawValue
+	ContentInfo      asn1.RawValue
+	Certificates     asn1.RawValue `asn1:"optional,tag:0"`
+}
+
+// Certificates of a PKCS#7 signedData structure like .p7b/.p7c bundles
+func ParsePKCS7(der []byte) ([]*x509.Certificate, error) {
+	var info contentInfo
+	if rest, err := asn1.Unmarshal(der, &info); err != nil {
+		return nil, err
+	} else if len(rest) > 0 {
+		return nil, errors.New("trailing data after PKCS#7 content")
+	}
+	if !info.ContentType.Equal(oidSignedData) {
+		return nil, errors.New("PKCS#7 content is not signedData")
+	}
+
+	var sd signedData
+	if _, err := asn1.Unmarshal(info.Content.Bytes, &sd); err != nil {
+		return nil, err
+	}
+	if len(sd.Certificates.Bytes) == 0 {
+		return nil, errors.New("PKCS#7 has no certificates")
+	}
+	return x509.ParseCertificates(sd.Certificates.Bytes)
+}
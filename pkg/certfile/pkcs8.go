@@ -0,0 +1,140 @@
+package certfile
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/des"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"hash"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// PKCS#5 v2 identifiers
+var (
+	oidPBES2  = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 13}
+	oidPBKDF2 = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 12}
+)
+
+// HMAC hashes of the PBKDF2 pseudo random function, HMAC-SHA1 when none is given
+var pbkdf2PRFs = map[string]func() hash.Hash{
+	"1.2.840.113549.2.7":  sha1.New,
+	"1.2.840.113549.2.8":  sha256.New224,
+	"1.2.840.113549.2.9":  sha256.New,
+	"1.2.840.113549.2.10": sha512.New384,
+	"1.2.840.113549.2.11": sha512.New,
+}
+
+// CBC ciphers of the PBES2 encryption scheme and their key sizes
+var pbes2Ciphers = map[string]struct {
+	keySize int
+	block   func(key []byte) (cipher.Block, error)
+}{
+	"2.16.840.1.101.3.4.1.2":  {16, aes.NewCipher},
+	"2.16.840.1.101.3.4.1.22": {24, aes.NewCipher},
+	"2.16.840.1.101.3.4.1.42": {32, aes.NewCipher},
+	"1.2.840.113549.3.7":      {24, des.NewTripleDESCipher},
+}
+
+type encryptedPrivateKeyInfo struct {
+	Algorithm     pkix.AlgorithmIdentifier
+	EncryptedData []byte
+}
+
+type pbes2Params struct {
+	KeyDerivationFunc pkix.AlgorithmIdentifier
+	EncryptionScheme  pkix.AlgorithmIdentifier
+}
+
+type pbkdf2Params struct {
+	Salt           []byte
+	IterationCount int
+	KeyLength      int                      `asn1:"optional"`
+	PRF            pkix.AlgorithmIdentifier `asn1:"optional"`
+}
+
+// Wrong password or damaged content, both end in a bad padding or an unparsable key
+var errPKCS8Decrypt = errors.New("pkcs8: decryption failed")
+
+// Decrypt an ENCRYPTED PRIVATE KEY, only PBES2 with PBKDF2 and AES or 3DES in CBC mode is supported.
+// OpenSSL 1.1 and later and keytool write this scheme by default.
+func decryptPKCS8(der []byte, password string) ([]byte, error) {
+	var info encryptedPrivateKeyInfo
+	if _, err := asn1.Unmarshal(der, &info); err != nil {
+		return nil, err
+	}
+	if !info.Algorithm.Algorithm.Equal(oidPBES2) {
+		return nil, errors.New("pkcs8: unsupported encryption " + info.Algorithm.Algorithm.String())
+	}
+
+	var params pbes2Params
+	if _, err := asn1.Unmarshal(info.Algorithm.Parameters.FullBytes, &params); err != nil {
+		return nil, err
+	}
+	if !params.KeyDerivationFunc.Algorithm.Equal(oidPBKDF2) {
+		return nil, errors.New("pkcs8: unsupported key derivation " + params.KeyDerivationFunc.Algorithm.String())
+	}
+	var kdf pbkdf2Params
+	if _, err := asn1.Unmarshal(params.KeyDerivationFunc.Parameters.FullBytes, &kdf); err != nil {
+		return nil, err
+	}
+	prf := sha1.New
+	if len(kdf.PRF.Algorithm) > 0 {
+		var ok bool
+		if prf, ok = pbkdf2PRFs[kdf.PRF.Algorithm.String()]; !ok {
+			return nil, errors.New("pkcs8: unsupported PRF " + kdf.PRF.Algorithm.String())
+		}
+	}
+
+	scheme, ok := pbes2Ciphers[params.EncryptionScheme.Algorithm.String()]
+	if !ok {
+		return nil, errors.New("pkcs8: unsupported cipher " + params.EncryptionScheme.Algorithm.String())
+	}
+	var iv []byte
+	if _, err := asn1.Unmarshal(params.EncryptionScheme.Parameters.FullBytes, &iv); err != nil {
+		return nil, err
+	}
+
+	key := pbkdf2.Key([]byte(password), kdf.Salt, kdf.IterationCount, scheme.keySize, prf)
+	block, err := scheme.block(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(iv) != block.BlockSize() || len(info.EncryptedData) == 0 || len(info.EncryptedData)%block.BlockSize() != 0 {
+		return nil, errors.New("pkcs8: malformed encrypted data")
+	}
+	plain := make([]byte, len(info.EncryptedData))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(plain, info.EncryptedData)
+
+	// PKCS#7 padding, a wrong password leaves garbage here most of the time
+	pad := int(plain[len(plain)-1])
+	if pad == 0 || pad > block.BlockSize() {
+		return nil, errPKCS8Decrypt
+	}
+	for _, b := range plain[len(plain)-pad:] {
+		if int(b) != pad {
+			return nil, errPKCS8Decrypt
+		}
+	}
+	return plain[:len(plain)-pad], nil
+}
+
+// Try every password on an ENCRYPTED PRIVATE KEY, nil when none opens it
+func decryptPKCS8Block(der []byte, passwords []string) []byte {
+	for _, password := range passwords {
+		plain, err := decryptPKCS8(der, password)
+		if err != nil {
+			continue
+		}
+		// The padding can match by chance, the key must parse as well
+		if _, err := parsePrivateKey(plain); err == nil {
+			return plain
+		}
+	}
+	return nil
+}
@@ -0,0 +1,163 @@
+package crawl
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Result of a crawl, the landing page and the hosts its resources are loaded from
+type Result struct {
+	Redirects []string // every URL requested, the last one is the landing page
+	Hosts     []string // host:port of HTTPS resources, sorted
+}
+
+// Link relations pointing to other pages rather than resources of this one
+var navigationRels = []string{"canonical", "alternate", "next", "prev", "author", "license", "help", "search", "me"}
+
+// Elements and attributes referencing external resources
+var resourceAttributes = map[string][]string{
+	"script": {"src"},
+	"link":   {"href"},
+	"img":    {"src", "srcset"},
+	"iframe": {"src"},
+	"source": {"src", "srcset"},
+	"video":  {"src", "poster"},
+	"audio":  {"src"},
+	"embed":  {"src"},
+	"object": {"data"},
+	"form":   {"action"},
+}
+
+// Follow redirects from the start URL and collect the resource hosts of the landing page
+func Crawl(client *http.Client, start string, maxRedirects int, maxSize int64) (Result, error) {
+	var result Result
+
+	// Redirects are followed by hand so every hop is recorded
+	c := *client
+	c.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	current := start
+	for {
+		result.Redirects = append(result.Redirects, current)
+		resp, err := c.Get(current)
+		if err != nil {
+			return result, err
+		}
+
+		location := resp.Header.Get("Location")
+		if resp.StatusCode >= 300 && resp.StatusCode < 400 && location != "" {
+			resp.Body.Close()
+			if len(result.Redirects) > maxRedirects {
+				return result, fmt.Errorf("stopped after %d redirects", maxRedirects)
+			}
+			next, err := resp.Request.URL.Parse(location)
+			if err != nil {
+				return result, err
+			}
+			current = next.String()
+			continue
+		}
+
+		defer resp.Body.Close()
+		if !strings.Contains(resp.Header.Get("Content-Type"), "html") {
+			return result, errors.New("landing page is not HTML")
+		}
+		result.Hosts = ResourceHosts(io.LimitReader(resp.Body, maxSize), resp.Request.URL)
+		return result, nil
+	}
+}
+
+// Hosts of the HTTPS resources referenced by the page, relative references are resolved against base
+func ResourceHosts(r io.Reader, base *url.URL) []string {
+	hosts := map[string]bool{}
+	tokenizer := html.NewTokenizer(r)
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			// EOF or the size cap, a truncated page still yields the hosts seen so far
+			var list []string
+			for host := range hosts {
+				list = append(list, host)
+			}
+			sort.Strings(list)
+			return list
+
+		case html.StartTagToken, html.SelfClosingTagToken:
+			token := tokenizer.Token()
+			attributes, ok := resourceAttributes[token.Data]
+			if !ok || (token.Data == "link" && isNavigation(token)) {
+				continue
+			}
+			for _, attr := range token.Attr {
+				if !contains(attributes, attr.Key) {
+					continue
+				}
+				for _, ref := range references(attr.Key, attr.Val) {
+					if host := resourceHost(base, ref); host != "" {
+						hosts[host] = true
+					}
+				}
+			}
+		}
+	}
+}
+
+func isNavigation(token html.Token) bool {
+	for _, attr := range token.Attr {
+		if attr.Key != "rel" {
+			continue
+		}
+		for _, rel := range strings.Fields(strings.ToLower(attr.Val)) {
+			if contains(navigationRels, rel) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// srcset holds a comma separated list of "url descriptor" candidates
+func references(key string, value string) []string {
+	if key != "srcset" {
+		return []string{value}
+	}
+	var refs []string
+	for _, candidate := range strings.Split(value, ",") {
+		if fields := strings.Fields(candidate); len(fields) > 0 {
+			refs = append(refs, fields[0])
+		}
+	}
+	return refs
+}
+
+// host:port of an HTTPS reference, empty for other schemes
+func resourceHost(base *url.URL, ref string) string {
+	u, err := base.Parse(strings.TrimSpace(ref))
+	if err != nil || u.Scheme != "https" || u.Hostname() == "" {
+		return ""
+	}
+	port := u.Port()
+	if port == "" {
+		port = "443"
+	}
+	return net.JoinHostPort(strings.ToLower(u.Hostname()), port)
+}
+
+func contains(list []string, v string) bool {
+	for _, item := range list {
+		if item == v {
+			return true
+		}
+	}
+	return false
+}
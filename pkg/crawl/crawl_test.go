@@ -0,0 +1,89 @@
+package crawl
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestResourceHosts(t *testing.T) {
+	base, _ := url.Parse("https://www.example.com/shop/")
+	tests := []struct {
+		name string
+		page string
+		want []string
+	}{
+		{"scripts images and styles", `<html><head>
+			<script src="https://CDN.example.net/app.js"></script>
+			<link rel="stylesheet" href="https://fonts.example.org/css">
+			</head><body><img src="https://img.example.com:8443/logo.png"/></body></html>`,
+			[]string{"cdn.example.net:443", "fonts.example.org:443", "img.example.com:8443"}},
+		{"srcset candidates", `<img srcset="https://a.example.com/1x.png 1x, https://b.example.com/2x.png 2x">`,
+			[]string{"a.example.com:443", "b.example.com:443"}},
+		{"navigation links ignored", `<link rel="canonical" href="https://other.example.com/"><link rel="Alternate Stylesheet" href="https://alt.example.com/"><a href="https://a.example.com/">`,
+			nil},
+		{"relative references resolve to the page host", `<script src="/app.js"></script><img src="logo.png">`,
+			[]string{"www.example.com:443"}},
+		{"other schemes ignored", `<script src="http://plain.example.com/app.js"></script><img src="data:image/png;base64,AAAA"><iframe src="//frame.example.com/"></iframe>`,
+			[]string{"frame.example.com:443"}},
+		{"truncated page", `<script src="https://cdn.example.net/app.js"></script><img src="https://img.exa`,
+			[]string{"cdn.example.net:443"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ResourceHosts(strings.NewReader(tt.page), base)
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("hosts = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCrawl(t *testing.T) {
+	// /hop/N redirects to /hop/N-1, /hop/0 is the landing page
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/text":
+			w.Header().Set("Content-Type", "text/plain")
+			w.Write([]byte("hello"))
+		case r.URL.Path == "/hop/0":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.Write([]byte(`<script src="https://cdn.example.net/app.js"></script>`))
+		case strings.HasPrefix(r.URL.Path, "/hop/"):
+			n, _ := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/hop/"))
+			http.Redirect(w, r, "/hop/"+strconv.Itoa(n-1), http.StatusFound)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name      string
+		path      string
+		redirects int
+		wantErr   bool
+	}{
+		{"landing page", "/hop/0", 1, false},
+		{"within the redirect limit", "/hop/3", 4, false},
+		{"redirect limit", "/hop/4", 4, true},
+		{"not HTML", "/text", 1, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Crawl(server.Client(), server.URL+tt.path, 3, 1<<20)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(result.Redirects) != tt.redirects {
+				t.Errorf("redirects = %v, want %d", result.Redirects, tt.redirects)
+			}
+			if err == nil && (result.Redirects[len(result.Redirects)-1] != server.URL+"/hop/0" || strings.Join(result.Hosts, ",") != "cdn.example.net:443") {
+				t.Errorf("result = %+v", result)
+			}
+		})
+	}
+}
@@ -0,0 +1,137 @@
+package ct
+
+import (
+	"crypto"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Certificate Transparency log
+type Log struct {
+	Name     string
+	Operator string
+	ID       [32]byte
+	Key      crypto.PublicKey
+	Start    time.Time // temporal interval of the certificate expiry the log accepts, zero: unbounded
+	End      time.Time
+	Retired  time.Time // SCTs issued after retirement do not count, zero: not retired
+}
+
+// Log from its base64 DER encoded key
+func NewLog(name string, operator string, key string) (Log, error) {
+	der, err := base64.StdEncoding.DecodeString(key)
+	if err != nil {
+		return Log{}, fmt.Errorf("log %s: %w", name, err)
+	}
+	public, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return Log{}, fmt.Errorf("log %s: %w", name, err)
+	}
+	return Log{Name: name, Operator: operator, ID: LogID(der), Key: public}, nil
+}
+
+// Why an SCT of the log does not count for the certificate, empty when it does
+func (l Log) Rejects(sct SCT, cert *x509.Certificate) string {
+	switch {
+	case !l.Start.IsZero() && cert.NotAfter.Before(l.Start), !l.End.IsZero() && !cert.NotAfter.Before(l.End):
+		return "certificate expiry is outside the temporal interval of " + l.Name
+	case !l.Retired.IsZero() && !sct.Timestamp.Before(l.Retired):
+		return "SCT was issued after " + l.Name + " was retired"
+	case sct.Timestamp.After(time.Now()):
+		return "SCT timestamp is in the future"
+	}
+	return ""
+}
+
+// Log list in the v3 JSON format browsers publish (https://www.gstatic.com/ct/log_list/v3/log_list.json).
+// Pending and rejected logs are left out, their SCTs never count.
+func ParseLogList(data []byte) ([]Log, error) {
+	type logEntry struct {
+		Description string `json:"description"`
+		Key         string `json:"key"`
+		State       map[string]struct {
+			Timestamp time.Time `json:"timestamp"`
+		} `json:"state"`
+		TemporalInterval *struct {
+			StartInclusive time.Time `json:"start_inclusive"`
+			EndExclusive   time.Time `json:"end_exclusive"`
+		} `json:"temporal_interval"`
+	}
+	var list struct {
+		Operators []struct {
+			Name      string     `json:"name"`
+			Logs      []logEntry `json:"logs"`
+			TiledLogs []logEntry `json:"tiled_logs"`
+		} `json:"operators"`
+	}
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, err
+	}
+
+	var logs []Log
+	for _, operator := range list.Operators {
+		for _, entry := range append(operator.Logs, operator.TiledLogs...) {
+			if _, pending := entry.State["pending"]; pending {
+				continue
+			}
+			if _, rejected := entry.State["rejected"]; rejected {
+				continue
+			}
+			log, err := NewLog(entry.Description, operator.Name, entry.Key)
+			if err != nil {
+				return nil, err
+			}
+			if retired, ok := entry.State["retired"]; ok {
+				log.Retired = retired.Timestamp
+			}
+			if entry.TemporalInterval != nil {
+				log.Start = entry.TemporalInterval.StartInclusive
+				log.End = entry.TemporalInterval.EndExclusive
+			}
+			logs = append(logs, log)
+		}
+	}
+	return logs, nil
+}
+
+// Check the valid SCTs against the browser CT policy: embedded SCTs from 2 logs for certificates valid
+// up to 180 days and 3 beyond, or 2 delivered over TLS or OCSP, from at least 2 log operators either way.
+// Returns why the certificate is not compliant, empty when it is.
+func CheckPolicy(cert *x509.Certificate, valid []SCT, logs map[[32]byte]Log) string {
+	embedded, delivered := map[[32]byte]bool{}, map[[32]byte]bool{}
+	embeddedOperators, deliveredOperators := map[string]bool{}, map[string]bool{}
+	for _, sct := range valid {
+		operator := logs[sct.LogID].Operator
+		if sct.Source == SourceEmbedded {
+			embedded[sct.LogID] = true
+			embeddedOperators[operator] = true
+		} else {
+			delivered[sct.LogID] = true
+			deliveredOperators[operator] = true
+		}
+	}
+
+	required := 2
+	if cert.NotAfter.Sub(cert.NotBefore) > 180*24*time.Hour {
+		required = 3
+	}
+	if len(embedded) >= required && len(embeddedOperators) >= 2 {
+		return ""
+	}
+	if len(delivered) >= 2 && len(deliveredOperators) >= 2 {
+		return ""
+	}
+	if len(valid) == 0 {
+		return "no valid SCTs"
+	}
+	if len(embedded) >= required || len(delivered) >= 2 {
+		return "valid SCTs come from a single log operator, at least 2 are required"
+	}
+	if len(delivered) > 0 {
+		return fmt.Sprintf("%d valid SCTs delivered over TLS or OCSP, 2 are required", len(delivered))
+	}
+	return fmt.Sprintf("%d valid embedded SCTs, %d are required", len(embedded), required)
+}
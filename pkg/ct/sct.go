@@ -0,0 +1,243 @@
+package ct
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"time"
+
+	"golang.org/x/crypto/cryptobyte"
+	cbasn1 "golang.org/x/crypto/cryptobyte/asn1"
+	"golang.org/x/crypto/ocsp"
+)
+
+// Where an SCT was delivered
+const (
+	SourceEmbedded = "embedded"
+	SourceTLS      = "tls"
+	SourceOCSP     = "ocsp"
+)
+
+var (
+	// Embedded SCT list of a certificate (RFC 6962, 3.3)
+	oidSCTList = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 11129, 2, 4, 2}
+	// SCT list in a single response of an OCSP response (RFC 6962, 3.3)
+	oidOCSPSCTList = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 11129, 2, 4, 5}
+)
+
+// Signed certificate timestamp (RFC 6962, 3.2)
+type SCT struct {
+	Source     string
+	Version    uint8
+	LogID      [32]byte
+	Timestamp  time.Time
+	Extensions []byte
+	HashAlg    uint8
+	SigAlg     uint8
+	Signature  []byte
+}
+
+// Every SCT of the certificate: embedded, from the TLS extension and from the stapled OCSP response
+func Collect(cert *x509.Certificate, tlsSCTs [][]byte, staple []byte) ([]SCT, error) {
+	var scts []SCT
+	var errs []error
+	for _, ext := range cert.Extensions {
+		if ext.Id.Equal(oidSCTList) {
+			list, err := parseExtensionList(ext.Value, SourceEmbedded)
+			scts = append(scts, list...)
+			errs = append(errs, err)
+		}
+	}
+	for _, raw := range tlsSCTs {
+		sct, err := ParseSCT(raw, SourceTLS)
+		if err == nil {
+			scts = append(scts, sct)
+		}
+		errs = append(errs, err)
+	}
+	if len(staple) > 0 {
+		// The issuer is not needed to read the extensions, revocation checks the signature
+		if resp, err := ocsp.ParseResponse(staple, nil); err == nil {
+			for _, ext := range resp.Extensions {
+				if ext.Id.Equal(oidOCSPSCTList) {
+					list, err := parseExtensionList(ext.Value, SourceOCSP)
+					scts = append(scts, list...)
+					errs = append(errs, err)
+				}
+			}
+		}
+	}
+	return scts, errors.Join(errs...)
+}
+
+// Extension value: an OCTET STRING holding the TLS encoded SignedCertificateTimestampList
+func parseExtensionList(value []byte, source string) ([]SCT, error) {
+	var list []byte
+	if _, err := asn1.Unmarshal(value, &list); err != nil {
+		return nil, fmt.Errorf("malformed SCT list: %w", err)
+	}
+	return ParseSCTList(list, source)
+}
+
+// Parse a SignedCertificateTimestampList
+func ParseSCTList(data []byte, source string) ([]SCT, error) {
+	s := cryptobyte.String(data)
+	var list cryptobyte.String
+	if !s.ReadUint16LengthPrefixed(&list) || !s.Empty() {
+		return nil, errors.New("malformed SCT list")
+	}
+	var scts []SCT
+	for !list.Empty() {
+		var raw cryptobyte.String
+		if !list.ReadUint16LengthPrefixed(&raw) {
+			return scts, errors.New("malformed SCT list")
+		}
+		sct, err := ParseSCT(raw, source)
+		if err != nil {
+			return scts, err
+		}
+		scts = append(scts, sct)
+	}
+	return scts, nil
+}
+
+// Parse a single serialized SCT
+func ParseSCT(data []byte, source string) (SCT, error) {
+	sct := SCT{Source: source}
+	s := cryptobyte.String(data)
+	var logID []byte
+	var timestamp uint64
+	var extensions, signature cryptobyte.String
+	if !s.ReadUint8(&sct.Version) ||
+		!s.ReadBytes(&logID, 32) ||
+		!s.ReadUint64(&timestamp) ||
+		!s.ReadUint16LengthPrefixed(&extensions) ||
+		!s.ReadUint8(&sct.HashAlg) ||
+		!s.ReadUint8(&sct.SigAlg) ||
+		!s.ReadUint16LengthPrefixed(&signature) ||
+		!s.Empty() {
+		return sct, errors.New("malformed SCT")
+	}
+	if sct.Version != 0 {
+		return sct, fmt.Errorf("unsupported SCT version %d", sct.Version)
+	}
+	copy(sct.LogID[:], logID)
+	sct.Timestamp = time.UnixMilli(int64(timestamp)).UTC()
+	sct.Extensions = extensions
+	sct.Signature = signature
+	return sct, nil
+}
+
+// Verify the SCT signature with the log key. Embedded SCTs sign the precertificate, which needs the issuer.
+func (s SCT) Verify(key crypto.PublicKey, cert *x509.Certificate, issuer *x509.Certificate) error {
+	if s.HashAlg != 4 { // sha256
+		return fmt.Errorf("unsupported SCT hash algorithm %d", s.HashAlg)
+	}
+
+	var b cryptobyte.Builder
+	b.AddUint8(s.Version)
+	b.AddUint8(0) // certificate_timestamp
+	b.AddUint64(uint64(s.Timestamp.UnixMilli()))
+	if s.Source == SourceEmbedded {
+		if issuer == nil {
+			return errors.New("issuer of the precertificate is unknown")
+		}
+		tbs, err := removeSCTExtension(cert.RawTBSCertificate)
+		if err != nil {
+			return err
+		}
+		keyHash := sha256.Sum256(issuer.RawSubjectPublicKeyInfo)
+		b.AddUint16(1) // precert_entry
+		b.AddBytes(keyHash[:])
+		b.AddUint24LengthPrefixed(func(b *cryptobyte.Builder) { b.AddBytes(tbs) })
+	} else {
+		b.AddUint16(0) // x509_entry
+		b.AddUint24LengthPrefixed(func(b *cryptobyte.Builder) { b.AddBytes(cert.Raw) })
+	}
+	b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) { b.AddBytes(s.Extensions) })
+	signed, err := b.Bytes()
+	if err != nil {
+		return err
+	}
+	digest := sha256.Sum256(signed)
+
+	switch k := key.(type) {
+	case *ecdsa.PublicKey:
+		if s.SigAlg != 3 || !ecdsa.VerifyASN1(k, digest[:], s.Signature) {
+			return errors.New("invalid SCT signature")
+		}
+	case *rsa.PublicKey:
+		if s.SigAlg != 1 || rsa.VerifyPKCS1v15(k, crypto.SHA256, digest[:], s.Signature) != nil {
+			return errors.New("invalid SCT signature")
+		}
+	default:
+		return fmt.Errorf("unsupported log key %T", key)
+	}
+	return nil
+}
+
+// TBSCertificate of the precertificate: the final one without the SCT list extension (RFC 6962, 3.2)
+func removeSCTExtension(tbs []byte) ([]byte, error) {
+	input := cryptobyte.String(tbs)
+	var body cryptobyte.String
+	if !input.ReadASN1(&body, cbasn1.SEQUENCE) {
+		return nil, errors.New("malformed TBSCertificate")
+	}
+
+	var b cryptobyte.Builder
+	var err error
+	b.AddASN1(cbasn1.SEQUENCE, func(b *cryptobyte.Builder) {
+		for !body.Empty() {
+			var element cryptobyte.String
+			var tag cbasn1.Tag
+			if !body.ReadAnyASN1Element(&element, &tag) {
+				err = errors.New("malformed TBSCertificate")
+				return
+			}
+			if tag != cbasn1.Tag(3).Constructed().ContextSpecific() {
+				b.AddBytes(element)
+				continue
+			}
+
+			var wrapper, extensions cryptobyte.String
+			if !element.ReadASN1(&wrapper, tag) || !wrapper.ReadASN1(&extensions, cbasn1.SEQUENCE) {
+				err = errors.New("malformed certificate extensions")
+				return
+			}
+			b.AddASN1(tag, func(b *cryptobyte.Builder) {
+				b.AddASN1(cbasn1.SEQUENCE, func(b *cryptobyte.Builder) {
+					for !extensions.Empty() {
+						var extension, contents cryptobyte.String
+						var oid asn1.ObjectIdentifier
+						if !extensions.ReadASN1Element(&extension, cbasn1.SEQUENCE) {
+							err = errors.New("malformed certificate extension")
+							return
+						}
+						contents = extension
+						if !contents.ReadASN1(&contents, cbasn1.SEQUENCE) || !contents.ReadASN1ObjectIdentifier(&oid) {
+							err = errors.New("malformed certificate extension")
+							return
+						}
+						if !oid.Equal(oidSCTList) {
+							b.AddBytes(extension)
+						}
+					}
+				})
+			})
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return b.Bytes()
+}
+
+// Log ID of a log key: SHA-256 of its DER encoded SubjectPublicKeyInfo
+func LogID(keyDER []byte) [32]byte {
+	return sha256.Sum256(keyDER)
+}
@@ -0,0 +1,241 @@
+package ct
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"math/big"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/cryptobyte"
+)
+
+var (
+	caKey, _     = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	leafKey, _   = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	ecLogKey, _  = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	rsaLogKey, _ = rsa.GenerateKey(rand.Reader, 2048)
+)
+
+func testIssuer(t *testing.T) *x509.Certificate {
+	t.Helper()
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return cert
+}
+
+// Leaf issued by the CA, with the extensions added after the standard ones
+func testLeaf(t *testing.T, issuer *x509.Certificate, extensions ...pkix.Extension) *x509.Certificate {
+	t.Helper()
+	tmpl := &x509.Certificate{
+		SerialNumber:    big.NewInt(42),
+		Subject:         pkix.Name{CommonName: "www.example.com"},
+		DNSNames:        []string{"www.example.com"},
+		NotBefore:       time.Unix(1700000000, 0),
+		NotAfter:        time.Unix(1700000000, 0).Add(90 * 24 * time.Hour),
+		ExtraExtensions: extensions,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, issuer, &leafKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return cert
+}
+
+// Serialized SCT signed by the log over the x509 or precert entry
+func signSCT(t *testing.T, key crypto.Signer, timestamp time.Time, entry func(b *cryptobyte.Builder)) []byte {
+	t.Helper()
+	var b cryptobyte.Builder
+	b.AddUint8(0)
+	b.AddUint8(0)
+	b.AddUint64(uint64(timestamp.UnixMilli()))
+	entry(&b)
+	b.AddUint16(0) // no extensions
+	signed := b.BytesOrPanic()
+	digest := sha256.Sum256(signed)
+
+	sigAlg := uint8(3)
+	if _, ok := key.(*rsa.PrivateKey); ok {
+		sigAlg = 1
+	}
+	signature, err := key.Sign(rand.Reader, digest[:], crypto.SHA256)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	der, _ := x509.MarshalPKIXPublicKey(key.Public())
+	logID := LogID(der)
+	var sct cryptobyte.Builder
+	sct.AddUint8(0)
+	sct.AddBytes(logID[:])
+	sct.AddUint64(uint64(timestamp.UnixMilli()))
+	sct.AddUint16(0)
+	sct.AddUint8(4)
+	sct.AddUint8(sigAlg)
+	sct.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) { b.AddBytes(signature) })
+	return sct.BytesOrPanic()
+}
+
+func precertEntry(issuer *x509.Certificate, tbs []byte) func(b *cryptobyte.Builder) {
+	return func(b *cryptobyte.Builder) {
+		keyHash := sha256.Sum256(issuer.RawSubjectPublicKeyInfo)
+		b.AddUint16(1)
+		b.AddBytes(keyHash[:])
+		b.AddUint24LengthPrefixed(func(b *cryptobyte.Builder) { b.AddBytes(tbs) })
+	}
+}
+
+func x509Entry(cert *x509.Certificate) func(b *cryptobyte.Builder) {
+	return func(b *cryptobyte.Builder) {
+		b.AddUint16(0)
+		b.AddUint24LengthPrefixed(func(b *cryptobyte.Builder) { b.AddBytes(cert.Raw) })
+	}
+}
+
+// SCT list extension of the given serialized SCTs
+func sctListExtension(scts ...[]byte) pkix.Extension {
+	var b cryptobyte.Builder
+	b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) {
+		for _, sct := range scts {
+			b.AddUint16LengthPrefixed(func(b *cryptobyte.Builder) { b.AddBytes(sct) })
+		}
+	})
+	value, _ := asn1.Marshal(b.BytesOrPanic())
+	return pkix.Extension{Id: oidSCTList, Value: value}
+}
+
+func TestRemoveSCTExtension(t *testing.T) {
+	issuer := testIssuer(t)
+	other := pkix.Extension{Id: asn1.ObjectIdentifier{1, 2, 3, 4}, Value: []byte{5, 0}}
+	precert := testLeaf(t, issuer, other)
+	final := testLeaf(t, issuer, other, sctListExtension([]byte{1, 2, 3}))
+
+	tests := []struct {
+		name    string
+		tbs     []byte
+		want    []byte
+		wantErr bool
+	}{
+		{"SCT list removed", final.RawTBSCertificate, precert.RawTBSCertificate, false},
+		{"nothing to remove", precert.RawTBSCertificate, precert.RawTBSCertificate, false},
+		{"not a sequence", []byte{0x04, 0x01, 0x00}, nil, true},
+		{"truncated", final.RawTBSCertificate[:len(final.RawTBSCertificate)-10], nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tbs, err := removeSCTExtension(tt.tbs)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !bytes.Equal(tbs, tt.want) {
+				t.Error("TBSCertificate differs from the precertificate")
+			}
+		})
+	}
+}
+
+func TestVerify(t *testing.T) {
+	issuer := testIssuer(t)
+	timestamp := time.Unix(1700000100, 0)
+	precert := testLeaf(t, issuer)
+	embedded := signSCT(t, ecLogKey, timestamp, precertEntry(issuer, precert.RawTBSCertificate))
+	embeddedRSA := signSCT(t, rsaLogKey, timestamp, precertEntry(issuer, precert.RawTBSCertificate))
+	cert := testLeaf(t, issuer, sctListExtension(embedded, embeddedRSA))
+	tlsSCT := signSCT(t, ecLogKey, timestamp, x509Entry(cert))
+
+	collected, err := Collect(cert, [][]byte{tlsSCT}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(collected) != 3 || collected[0].Source != SourceEmbedded || collected[2].Source != SourceTLS {
+		t.Fatalf("collected %+v", collected)
+	}
+	if !collected[0].Timestamp.Equal(timestamp) {
+		t.Errorf("timestamp = %s, want %s", collected[0].Timestamp, timestamp)
+	}
+
+	tampered := collected[2]
+	tampered.Timestamp = tampered.Timestamp.Add(time.Millisecond)
+	sha384 := collected[2]
+	sha384.HashAlg = 5
+	// The precertificate entry binds the issuer key, not its name
+	otherKey, _ := x509.MarshalPKIXPublicKey(&leafKey.PublicKey)
+	otherIssuer := &x509.Certificate{RawSubjectPublicKeyInfo: otherKey}
+	tests := []struct {
+		name    string
+		sct     SCT
+		key     crypto.PublicKey
+		issuer  *x509.Certificate
+		wantErr bool
+	}{
+		{"embedded ECDSA", collected[0], &ecLogKey.PublicKey, issuer, false},
+		{"embedded RSA", collected[1], &rsaLogKey.PublicKey, issuer, false},
+		{"TLS extension", collected[2], &ecLogKey.PublicKey, nil, false},
+		{"embedded without issuer", collected[0], &ecLogKey.PublicKey, nil, true},
+		{"embedded with the wrong issuer", collected[0], &ecLogKey.PublicKey, otherIssuer, true},
+		{"key of another log", collected[0], &rsaLogKey.PublicKey, issuer, true},
+		{"tampered timestamp", tampered, &ecLogKey.PublicKey, nil, true},
+		{"unsupported hash", sha384, &ecLogKey.PublicKey, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.sct.Verify(tt.key, cert, tt.issuer)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseSCT(t *testing.T) {
+	valid := signSCT(t, ecLogKey, time.Unix(1700000100, 0), func(b *cryptobyte.Builder) {})
+	v2 := append([]byte{1}, valid[1:]...)
+
+	tests := []struct {
+		name    string
+		data    []byte
+		wantErr bool
+	}{
+		{"valid", valid, false},
+		{"trailing data", append(append([]byte{}, valid...), 0), true},
+		{"truncated", valid[:40], true},
+		{"unsupported version", v2, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sct, err := ParseSCT(tt.data, SourceTLS)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			der, _ := x509.MarshalPKIXPublicKey(&ecLogKey.PublicKey)
+			if err == nil && (sct.LogID != LogID(der) || sct.HashAlg != 4 || sct.SigAlg != 3) {
+				t.Errorf("sct = %+v", sct)
+			}
+		})
+	}
+
+	if _, err := ParseSCTList([]byte{0x00, 0x05, 0x00}, SourceTLS); err == nil {
+		t.Error("list with a wrong length was parsed")
+	}
+}
@@ -0,0 +1,132 @@
+package dane
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+
+	"sentinel/pkg/dnsquery"
+)
+
+// Certificate usages (RFC 7218)
+const (
+	UsagePKIXTA = 0
+	UsagePKIXEE = 1
+	UsageDANETA = 2
+	UsageDANEEE = 3
+)
+
+// Validation states
+const (
+	StatusValid    = "valid"
+	StatusMismatch = "mismatch"
+	StatusNone     = "none"
+)
+
+// Validation result of a TLSA record set
+type Result struct {
+	Status   string
+	Matched  []dnsquery.TLSA // records the presented chain satisfies
+	Unusable []dnsquery.TLSA // records with unknown parameters, ignored like a DANE client does (RFC 7671, 4)
+}
+
+// Validate the TLSA records against the presented chain. verified is the chain built from the
+// system roots, nil when PKIX validation failed. hostname is checked for the PKIX and DANE-TA usages.
+func Validate(records []dnsquery.TLSA, presented []*x509.Certificate, verified []*x509.Certificate, hostname string) Result {
+	result := Result{Status: StatusNone}
+	if len(records) == 0 || len(presented) == 0 {
+		return result
+	}
+
+	usable := 0
+	for _, record := range records {
+		if !isUsable(record) {
+			result.Unusable = append(result.Unusable, record)
+			continue
+		}
+		usable++
+		if matchesRecord(record, presented, verified, hostname) {
+			result.Matched = append(result.Matched, record)
+		}
+	}
+	switch {
+	case len(result.Matched) > 0:
+		result.Status = StatusValid
+	case usable > 0:
+		result.Status = StatusMismatch
+	}
+	return result
+}
+
+func isUsable(record dnsquery.TLSA) bool {
+	return record.Usage <= UsageDANEEE && record.Selector <= 1 && record.MatchingType <= 2
+}
+
+func matchesRecord(record dnsquery.TLSA, presented []*x509.Certificate, verified []*x509.Certificate, hostname string) bool {
+	leaf := presented[0]
+	switch record.Usage {
+	case UsageDANEEE:
+		// Only the key or certificate counts, no name or expiry checks (RFC 7671, 5.1)
+		return Matches(record, leaf)
+
+	case UsagePKIXEE:
+		return verified != nil && leaf.VerifyHostname(hostname) == nil && Matches(record, leaf)
+
+	case UsagePKIXTA:
+		if verified == nil || leaf.VerifyHostname(hostname) != nil {
+			return false
+		}
+		for _, cert := range verified[1:] {
+			if Matches(record, cert) {
+				return true
+			}
+		}
+
+	case UsageDANETA:
+		// The trust anchor has to be in the presented chain, the leaf must chain up to it
+		for _, anchor := range presented[1:] {
+			if Matches(record, anchor) && chainsTo(presented, anchor, hostname) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Whether the leaf verifies up to the anchor through the presented intermediates
+func chainsTo(presented []*x509.Certificate, anchor *x509.Certificate, hostname string) bool {
+	roots := x509.NewCertPool()
+	roots.AddCert(anchor)
+	intermediates := x509.NewCertPool()
+	for _, cert := range presented[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, err := presented[0].Verify(x509.VerifyOptions{
+		DNSName:       hostname,
+		Roots:         roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	return err == nil
+}
+
+// Whether the certificate matches the selector and matching type of the record
+func Matches(record dnsquery.TLSA, cert *x509.Certificate) bool {
+	selected := cert.Raw
+	if record.Selector == 1 {
+		selected = cert.RawSubjectPublicKeyInfo
+	}
+
+	switch record.MatchingType {
+	case 0:
+		return bytes.Equal(selected, record.Data)
+	case 1:
+		sum := sha256.Sum256(selected)
+		return bytes.Equal(sum[:], record.Data)
+	case 2:
+		sum := sha512.Sum512(selected)
+		return bytes.Equal(sum[:], record.Data)
+	}
+	return false
+}
@@ -0,0 +1,132 @@
+package dane
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"testing"
+	"time"
+
+	"sentinel/pkg/dnsquery"
+)
+
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func issue(t *testing.T, cn string, parent *testCert, ca bool, notAfter time.Time) *testCert {
+	t.Helper()
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: cn},
+		NotBefore:             time.Now().Add(-48 * time.Hour),
+		NotAfter:              notAfter,
+		BasicConstraintsValid: true,
+		IsCA:                  ca,
+	}
+	if ca {
+		tmpl.KeyUsage = x509.KeyUsageCertSign
+	} else {
+		tmpl.DNSNames = []string{cn}
+	}
+	issuer, signer := tmpl, key
+	if parent != nil {
+		issuer, signer = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, issuer, &key.PublicKey, signer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return &testCert{cert: cert, key: key}
+}
+
+func spki256(cert *x509.Certificate) []byte {
+	sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return sum[:]
+}
+
+func TestMatches(t *testing.T) {
+	leaf := issue(t, "mail.example.com", nil, false, time.Now().Add(time.Hour)).cert
+	spki512 := sha512.Sum512(leaf.RawSubjectPublicKeyInfo)
+	cert256 := sha256.Sum256(leaf.Raw)
+
+	tests := []struct {
+		name   string
+		record dnsquery.TLSA
+		want   bool
+	}{
+		{"full certificate exact", dnsquery.TLSA{Selector: 0, MatchingType: 0, Data: leaf.Raw}, true},
+		{"full certificate SHA-256", dnsquery.TLSA{Selector: 0, MatchingType: 1, Data: cert256[:]}, true},
+		{"public key SHA-256", dnsquery.TLSA{Selector: 1, MatchingType: 1, Data: spki256(leaf)}, true},
+		{"public key SHA-512", dnsquery.TLSA{Selector: 1, MatchingType: 2, Data: spki512[:]}, true},
+		{"selector mixed up", dnsquery.TLSA{Selector: 0, MatchingType: 1, Data: spki256(leaf)}, false},
+		{"unknown matching type", dnsquery.TLSA{Selector: 1, MatchingType: 3, Data: spki256(leaf)}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Matches(tt.record, leaf); got != tt.want {
+				t.Errorf("Matches = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	later := time.Now().Add(24 * time.Hour)
+	root := issue(t, "Root", nil, true, later)
+	intermediate := issue(t, "Intermediate", root, true, later)
+	leaf := issue(t, "mail.example.com", intermediate, false, later)
+	expired := issue(t, "mail.example.com", intermediate, false, time.Now().Add(-time.Hour))
+	other := issue(t, "other.example.com", nil, false, later)
+
+	presented := []*x509.Certificate{leaf.cert, intermediate.cert}
+	verified := []*x509.Certificate{leaf.cert, intermediate.cert, root.cert}
+	record := func(usage uint8, cert *x509.Certificate) dnsquery.TLSA {
+		return dnsquery.TLSA{Usage: usage, Selector: 1, MatchingType: 1, Data: spki256(cert)}
+	}
+	unusable := dnsquery.TLSA{Usage: 4, Selector: 1, MatchingType: 1, Data: spki256(leaf.cert)}
+
+	tests := []struct {
+		name      string
+		records   []dnsquery.TLSA
+		presented []*x509.Certificate
+		verified  []*x509.Certificate
+		hostname  string
+		status    string
+		matched   int
+		unusable  int
+	}{
+		{"no records", nil, presented, verified, "mail.example.com", StatusNone, 0, 0},
+		{"DANE-EE ignores the name", []dnsquery.TLSA{record(UsageDANEEE, leaf.cert)}, presented, nil, "other.name", StatusValid, 1, 0},
+		{"DANE-EE ignores expiry", []dnsquery.TLSA{record(UsageDANEEE, expired.cert)}, []*x509.Certificate{expired.cert}, nil, "mail.example.com", StatusValid, 1, 0},
+		{"DANE-EE of another key", []dnsquery.TLSA{record(UsageDANEEE, other.cert)}, presented, verified, "mail.example.com", StatusMismatch, 0, 0},
+		{"DANE-TA presented intermediate", []dnsquery.TLSA{record(UsageDANETA, intermediate.cert)}, presented, nil, "mail.example.com", StatusValid, 1, 0},
+		{"DANE-TA wrong hostname", []dnsquery.TLSA{record(UsageDANETA, intermediate.cert)}, presented, nil, "www.example.com", StatusMismatch, 0, 0},
+		{"DANE-TA anchor not presented", []dnsquery.TLSA{record(UsageDANETA, root.cert)}, presented, verified, "mail.example.com", StatusMismatch, 0, 0},
+		{"DANE-TA expired leaf", []dnsquery.TLSA{record(UsageDANETA, intermediate.cert)}, []*x509.Certificate{expired.cert, intermediate.cert}, nil, "mail.example.com", StatusMismatch, 0, 0},
+		{"PKIX-EE", []dnsquery.TLSA{record(UsagePKIXEE, leaf.cert)}, presented, verified, "mail.example.com", StatusValid, 1, 0},
+		{"PKIX-EE without PKIX validation", []dnsquery.TLSA{record(UsagePKIXEE, leaf.cert)}, presented, nil, "mail.example.com", StatusMismatch, 0, 0},
+		{"PKIX-EE wrong hostname", []dnsquery.TLSA{record(UsagePKIXEE, leaf.cert)}, presented, verified, "www.example.com", StatusMismatch, 0, 0},
+		{"PKIX-TA root of the verified chain", []dnsquery.TLSA{record(UsagePKIXTA, root.cert)}, presented, verified, "mail.example.com", StatusValid, 1, 0},
+		{"PKIX-TA leaf is not an anchor", []dnsquery.TLSA{record(UsagePKIXTA, leaf.cert)}, presented, verified, "mail.example.com", StatusMismatch, 0, 0},
+		{"only unusable records", []dnsquery.TLSA{unusable}, presented, verified, "mail.example.com", StatusNone, 0, 1},
+		{"one matching record is enough", []dnsquery.TLSA{unusable, record(UsageDANEEE, other.cert), record(UsageDANEEE, leaf.cert)}, presented, verified, "mail.example.com", StatusValid, 1, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := Validate(tt.records, tt.presented, tt.verified, tt.hostname)
+			if result.Status != tt.status || len(result.Matched) != tt.matched || len(result.Unusable) != tt.unusable {
+				t.Errorf("got %s with %d matched and %d unusable, want %s with %d and %d",
+					result.Status, len(result.Matched), len(result.Unusable), tt.status, tt.matched, tt.unusable)
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package dnsquery
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// CAA flag telling CAs to refuse issuance when they do not understand the tag
+const caaCritical = 128
+
+// CAA record (RFC 8659)
+type CAA struct {
+	Flags uint8
+	Tag   string
+	Value string
+}
+
+// Zone file presentation like `0 issue "letsencrypt.org"`
+func (c CAA) String() string {
+	return fmt.Sprintf("%d %s %q", c.Flags, c.Tag, c.Value)
+}
+
+// Whether CAs must refuse issuance when they do not understand the tag
+func (c CAA) Critical() bool {
+	return c.Flags&caaCritical != 0
+}
+
+// Issuer domain of an issue or issuewild value, empty when issuance is forbidden
+func (c CAA) IssuerDomain() string {
+	domain, _, _ := strings.Cut(c.Value, ";")
+	return strings.ToLower(strings.TrimSpace(domain))
+}
+
+// Parse the RDATA of a CAA record
+func ParseCAA(data []byte) (CAA, error) {
+	if len(data) < 2 || len(data) < 2+int(data[1]) {
+		return CAA{}, errors.New("malformed CAA record")
+	}
+	tagLength := int(data[1])
+	return CAA{
+		Flags: data[0],
+		Tag:   strings.ToLower(string(data[2 : 2+tagLength])),
+		Value: string(data[2+tagLength:]),
+	}, nil
+}
+
+// Relevant CAA record set of the host: the records of the closest name up the tree (RFC 8659, 3).
+// The name the records were found at is returned with them, nothing found means no restriction.
+func LookupCAA(server string, host string) (string, []CAA, error) {
+	labels := strings.Split(strings.TrimSuffix(strings.ToLower(host), "."), ".")
+	for i := range labels {
+		name := strings.Join(labels[i:], ".")
+		resp, err := Query(server, name, TypeCAA)
+		if err != nil {
+			return name, nil, err
+		}
+		if resp.RCode != dnsmessage.RCodeSuccess && resp.RCode != dnsmessage.RCodeNameError {
+			return name, nil, fmt.Errorf("CAA lookup of %s failed with %s", name, resp.RCode)
+		}
+
+		var records []CAA
+		for _, answer := range resp.Answers {
+			record, err := ParseCAA(answer.Data)
+			if err != nil {
+				return name, nil, err
+			}
+			records = append(records, record)
+		}
+		if len(records) > 0 {
+			return name, records, nil
+		}
+	}
+	return "", nil, nil
+}
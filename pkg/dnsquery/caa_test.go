@@ -0,0 +1,100 @@
+package dnsquery
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+func caaData(flags uint8, tag, value string) []byte {
+	return append(append([]byte{flags, uint8(len(tag))}, tag...), value...)
+}
+
+func TestParseCAA(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     []byte
+		want     CAA
+		critical bool
+		issuer   string
+		wantErr  bool
+	}{
+		{"issue", caaData(0, "issue", "letsencrypt.org"), CAA{0, "issue", "letsencrypt.org"}, false, "letsencrypt.org", false},
+		{"parameters", caaData(0, "issue", "Pki.Goog; validationmethods=dns-01"), CAA{0, "issue", "Pki.Goog; validationmethods=dns-01"}, false, "pki.goog", false},
+		{"issuance forbidden", caaData(0, "issuewild", ";"), CAA{0, "issuewild", ";"}, false, "", false},
+		{"critical unknown tag", caaData(128, "Future", "x"), CAA{128, "future", "x"}, true, "x", false},
+		{"empty value", caaData(0, "iodef", ""), CAA{0, "iodef", ""}, false, "", false},
+		{"too short", []byte{0}, CAA{}, false, "", true},
+		{"tag past the end", []byte{0, 10, 'i', 's'}, CAA{}, false, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			record, err := ParseCAA(tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if record != tt.want || record.Critical() != tt.critical || record.IssuerDomain() != tt.issuer {
+				t.Errorf("got %s critical %v issuer %q, want %s critical %v issuer %q",
+					record, record.Critical(), record.IssuerDomain(), tt.want, tt.critical, tt.issuer)
+			}
+		})
+	}
+}
+
+func TestLookupCAA(t *testing.T) {
+	caa := func(name string, value string) dnsmessage.Resource {
+		return resource(name, &dnsmessage.UnknownResource{Type: TypeCAA, Data: caaData(0, "issue", value)})
+	}
+	zone := map[string][]dnsmessage.Resource{
+		"example.com.":      {caa("example.com.", "letsencrypt.org"), caa("example.com.", "pki.goog")},
+		"shop.example.com.": nil, // exists without CAA records
+		"api.example.com.":  {caa("api.example.com.", "digicert.com")},
+	}
+	server := newResponder(t, func(q dnsmessage.Question, tcp bool) dnsmessage.Message {
+		if strings.HasSuffix(q.Name.String(), "broken.example.org.") {
+			return dnsmessage.Message{Header: dnsmessage.Header{RCode: dnsmessage.RCodeServerFailure}}
+		}
+		answers, ok := zone[q.Name.String()]
+		if !ok {
+			return dnsmessage.Message{Header: dnsmessage.Header{RCode: dnsmessage.RCodeNameError}}
+		}
+		return dnsmessage.Message{Answers: answers}
+	})
+
+	tests := []struct {
+		host    string
+		name    string
+		records string
+		queries string
+		wantErr bool
+	}{
+		{"www.shop.example.com", "example.com", "0 issue \"letsencrypt.org\",0 issue \"pki.goog\"",
+			"udp www.shop.example.com.,udp shop.example.com.,udp example.com.", false},
+		{"API.example.com.", "api.example.com", "0 issue \"digicert.com\"", "udp api.example.com.", false},
+		{"www.example.net", "", "", "udp www.example.net.,udp example.net.,udp net.", false},
+		{"www.broken.example.org", "www.broken.example.org", "", "udp www.broken.example.org.", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			server.reset()
+			name, records, err := LookupCAA(server.addr, tt.host)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			var list []string
+			for _, record := range records {
+				list = append(list, record.String())
+			}
+			if name != tt.name || strings.Join(list, ",") != tt.records {
+				t.Errorf("got %q with %v, want %q with %s", name, list, tt.name, tt.records)
+			}
+			if got := server.log(); got != tt.queries {
+				t.Errorf("queries = %s, want %s", got, tt.queries)
+			}
+		})
+	}
+}
@@ -0,0 +1,196 @@
+package dnsquery
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// Record types the standard resolver cannot query
+const (
+	TypeTLSA dnsmessage.Type = 52
+	TypeCAA  dnsmessage.Type = 257
+)
+
+// Timeout of a single query
+var Timeout = 5 * time.Second
+
+// Answer record of the queried type, Data is the raw RDATA
+type Record struct {
+	Name string
+	TTL  uint32
+	Data []byte
+}
+
+// Response of a query
+type Response struct {
+	RCode         dnsmessage.RCode
+	AuthenticData bool // the resolver validated the answer with DNSSEC
+	Answers       []Record
+}
+
+// Query the server for records of the given type, retried over TCP when the UDP answer is truncated
+func Query(server string, name string, qtype dnsmessage.Type) (Response, error) {
+	server = ServerAddress(server)
+	msg, id, err := buildQuery(name, qtype)
+	if err != nil {
+		return Response{}, err
+	}
+
+	raw, err := exchange("udp", server, msg)
+	if err != nil {
+		return Response{}, err
+	}
+	var header dnsmessage.Header
+	var p dnsmessage.Parser
+	if header, err = p.Start(raw); err != nil {
+		return Response{}, err
+	}
+	if header.Truncated {
+		if raw, err = exchange("tcp", server, msg); err != nil {
+			return Response{}, err
+		}
+		if header, err = p.Start(raw); err != nil {
+			return Response{}, err
+		}
+	}
+	if header.ID != id {
+		return Response{}, errors.New("DNS response ID mismatch")
+	}
+
+	resp := Response{RCode: header.RCode, AuthenticData: header.AuthenticData}
+	if err := p.SkipAllQuestions(); err != nil {
+		return resp, err
+	}
+	for {
+		h, err := p.AnswerHeader()
+		if errors.Is(err, dnsmessage.ErrSectionDone) {
+			return resp, nil
+		}
+		if err != nil {
+			return resp, err
+		}
+		if h.Type != qtype {
+			// CNAMEs on the way to the answer
+			if err := p.SkipAnswer(); err != nil {
+				return resp, err
+			}
+			continue
+		}
+		body, err := p.UnknownResource()
+		if err != nil {
+			return resp, err
+		}
+		resp.Answers = append(resp.Answers, Record{Name: h.Name.String(), TTL: h.TTL, Data: body.Data})
+	}
+}
+
+// Default to port 53, an empty server means the system resolver
+func ServerAddress(server string) string {
+	if server == "" {
+		server = SystemServer()
+	}
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		server = net.JoinHostPort(server, "53")
+	}
+	return server
+}
+
+// First nameserver of /etc/resolv.conf
+func SystemServer() string {
+	f, err := os.Open("/etc/resolv.conf")
+	if err != nil {
+		return "127.0.0.1"
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 2 && fields[0] == "nameserver" {
+			return fields[1]
+		}
+	}
+	return "127.0.0.1"
+}
+
+func buildQuery(name string, qtype dnsmessage.Type) ([]byte, uint16, error) {
+	if !strings.HasSuffix(name, ".") {
+		name += "."
+	}
+	qname, err := dnsmessage.NewName(name)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// AD asks a validating resolver to report whether the answer is authenticated (RFC 6840, 5.7)
+	id := uint16(rand.Uint32())
+	b := dnsmessage.NewBuilder(nil, dnsmessage.Header{ID: id, RecursionDesired: true, AuthenticData: true})
+	b.EnableCompression()
+	if err := b.StartQuestions(); err != nil {
+		return nil, 0, err
+	}
+	if err := b.Question(dnsmessage.Question{Name: qname, Type: qtype, Class: dnsmessage.ClassINET}); err != nil {
+		return nil, 0, err
+	}
+
+	// EDNS0 for answers bigger than 512 bytes
+	if err := b.StartAdditionals(); err != nil {
+		return nil, 0, err
+	}
+	var opt dnsmessage.ResourceHeader
+	if err := opt.SetEDNS0(4096, dnsmessage.RCodeSuccess, true); err != nil {
+		return nil, 0, err
+	}
+	if err := b.OPTResource(opt, dnsmessage.OPTResource{}); err != nil {
+		return nil, 0, err
+	}
+	msg, err := b.Finish()
+	return msg, id, err
+}
+
+func exchange(network string, server string, msg []byte) ([]byte, error) {
+	conn, err := net.DialTimeout(network, server, Timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(Timeout))
+
+	if network == "udp" {
+		if _, err := conn.Write(msg); err != nil {
+			return nil, err
+		}
+		buf := make([]byte, 65535)
+		n, err := conn.Read(buf)
+		if err != nil {
+			return nil, err
+		}
+		return buf[:n], nil
+	}
+
+	// TCP messages carry a two byte length prefix
+	framed := make([]byte, 2+len(msg))
+	binary.BigEndian.PutUint16(framed, uint16(len(msg)))
+	copy(framed[2:], msg)
+	if _, err := conn.Write(framed); err != nil {
+		return nil, err
+	}
+	var length uint16
+	if err := binary.Read(conn, binary.BigEndian, &length); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, length)
+	if _, err := io.ReadFull(conn, buf); err != nil {
+		return nil, fmt.Errorf("short DNS response: %w", err)
+	}
+	return buf, nil
+}
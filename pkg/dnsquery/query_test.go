@@ -0,0 +1,201 @@
+package dnsquery
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// DNS server on one loopback port for UDP and TCP, answers come from handle
+type responder struct {
+	addr    string
+	handle  func(q dnsmessage.Question, tcp bool) dnsmessage.Message
+	mu      sync.Mutex
+	queries []string // "udp example.com." for every query received
+}
+
+func newResponder(t *testing.T, handle func(q dnsmessage.Question, tcp bool) dnsmessage.Message) *responder {
+	t.Helper()
+	r := &responder{handle: handle}
+
+	// The TCP retry goes to the same address, find a port free for both
+	var udp net.PacketConn
+	var tcp net.Listener
+	for i := 0; i < 10 && tcp == nil; i++ {
+		var err error
+		if udp, err = net.ListenPacket("udp", "127.0.0.1:0"); err != nil {
+			t.Fatal(err)
+		}
+		if tcp, err = net.Listen("tcp", udp.LocalAddr().String()); err != nil {
+			udp.Close()
+		}
+	}
+	if tcp == nil {
+		t.Fatal("no free port for UDP and TCP")
+	}
+	t.Cleanup(func() {
+		udp.Close()
+		tcp.Close()
+	})
+	r.addr = udp.LocalAddr().String()
+
+	go func() {
+		buf := make([]byte, 65535)
+		for {
+			n, from, err := udp.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			if resp := r.answer(buf[:n], false); resp != nil {
+				udp.WriteTo(resp, from)
+			}
+		}
+	}()
+	go func() {
+		for {
+			conn, err := tcp.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				var length uint16
+				if binary.Read(conn, binary.BigEndian, &length) != nil {
+					return
+				}
+				msg := make([]byte, length)
+				if _, err := io.ReadFull(conn, msg); err != nil {
+					return
+				}
+				if resp := r.answer(msg, true); resp != nil {
+					binary.Write(conn, binary.BigEndian, uint16(len(resp)))
+					conn.Write(resp)
+				}
+			}()
+		}
+	}()
+	return r
+}
+
+func (r *responder) answer(msg []byte, tcp bool) []byte {
+	var query dnsmessage.Message
+	if err := query.Unpack(msg); err != nil || len(query.Questions) != 1 {
+		return nil
+	}
+	q := query.Questions[0]
+	network := "udp"
+	if tcp {
+		network = "tcp"
+	}
+	r.mu.Lock()
+	r.queries = append(r.queries, network+" "+q.Name.String())
+	r.mu.Unlock()
+
+	// The handler sets an ID offset to answer another query
+	resp := r.handle(q, tcp)
+	resp.Header.ID += query.Header.ID
+	resp.Header.Response = true
+	resp.Questions = query.Questions
+	packed, err := resp.Pack()
+	if err != nil {
+		return nil
+	}
+	return packed
+}
+
+func (r *responder) log() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return strings.Join(r.queries, ",")
+}
+
+func (r *responder) reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.queries = nil
+}
+
+func resource(name string, body dnsmessage.ResourceBody) dnsmessage.Resource {
+	return dnsmessage.Resource{
+		Header: dnsmessage.ResourceHeader{Name: dnsmessage.MustNewName(name), Class: dnsmessage.ClassINET, TTL: 300},
+		Body:   body,
+	}
+}
+
+func txt(name string, value string) dnsmessage.Resource {
+	return resource(name, &dnsmessage.TXTResource{TXT: []string{value}})
+}
+
+func TestQuery(t *testing.T) {
+	server := newResponder(t, func(q dnsmessage.Question, tcp bool) dnsmessage.Message {
+		switch q.Name.String() {
+		case "www.example.com.":
+			// The resolver follows the CNAME and returns both records
+			return dnsmessage.Message{
+				Header: dnsmessage.Header{AuthenticData: true},
+				Answers: []dnsmessage.Resource{
+					resource("www.example.com.", &dnsmessage.CNAMEResource{CNAME: dnsmessage.MustNewName("web.example.com.")}),
+					txt("web.example.com.", "v=spf1 -all"),
+				},
+			}
+		case "big.example.com.":
+			if !tcp {
+				return dnsmessage.Message{Header: dnsmessage.Header{Truncated: true}}
+			}
+			return dnsmessage.Message{Answers: []dnsmessage.Resource{txt("big.example.com.", "a"), txt("big.example.com.", "b")}}
+		case "spoofed.example.com.":
+			return dnsmessage.Message{Header: dnsmessage.Header{ID: 1}, Answers: []dnsmessage.Resource{txt("spoofed.example.com.", "x")}}
+		}
+		return dnsmessage.Message{Header: dnsmessage.Header{RCode: dnsmessage.RCodeNameError}}
+	})
+
+	resp, err := Query(server.addr, "www.example.com", dnsmessage.TypeTXT)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Answers) != 1 || resp.Answers[0].Name != "web.example.com." || resp.Answers[0].TTL != 300 || !resp.AuthenticData {
+		t.Errorf("response = %+v, want the TXT record without the CNAME", resp)
+	}
+	if value, _ := ParseTXT(resp.Answers[0].Data); value != "v=spf1 -all" {
+		t.Errorf("TXT = %q", value)
+	}
+
+	resp, err = Query(server.addr, "big.example.com.", dnsmessage.TypeTXT)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Answers) != 2 {
+		t.Errorf("answers = %d, want both from the TCP retry", len(resp.Answers))
+	}
+	if got := server.log(); !strings.HasSuffix(got, "udp big.example.com.,tcp big.example.com.") {
+		t.Errorf("queries = %s, want the truncated answer retried over TCP", got)
+	}
+
+	if _, err := Query(server.addr, "spoofed.example.com", dnsmessage.TypeTXT); err == nil || !strings.Contains(err.Error(), "ID mismatch") {
+		t.Errorf("err = %v, want an ID mismatch", err)
+	}
+
+	resp, err = Query(server.addr, "missing.example.com", dnsmessage.TypeTXT)
+	if err != nil || resp.RCode != dnsmessage.RCodeNameError || len(resp.Answers) != 0 {
+		t.Errorf("missing name = %+v, %v", resp, err)
+	}
+}
+
+func TestServerAddress(t *testing.T) {
+	tests := map[string]string{
+		"192.0.2.1":          "192.0.2.1:53",
+		"192.0.2.1:5353":     "192.0.2.1:5353",
+		"2001:db8::1":        "[2001:db8::1]:53",
+		"[2001:db8::1]:5353": "[2001:db8::1]:5353",
+	}
+	for server, want := range tests {
+		if got := ServerAddress(server); got != want {
+			t.Errorf("ServerAddress(%q) = %q, want %q", server, got, want)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package dnsquery
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// TLSA record (RFC 6698)
+type TLSA struct {
+	Usage        uint8 // 0 PKIX-TA, 1 PKIX-EE, 2 DANE-TA, 3 DANE-EE
+	Selector     uint8 // 0 full certificate, 1 SubjectPublicKeyInfo
+	MatchingType uint8 // 0 exact, 1 SHA-256, 2 SHA-512
+	Data         []byte
+}
+
+// Zone file presentation like "3 1 1 0a1b..."
+func (t TLSA) String() string {
+	return fmt.Sprintf("%d %d %d %s", t.Usage, t.Selector, t.MatchingType, hex.EncodeToString(t.Data))
+}
+
+// Parse the RDATA of a TLSA record
+func ParseTLSA(data []byte) (TLSA, error) {
+	if len(data) < 4 {
+		return TLSA{}, errors.New("malformed TLSA record")
+	}
+	return TLSA{Usage: data[0], Selector: data[1], MatchingType: data[2], Data: data[3:]}, nil
+}
+
+// TLSA records of _port._tcp.host and whether the resolver validated them with DNSSEC
+func LookupTLSA(server string, host string, port string) ([]TLSA, bool, error) {
+	name := "_" + port + "._tcp." + strings.TrimSuffix(host, ".")
+	resp, err := Query(server, name, TypeTLSA)
+	if err != nil {
+		return nil, false, err
+	}
+	if resp.RCode != dnsmessage.RCodeSuccess && resp.RCode != dnsmessage.RCodeNameError {
+		return nil, false, fmt.Errorf("TLSA lookup of %s failed with %s", name, resp.RCode)
+	}
+
+	var records []TLSA
+	for _, answer := range resp.Answers {
+		record, err := ParseTLSA(answer.Data)
+		if err != nil {
+			return nil, resp.AuthenticData, err
+		}
+		records = append(records, record)
+	}
+	return records, resp.AuthenticData, nil
+}
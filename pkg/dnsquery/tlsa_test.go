@@ -0,0 +1,56 @@
+package dnsquery
+
+import (
+	"bytes"
+	"testing"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+func TestParseTLSA(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    []byte
+		want    string
+		wantErr bool
+	}{
+		{"DANE-EE SPKI SHA-256", []byte{3, 1, 1, 0x0a, 0x1b}, "3 1 1 0a1b", false},
+		{"PKIX-TA full certificate", []byte{0, 0, 0, 0x30, 0x82}, "0 0 0 3082", false},
+		{"no association data", []byte{3, 1, 1}, "", true},
+		{"empty", nil, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			record, err := ParseTLSA(tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && record.String() != tt.want {
+				t.Errorf("record = %s, want %s", record, tt.want)
+			}
+		})
+	}
+}
+
+func TestLookupTLSA(t *testing.T) {
+	server := newResponder(t, func(q dnsmessage.Question, tcp bool) dnsmessage.Message {
+		if q.Name.String() != "_25._tcp.mail.example.com." || q.Type != TypeTLSA {
+			return dnsmessage.Message{Header: dnsmessage.Header{RCode: dnsmessage.RCodeNameError}}
+		}
+		return dnsmessage.Message{
+			Header:  dnsmessage.Header{AuthenticData: true},
+			Answers: []dnsmessage.Resource{resource(q.Name.String(), &dnsmessage.UnknownResource{Type: TypeTLSA, Data: []byte{3, 1, 1, 0xaa}})},
+		}
+	})
+
+	records, secure, err := LookupTLSA(server.addr, "mail.example.com.", "25")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 || !secure || records[0].Usage != 3 || !bytes.Equal(records[0].Data, []byte{0xaa}) {
+		t.Errorf("records = %v, secure %v", records, secure)
+	}
+	if records, _, err := LookupTLSA(server.addr, "www.example.com", "443"); err != nil || len(records) != 0 {
+		t.Errorf("missing name = %v, %v", records, err)
+	}
+}
@@ -0,0 +1,44 @@
+package dnsquery
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// Parse the RDATA of a TXT record, the character strings are joined (RFC 7208, 3.3)
+func ParseTXT(data []byte) (string, error) {
+	var b strings.Builder
+	for len(data) > 0 {
+		length := int(data[0])
+		if len(data) < 1+length {
+			return "", errors.New("malformed TXT record")
+		}
+		b.Write(data[1 : 1+length])
+		data = data[1+length:]
+	}
+	return b.String(), nil
+}
+
+// TXT records of the name, a missing name is not an error
+func LookupTXT(server string, name string) ([]string, error) {
+	resp, err := Query(server, name, dnsmessage.TypeTXT)
+	if err != nil {
+		return nil, err
+	}
+	if resp.RCode != dnsmessage.RCodeSuccess && resp.RCode != dnsmessage.RCodeNameError {
+		return nil, fmt.Errorf("TXT lookup of %s failed with %s", name, resp.RCode)
+	}
+
+	var records []string
+	for _, answer := range resp.Answers {
+		record, err := ParseTXT(answer.Data)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
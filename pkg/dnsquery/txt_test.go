@@ -0,0 +1,29 @@
+package dnsquery
+
+import "testing"
+
+func TestParseTXT(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    []byte
+		want    string
+		wantErr bool
+	}{
+		{"one string", []byte("\x0bv=spf1 -all"), "v=spf1 -all", false},
+		{"strings joined without separator", []byte("\x07v=DKIM1\x04; k=\x03rsa"), "v=DKIM1; k=rsa", false},
+		{"empty string", []byte{0}, "", false},
+		{"no strings", nil, "", false},
+		{"string past the end", []byte("\x0cv=spf1 -all"), "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseTXT(tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseTXT = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,281 @@
+package egress
+
+import (
+	"bufio"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"sentinel/models"
+
+	"golang.org/x/net/proxy"
+)
+
+// Dial opens a TCP connection to address through the configured egress
+type DialFunc func(address string) (net.Conn, error)
+
+// Build the dial function of the egress settings
+func Dialer(e models.Egress, timeout time.Duration) (DialFunc, error) {
+	base, err := newSourceDialer(e, timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case e.HTTPProxy != "" && e.SOCKS5Proxy != "":
+		return nil, errors.New("egress: http_proxy and socks5_proxy are mutually exclusive")
+
+	case e.SOCKS5Proxy != "":
+		u, err := parseProxyURL(e.SOCKS5Proxy, "socks5")
+		if err != nil {
+			return nil, err
+		}
+		var auth *proxy.Auth
+		if u.User != nil {
+			password, _ := u.User.Password()
+			auth = &proxy.Auth{User: u.User.Username(), Password: password}
+		}
+		socks, err := proxy.SOCKS5("tcp", u.Host, auth, base)
+		if err != nil {
+			return nil, err
+		}
+		return func(address string) (net.Conn, error) {
+			return withDeadline(timeout, func() (net.Conn, error) { return socks.Dial("tcp", address) })
+		}, nil
+
+	case e.HTTPProxy != "":
+		u, err := parseProxyURL(e.HTTPProxy, "http")
+		if err != nil {
+			return nil, err
+		}
+		return func(address string) (net.Conn, error) {
+			return withDeadline(timeout, func() (net.Conn, error) { return httpConnect(base, u, address, timeout) })
+		}, nil
+
+	default:
+		return func(address string) (net.Conn, error) {
+			return base.Dial("tcp", address)
+		}, nil
+	}
+}
+
+// Whether ip can be reached through the egress, a proxy reaches every address family
+func Routable(e models.Egress, ip net.IP) bool {
+	if e.HTTPProxy != "" || e.SOCKS5Proxy != "" {
+		return true
+	}
+	d, err := newSourceDialer(e, 0)
+	if err != nil {
+		return false
+	}
+	local, ok := d.source(ip)
+	if !ok {
+		return false
+	}
+
+	// Connecting a UDP socket only looks up the route, no packet is sent
+	udp := net.Dialer{}
+	if local != nil {
+		udp.LocalAddr = &net.UDPAddr{IP: local}
+	}
+	conn, err := udp.Dial("udp", net.JoinHostPort(ip.String(), "9"))
+	if err != nil {
+		return false
+	}
+	conn.Close()
+	return true
+}
+
+// Open a tunnel with HTTP CONNECT
+func httpConnect(base *sourceDialer, u *url.URL, address string, timeout time.Duration) (net.Conn, error) {
+	conn, err := base.Dial("tcp", u.Host)
+	if err != nil {
+		return nil, err
+	}
+	conn.SetDeadline(time.Now().Add(timeout))
+
+	req := "CONNECT " + address + " HTTP/1.1\r\nHost: " + address + "\r\n"
+	if u.User != nil {
+		password, _ := u.User.Password()
+		credentials := base64.StdEncoding.EncodeToString([]byte(u.User.Username() + ":" + password))
+		req += "Proxy-Authorization: Basic " + credentials + "\r\n"
+	}
+	req += "\r\n"
+	if _, err := conn.Write([]byte(req)); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, &http.Request{Method: http.MethodConnect})
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		conn.Close()
+		return nil, fmt.Errorf("egress: proxy %s refused CONNECT %s: %s", u.Host, address, resp.Status)
+	}
+
+	conn.SetDeadline(time.Time{})
+	// Bytes the proxy sent after the response belong to the tunnel
+	if reader.Buffered() > 0 {
+		return &bufferedConn{Conn: conn, reader: reader}, nil
+	}
+	return conn, nil
+}
+
+// Direct dialer binding the source address of the destination family
+type sourceDialer struct {
+	timeout time.Duration
+	bound   bool   // source_ip or interface is set
+	ipv4    net.IP // source for IPv4 destinations, nil when the family has none
+	ipv6    net.IP
+}
+
+// Source addresses of the source IP or of the interface, one per family
+func newSourceDialer(e models.Egress, timeout time.Duration) (*sourceDialer, error) {
+	d := &sourceDialer{timeout: timeout}
+	if e.SourceIP != "" {
+		ip := net.ParseIP(e.SourceIP)
+		if ip == nil {
+			return nil, fmt.Errorf("egress: invalid source_ip %q", e.SourceIP)
+		}
+		d.bound = true
+		d.add(ip)
+		return d, nil
+	}
+	if e.Interface == "" {
+		return d, nil
+	}
+
+	iface, err := net.InterfaceByName(e.Interface)
+	if err != nil {
+		return nil, err
+	}
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return nil, err
+	}
+	d.bound = true
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok && !ipNet.IP.IsLinkLocalUnicast() {
+			d.add(ipNet.IP)
+		}
+	}
+	if d.ipv4 == nil && d.ipv6 == nil {
+		return nil, fmt.Errorf("egress: interface %s has no usable address", e.Interface)
+	}
+	return d, nil
+}
+
+// Keep the first address of each family
+func (d *sourceDialer) add(ip net.IP) {
+	if ip.To4() != nil {
+		if d.ipv4 == nil {
+			d.ipv4 = ip
+		}
+	} else if d.ipv6 == nil {
+		d.ipv6 = ip
+	}
+}
+
+// Source address for the destination, false when the binding has no address of its family
+func (d *sourceDialer) source(dest net.IP) (net.IP, bool) {
+	if !d.bound {
+		return nil, true
+	}
+	local := d.ipv6
+	if dest.To4() != nil {
+		local = d.ipv4
+	}
+	return local, local != nil
+}
+
+// Dial address from the source of its family, host names are tried address by address
+func (d *sourceDialer) Dial(network, address string) (net.Conn, error) {
+	dialer := net.Dialer{Timeout: d.timeout}
+	if !d.bound {
+		return dialer.Dial(network, address)
+	}
+
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+	ips := []net.IP{net.ParseIP(host)}
+	if ips[0] == nil {
+		if ips, err = net.LookupIP(host); err != nil {
+			return nil, err
+		}
+	}
+
+	err = fmt.Errorf("egress: no source address of the address family of %s", address)
+	for _, ip := range ips {
+		local, ok := d.source(ip)
+		if !ok {
+			continue
+		}
+		dialer.LocalAddr = &net.TCPAddr{IP: local}
+		conn, dialErr := dialer.Dial(network, net.JoinHostPort(ip.String(), port))
+		if dialErr == nil {
+			return conn, nil
+		}
+		err = dialErr
+	}
+	return nil, err
+}
+
+func parseProxyURL(raw string, scheme string) (*url.URL, error) {
+	if !strings.Contains(raw, "://") {
+		raw = scheme + "://" + raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, err
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("egress: proxy address missing in %q", raw)
+	}
+	return u, nil
+}
+
+// Bound the whole proxy handshake, proxy.SOCKS5 only honors the dial timeout
+func withDeadline(timeout time.Duration, dial func() (net.Conn, error)) (net.Conn, error) {
+	type result struct {
+		conn net.Conn
+		err  error
+	}
+	done := make(chan result, 1)
+	go func() {
+		conn, err := dial()
+		done <- result{conn, err}
+	}()
+
+	select {
+	case r := <-done:
+		return r.conn, r.err
+	case <-time.After(timeout):
+		go func() {
+			if r := <-done; r.conn != nil {
+				r.conn.Close()
+			}
+		}()
+		return nil, errors.New("egress: proxy handshake timed out")
+	}
+}
+
+type bufferedConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+func (c *bufferedConn) Read(b []byte) (int, error) {
+	return c.reader.Read(b)
+}
@@ -0,0 +1,469 @@
+package egress
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"sentinel/models"
+)
+
+const testTimeout = 5 * time.Second
+
+// Echo server recording the remote address of every connection
+type echoServer struct {
+	addr    string
+	remotes chan net.Addr
+}
+
+func newEchoServer(t *testing.T, network, address string) *echoServer {
+	t.Helper()
+	ln, err := net.Listen(network, address)
+	if err != nil {
+		t.Skipf("cannot listen on %s: %v", address, err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	s := &echoServer{addr: ln.Addr().String(), remotes: make(chan net.Addr, 16)}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			s.remotes <- conn.RemoteAddr()
+			go func() {
+				defer conn.Close()
+				io.Copy(conn, conn)
+			}()
+		}
+	}()
+	return s
+}
+
+// Write a line and expect it back
+func roundTrip(t *testing.T, conn net.Conn) {
+	t.Helper()
+	conn.SetDeadline(time.Now().Add(testTimeout))
+	if _, err := conn.Write([]byte("ping\n")); err != nil {
+		t.Fatal(err)
+	}
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+	if line != "ping\n" {
+		t.Fatalf("echo = %q, want %q", line, "ping\n")
+	}
+}
+
+func pipe(a, b net.Conn) {
+	go func() {
+		io.Copy(a, b)
+		a.Close()
+	}()
+	io.Copy(b, a)
+	b.Close()
+}
+
+// In-process HTTP CONNECT proxy, credentials are required when user is set
+func newHTTPProxy(t *testing.T, user, password string) (string, chan string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	targets := make(chan string, 16)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				reader := bufio.NewReader(conn)
+				req, err := http.ReadRequest(reader)
+				if err != nil || req.Method != http.MethodConnect {
+					conn.Close()
+					return
+				}
+				targets <- req.Host
+
+				want := "Basic " + base64.StdEncoding.EncodeToString([]byte(user+":"+password))
+				if user != "" && req.Header.Get("Proxy-Authorization") != want {
+					io.WriteString(conn, "HTTP/1.1 407 Proxy Authentication Required\r\n\r\n")
+					conn.Close()
+					return
+				}
+				upstream, err := net.Dial("tcp", req.Host)
+				if err != nil {
+					io.WriteString(conn, "HTTP/1.1 502 Bad Gateway\r\n\r\n")
+					conn.Close()
+					return
+				}
+				io.WriteString(conn, "HTTP/1.1 200 Connection established\r\n\r\n")
+				pipe(conn, upstream)
+			}()
+		}
+	}()
+	return ln.Addr().String(), targets
+}
+
+// In-process SOCKS5 proxy with username/password authentication (RFC 1928, RFC 1929)
+func newSOCKS5Proxy(t *testing.T, user, password string) (string, chan string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	targets := make(chan string, 16)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				if target, ok := socks5Handshake(conn, user, password); ok {
+					targets <- target
+					upstream, err := net.Dial("tcp", target)
+					if err != nil {
+						conn.Write([]byte{5, 5, 0, 1, 0, 0, 0, 0, 0, 0})
+						conn.Close()
+						return
+					}
+					conn.Write([]byte{5, 0, 0, 1, 0, 0, 0, 0, 0, 0})
+					pipe(conn, upstream)
+					return
+				}
+				conn.Close()
+			}()
+		}
+	}()
+	return ln.Addr().String(), targets
+}
+
+func socks5Handshake(conn net.Conn, user, password string) (string, bool) {
+	r := bufio.NewReader(conn)
+	readN := func(n int) []byte {
+		b := make([]byte, n)
+		if _, err := io.ReadFull(r, b); err != nil {
+			return nil
+		}
+		return b
+	}
+
+	// Greeting, only username/password is accepted
+	head := readN(2)
+	if head == nil || head[0] != 5 {
+		return "", false
+	}
+	methods := readN(int(head[1]))
+	if !strings.Contains(string(methods), "\x02") {
+		conn.Write([]byte{5, 0xff})
+		return "", false
+	}
+	conn.Write([]byte{5, 2})
+
+	// Username/password subnegotiation
+	ver := readN(1)
+	ulen := readN(1)
+	if ver == nil || ulen == nil {
+		return "", false
+	}
+	gotUser := readN(int(ulen[0]))
+	plen := readN(1)
+	if plen == nil {
+		return "", false
+	}
+	gotPassword := readN(int(plen[0]))
+	if string(gotUser) != user || string(gotPassword) != password {
+		conn.Write([]byte{1, 1})
+		return "", false
+	}
+	conn.Write([]byte{1, 0})
+
+	// CONNECT request
+	req := readN(4)
+	if req == nil || req[1] != 1 {
+		return "", false
+	}
+	var host string
+	switch req[3] {
+	case 1:
+		host = net.IP(readN(4)).String()
+	case 4:
+		host = net.IP(readN(16)).String()
+	case 3:
+		n := readN(1)
+		host = string(readN(int(n[0])))
+	default:
+		return "", false
+	}
+	port := readN(2)
+	if port == nil {
+		return "", false
+	}
+	return net.JoinHostPort(host, strconv.Itoa(int(binary.BigEndian.Uint16(port)))), true
+}
+
+func TestDialerDirect(t *testing.T) {
+	echo := newEchoServer(t, "tcp", "127.0.0.1:0")
+	dial, err := Dialer(models.Egress{}, testTimeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, err := dial(echo.addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	roundTrip(t, conn)
+}
+
+func TestDialerHTTPProxy(t *testing.T) {
+	echo := newEchoServer(t, "tcp", "127.0.0.1:0")
+
+	tests := []struct {
+		name    string
+		proxy   func(addr string) string
+		wantErr bool
+	}{
+		{"credentials", func(addr string) string { return "http://probe:s3cret@" + addr }, false},
+		{"scheme is optional", func(addr string) string { return "probe:s3cret@" + addr }, false},
+		{"wrong password", func(addr string) string { return "http://probe:wrong@" + addr }, true},
+		{"no credentials", func(addr string) string { return addr }, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			addr, targets := newHTTPProxy(t, "probe", "s3cret")
+			dial, err := Dialer(models.Egress{HTTPProxy: tt.proxy(addr)}, testTimeout)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			conn, err := dial(echo.addr)
+			if tt.wantErr {
+				if err == nil {
+					conn.Close()
+					t.Fatal("dial through the proxy succeeded without valid credentials")
+				}
+				if !strings.Contains(err.Error(), "407") {
+					t.Errorf("error = %v, want the proxy status", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer conn.Close()
+			roundTrip(t, conn)
+			if got := <-targets; got != echo.addr {
+				t.Errorf("proxy tunneled to %s, want %s", got, echo.addr)
+			}
+		})
+	}
+}
+
+func TestDialerSOCKS5Proxy(t *testing.T) {
+	echo := newEchoServer(t, "tcp", "127.0.0.1:0")
+	addr, targets := newSOCKS5Proxy(t, "probe", "s3cret")
+
+	dial, err := Dialer(models.Egress{SOCKS5Proxy: "socks5://probe:s3cret@" + addr}, testTimeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, err := dial(echo.addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	roundTrip(t, conn)
+	if got := <-targets; got != echo.addr {
+		t.Errorf("proxy connected to %s, want %s", got, echo.addr)
+	}
+
+	dial, err = Dialer(models.Egress{SOCKS5Proxy: "socks5://probe:wrong@" + addr}, testTimeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conn, err := dial(echo.addr); err == nil {
+		conn.Close()
+		t.Error("dial through the proxy succeeded with a wrong password")
+	}
+}
+
+func TestDialerProxyTimeout(t *testing.T) {
+	// A proxy that accepts but never answers must not hang the probe
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	held := make(chan net.Conn, 16)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			held <- conn
+		}
+	}()
+
+	for _, e := range []models.Egress{{HTTPProxy: ln.Addr().String()}, {SOCKS5Proxy: ln.Addr().String()}} {
+		dial, err := Dialer(e, 200*time.Millisecond)
+		if err != nil {
+			t.Fatal(err)
+		}
+		start := time.Now()
+		if conn, err := dial("192.0.2.1:443"); err == nil {
+			conn.Close()
+			t.Errorf("%+v: dial succeeded through a silent proxy", e)
+		}
+		if elapsed := time.Since(start); elapsed > 2*time.Second {
+			t.Errorf("%+v: dial took %s", e, elapsed)
+		}
+	}
+}
+
+func TestDialerSourceIP(t *testing.T) {
+	echo := newEchoServer(t, "tcp", "127.0.0.1:0")
+
+	// Any address of 127/8 is local on the loopback interface
+	dial, err := Dialer(models.Egress{SourceIP: "127.0.0.2"}, testTimeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, err := dial(echo.addr)
+	if err != nil {
+		t.Skipf("cannot bind 127.0.0.2: %v", err)
+	}
+	defer conn.Close()
+	roundTrip(t, conn)
+	if remote := (<-echo.remotes).(*net.TCPAddr); !remote.IP.Equal(net.ParseIP("127.0.0.2")) {
+		t.Errorf("server saw %s, want 127.0.0.2", remote.IP)
+	}
+
+	// An IPv4 source cannot reach an IPv6 destination
+	if conn, err := dial("[::1]:443"); err == nil {
+		conn.Close()
+		t.Error("IPv4 source dialed an IPv6 destination")
+	} else if !strings.Contains(err.Error(), "address family") {
+		t.Errorf("error = %v, want an address family error", err)
+	}
+}
+
+func TestDialerInterface(t *testing.T) {
+	loopback := loopbackInterface(t)
+	dial, err := Dialer(models.Egress{Interface: loopback}, testTimeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Both families are dialed from the interface address of the destination family
+	for _, address := range []string{"127.0.0.1:0", "[::1]:0"} {
+		echo := newEchoServer(t, "tcp", address)
+		conn, err := dial(echo.addr)
+		if err != nil {
+			t.Fatalf("dial %s: %v", echo.addr, err)
+		}
+		roundTrip(t, conn)
+		conn.Close()
+
+		if remote := (<-echo.remotes).(*net.TCPAddr); !remote.IP.IsLoopback() {
+			t.Errorf("dial %s came from %s", echo.addr, remote.IP)
+		}
+	}
+}
+
+func TestSourceSelection(t *testing.T) {
+	v4 := net.ParseIP("192.0.2.10")
+	v6 := net.ParseIP("2001:db8::10")
+
+	tests := []struct {
+		name   string
+		dialer sourceDialer
+		dest   string
+		source net.IP
+		ok     bool
+	}{
+		{"unbound", sourceDialer{}, "198.51.100.1", nil, true},
+		{"IPv4 destination", sourceDialer{bound: true, ipv4: v4, ipv6: v6}, "198.51.100.1", v4, true},
+		{"IPv6 destination", sourceDialer{bound: true, ipv4: v4, ipv6: v6}, "2001:db8::1", v6, true},
+		{"IPv4 mapped destination", sourceDialer{bound: true, ipv4: v4, ipv6: v6}, "::ffff:198.51.100.1", v4, true},
+		{"IPv6 only binding", sourceDialer{bound: true, ipv6: v6}, "198.51.100.1", nil, false},
+		{"IPv4 only binding", sourceDialer{bound: true, ipv4: v4}, "2001:db8::1", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source, ok := tt.dialer.source(net.ParseIP(tt.dest))
+			if ok != tt.ok || !source.Equal(tt.source) {
+				t.Errorf("source = %v, %v, want %v, %v", source, ok, tt.source, tt.ok)
+			}
+		})
+	}
+
+	// The first address of each family wins, link-local ones never get there
+	d := &sourceDialer{}
+	for _, ip := range []string{"2001:db8::10", "192.0.2.10", "2001:db8::11", "192.0.2.11"} {
+		d.add(net.ParseIP(ip))
+	}
+	if !d.ipv4.Equal(v4) || !d.ipv6.Equal(v6) {
+		t.Errorf("sources = %v, %v, want %v, %v", d.ipv4, d.ipv6, v4, v6)
+	}
+}
+
+func TestRoutable(t *testing.T) {
+	v4 := net.ParseIP("127.0.0.1")
+	v6 := net.ParseIP("::1")
+
+	if !Routable(models.Egress{}, v4) {
+		t.Error("loopback is not routable")
+	}
+	if Routable(models.Egress{SourceIP: "127.0.0.1"}, v6) {
+		t.Error("IPv6 destination is routable from an IPv4 source")
+	}
+	if !Routable(models.Egress{SOCKS5Proxy: "127.0.0.1:1080", SourceIP: "127.0.0.1"}, v6) {
+		t.Error("proxy does not reach IPv6 destinations")
+	}
+}
+
+func TestDialerInvalid(t *testing.T) {
+	for _, e := range []models.Egress{
+		{HTTPProxy: "127.0.0.1:3128", SOCKS5Proxy: "127.0.0.1:1080"},
+		{SourceIP: "not-an-ip"},
+		{HTTPProxy: "http://"},
+		{Interface: "sentinel-does-not-exist0"},
+	} {
+		if _, err := Dialer(e, testTimeout); err == nil {
+			t.Errorf("%+v: no error", e)
+		}
+	}
+}
+
+func loopbackInterface(t *testing.T) string {
+	t.Helper()
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagLoopback != 0 && iface.Flags&net.FlagUp != 0 {
+			return iface.Name
+		}
+	}
+	t.Skip("no loopback interface")
+	return ""
+}
@@ -0,0 +1,334 @@
+package image
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Blobs bigger than this are layers, never manifests
+const maxManifestSize = 4 << 20
+
+// File found in the filesystem of an image
+type File struct {
+	Image string // repository tags of the image, empty for a plain tar archive
+	Path  string
+	Layer string // digest of the layer that last wrote the file
+	Data  []byte
+}
+
+// Decides whether a file of the given size is worth reading, and after reading whether to keep it
+type Filter struct {
+	MaxSize int64
+	Keep    func(path string, data []byte) bool
+}
+
+// Image of a docker save or OCI layout tarball
+type imageManifest struct {
+	Tags   []string
+	Layers []string // archive entry of every layer, bottom first
+}
+
+// Files a layer adds and removes
+type layer struct {
+	digest    string
+	files     map[string][]byte
+	whiteouts []string // removed paths
+	opaque    []string // directories whose lower layer content is hidden
+}
+
+// Read the final filesystem of every image in a docker save or OCI tarball.
+// An archive without image manifests is read as a plain filesystem tar.
+func Files(archive string, filter Filter) ([]File, error) {
+	images, err := readManifests(archive)
+	if err != nil {
+		return nil, err
+	}
+	if len(images) == 0 {
+		images = []imageManifest{{Layers: []string{""}}}
+	}
+
+	wanted := map[string]bool{}
+	for _, image := range images {
+		for _, name := range image.Layers {
+			wanted[name] = true
+		}
+	}
+	layers, err := readLayers(archive, wanted, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	// Apply the layers bottom up like the container runtime does
+	var files []File
+	for _, image := range images {
+		fs := map[string]File{}
+		for _, name := range image.Layers {
+			l, ok := layers[name]
+			if !ok {
+				return files, fmt.Errorf("layer %s is missing from %s", name, archive)
+			}
+			for _, dir := range l.opaque {
+				removeTree(fs, dir, false)
+			}
+			for _, removed := range l.whiteouts {
+				removeTree(fs, removed, true)
+			}
+			for p, data := range l.files {
+				fs[p] = File{Image: strings.Join(image.Tags, ", "), Path: p, Layer: l.digest, Data: data}
+			}
+		}
+
+		var paths []string
+		for p := range fs {
+			paths = append(paths, p)
+		}
+		sort.Strings(paths)
+		for _, p := range paths {
+			files = append(files, fs[p])
+		}
+	}
+	return files, nil
+}
+
+func removeTree(fs map[string]File, p string, self bool) {
+	for existing := range fs {
+		if (self && existing == p) || strings.HasPrefix(existing, p+"/") {
+			delete(fs, existing)
+		}
+	}
+}
+
+// First pass: docker save manifest.json or OCI index.json with the manifests it points to
+func readManifests(archive string) ([]imageManifest, error) {
+	small := map[string][]byte{}
+	links := map[string]string{}
+	err := walkArchive(archive, func(header *tar.Header, r io.Reader) error {
+		// Legacy docker save links layers shared between images like <id>/layer.tar -> ../<other>/layer.tar
+		switch header.Typeflag {
+		case tar.TypeSymlink:
+			links[cleanPath(header.Name)] = cleanPath(path.Join(path.Dir(cleanPath(header.Name)), header.Linkname))
+		case tar.TypeLink:
+			links[cleanPath(header.Name)] = cleanPath(header.Linkname)
+		}
+		if header.Typeflag == tar.TypeReg && header.Size <= maxManifestSize && (strings.HasSuffix(header.Name, ".json") || strings.HasPrefix(cleanPath(header.Name), "blobs/")) {
+			data, err := io.ReadAll(r)
+			if err != nil {
+				return err
+			}
+			small[cleanPath(header.Name)] = data
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// docker save, newer versions write the OCI layout next to it
+	if data, ok := small["manifest.json"]; ok {
+		var manifests []struct {
+			RepoTags []string
+			Layers   []string
+		}
+		if err := json.Unmarshal(data, &manifests); err != nil {
+			return nil, fmt.Errorf("manifest.json: %w", err)
+		}
+		var images []imageManifest
+		for _, m := range manifests {
+			image := imageManifest{Tags: m.RepoTags}
+			for _, l := range m.Layers {
+				image.Layers = append(image.Layers, resolveLink(links, cleanPath(l)))
+			}
+			images = append(images, image)
+		}
+		return images, nil
+	}
+
+	if data, ok := small["index.json"]; ok {
+		return ociImages(data, small, nil)
+	}
+	return nil, nil
+}
+
+type ociDescriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Annotations map[string]string `json:"annotations"`
+}
+
+// Walk an OCI index down to the image manifests
+func ociImages(index []byte, blobs map[string][]byte, tags []string) ([]imageManifest, error) {
+	var doc struct {
+		Manifests []ociDescriptor `json:"manifests"`
+		Layers    []ociDescriptor `json:"layers"`
+	}
+	if err := json.Unmarshal(index, &doc); err != nil {
+		return nil, err
+	}
+
+	if len(doc.Layers) > 0 {
+		image := imageManifest{Tags: tags}
+		for _, l := range doc.Layers {
+			image.Layers = append(image.Layers, blobPath(l.Digest))
+		}
+		return []imageManifest{image}, nil
+	}
+
+	var images []imageManifest
+	for _, m := range doc.Manifests {
+		data, ok := blobs[blobPath(m.Digest)]
+		if !ok {
+			continue
+		}
+		nested := tags
+		if name := m.Annotations["org.opencontainers.image.ref.name"]; name != "" {
+			nested = []string{name}
+		}
+		found, err := ociImages(data, blobs, nested)
+		if err != nil {
+			return nil, err
+		}
+		images = append(images, found...)
+	}
+	return images, nil
+}
+
+// Archive entry a link points to, links to links are followed a few levels deep
+func resolveLink(links map[string]string, name string) string {
+	for i := 0; i < 8; i++ {
+		target, ok := links[name]
+		if !ok {
+			break
+		}
+		name = target
+	}
+	return name
+}
+
+func blobPath(digest string) string {
+	return "blobs/" + strings.Replace(digest, ":", "/", 1)
+}
+
+// Second pass: read the files of every wanted layer, "" is the archive itself
+func readLayers(archive string, wanted map[string]bool, filter Filter) (map[string]*layer, error) {
+	layers := map[string]*layer{}
+	if wanted[""] {
+		l := &layer{files: map[string][]byte{}}
+		err := walkArchive(archive, func(header *tar.Header, r io.Reader) error {
+			return l.add(header, r, filter)
+		})
+		layers[""] = l
+		return layers, err
+	}
+
+	err := walkArchive(archive, func(header *tar.Header, r io.Reader) error {
+		name := cleanPath(header.Name)
+		if !wanted[name] || header.Typeflag != tar.TypeReg {
+			return nil
+		}
+
+		// Legacy docker save layers are named by ID, their digest is computed while reading
+		hash := sha256.New()
+		l := &layer{files: map[string][]byte{}}
+		err := walkTar(io.TeeReader(r, hash), func(header *tar.Header, r io.Reader) error {
+			return l.add(header, r, filter)
+		})
+		if err != nil {
+			return fmt.Errorf("layer %s: %w", name, err)
+		}
+		io.Copy(hash, r)
+
+		l.digest = "sha256:" + hex.EncodeToString(hash.Sum(nil))
+		if strings.HasPrefix(name, "blobs/sha256/") {
+			l.digest = "sha256:" + path.Base(name)
+		}
+		layers[name] = l
+		return nil
+	})
+	return layers, err
+}
+
+func (l *layer) add(header *tar.Header, r io.Reader, filter Filter) error {
+	p := "/" + cleanPath(header.Name)
+	dir, base := path.Split(p)
+	dir = strings.TrimSuffix(dir, "/")
+
+	switch {
+	case base == ".wh..wh..opq":
+		l.opaque = append(l.opaque, dir)
+		return nil
+	case strings.HasPrefix(base, ".wh."):
+		l.whiteouts = append(l.whiteouts, path.Join(dir, strings.TrimPrefix(base, ".wh.")))
+		return nil
+	case header.Typeflag != tar.TypeReg || header.Size > filter.MaxSize:
+		return nil
+	}
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	if filter.Keep == nil || filter.Keep(p, data) {
+		l.files[p] = data
+	}
+	return nil
+}
+
+// Call fn for every entry of a tar archive on disk, gzip compression is detected
+func walkArchive(archive string, fn func(*tar.Header, io.Reader) error) error {
+	f, err := os.Open(archive)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return walkTar(f, fn)
+}
+
+func walkTar(r io.Reader, fn func(*tar.Header, io.Reader) error) error {
+	br := bufio.NewReader(r)
+	if magic, err := br.Peek(4); err == nil {
+		switch {
+		case bytes.HasPrefix(magic, []byte{0x1f, 0x8b}):
+			gz, err := gzip.NewReader(br)
+			if err != nil {
+				return err
+			}
+			defer gz.Close()
+			return walkEntries(gz, fn)
+		case bytes.Equal(magic, []byte{0x28, 0xb5, 0x2f, 0xfd}):
+			return errors.New("zstd compressed layers are not supported")
+		}
+	}
+	return walkEntries(br, fn)
+}
+
+func walkEntries(r io.Reader, fn func(*tar.Header, io.Reader) error) error {
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := fn(header, tr); err != nil {
+			return err
+		}
+	}
+}
+
+func cleanPath(name string) string {
+	return strings.TrimPrefix(path.Clean("/"+name), "/")
+}
@@ -0,0 +1,191 @@
+package image
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type entry struct {
+	name     string
+	data     []byte
+	typeflag byte
+	linkname string
+}
+
+func file(name string, data string) entry {
+	return entry{name: name, data: []byte(data), typeflag: tar.TypeReg}
+}
+
+func symlink(name, target string) entry {
+	return entry{name: name, typeflag: tar.TypeSymlink, linkname: target}
+}
+
+func tarball(t *testing.T, entries ...entry) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, e := range entries {
+		header := &tar.Header{Name: e.name, Typeflag: e.typeflag, Linkname: e.linkname, Size: int64(len(e.data)), Mode: 0644}
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		tw.Write(e.data)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func gzipped(data []byte) []byte {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	gz.Write(data)
+	gz.Close()
+	return buf.Bytes()
+}
+
+func marshal(t *testing.T, v interface{}) []byte {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func jsonFile(t *testing.T, name string, v interface{}) entry {
+	return entry{name: name, data: marshal(t, v), typeflag: tar.TypeReg}
+}
+
+// OCI blob entry and its digest
+func blob(data []byte) (entry, string) {
+	sum := sha256.Sum256(data)
+	digest := hex.EncodeToString(sum[:])
+	return entry{name: "blobs/sha256/" + digest, data: data, typeflag: tar.TypeReg}, "sha256:" + digest
+}
+
+func writeArchive(t *testing.T, data []byte) string {
+	t.Helper()
+	archive := filepath.Join(t.TempDir(), "image.tar")
+	if err := os.WriteFile(archive, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return archive
+}
+
+func paths(files []File) string {
+	var list []string
+	for _, f := range files {
+		list = append(list, f.Image+":"+f.Path)
+	}
+	return strings.Join(list, ",")
+}
+
+var filter = Filter{MaxSize: 1 << 20}
+
+func TestDockerSave(t *testing.T) {
+	base := tarball(t,
+		file("etc/ssl/a.pem", "a"),
+		file("etc/ssl/b.pem", "b"),
+		file("opt/app/c.pem", "c"),
+		file("opt/keep.pem", "keep"),
+	)
+	top := tarball(t,
+		file("etc/ssl/.wh.a.pem", ""),
+		file("opt/app/.wh..wh..opq", ""),
+		file("opt/app/d.pem", "d"),
+		file("etc/ssl/b.pem", "b2"),
+	)
+	archive := writeArchive(t, tarball(t,
+		jsonFile(t, "manifest.json", []map[string]interface{}{
+			{"RepoTags": []string{"app:1"}, "Layers": []string{"base/layer.tar", "top/layer.tar"}},
+		}),
+		entry{name: "base/layer.tar", data: base, typeflag: tar.TypeReg},
+		entry{name: "top/layer.tar", data: top, typeflag: tar.TypeReg},
+	))
+
+	files, err := Files(archive, filter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Whiteouts remove a file, an opaque directory hides everything below it
+	if got, want := paths(files), "app:1:/etc/ssl/b.pem,app:1:/opt/app/d.pem,app:1:/opt/keep.pem"; got != want {
+		t.Fatalf("files = %s, want %s", got, want)
+	}
+	topSum := sha256.Sum256(top)
+	if string(files[0].Data) != "b2" || files[0].Layer != "sha256:"+hex.EncodeToString(topSum[:]) {
+		t.Errorf("b.pem = %q from %s, want the top layer", files[0].Data, files[0].Layer)
+	}
+}
+
+func TestDockerSaveSymlinkedLayer(t *testing.T) {
+	shared := tarball(t, file("etc/ssl/ca.pem", "ca"))
+	archive := writeArchive(t, tarball(t,
+		jsonFile(t, "manifest.json", []map[string]interface{}{
+			{"RepoTags": []string{"app:1"}, "Layers": []string{"1111/layer.tar"}},
+			{"RepoTags": []string{"app:2"}, "Layers": []string{"2222/layer.tar"}},
+		}),
+		entry{name: "1111/layer.tar", data: shared, typeflag: tar.TypeReg},
+		symlink("2222/layer.tar", "../1111/layer.tar"),
+	))
+
+	files, err := Files(archive, filter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := paths(files), "app:1:/etc/ssl/ca.pem,app:2:/etc/ssl/ca.pem"; got != want {
+		t.Errorf("files = %s, want %s", got, want)
+	}
+}
+
+func TestOCILayout(t *testing.T) {
+	layerBlob, layerDigest := blob(gzipped(tarball(t, file("etc/ssl/server.pem", "server"))))
+	manifest, manifestDigest := blob(marshal(t, map[string]interface{}{
+		"mediaType": "application/vnd.oci.image.manifest.v1+json",
+		"layers":    []map[string]string{{"mediaType": "application/vnd.oci.image.layer.v1.tar+gzip", "digest": layerDigest}},
+	}))
+	// Multi-platform images nest an index between index.json and the manifest
+	nested, nestedDigest := blob(marshal(t, map[string]interface{}{
+		"mediaType": "application/vnd.oci.image.index.v1+json",
+		"manifests": []map[string]string{{"mediaType": "application/vnd.oci.image.manifest.v1+json", "digest": manifestDigest}},
+	}))
+	archive := writeArchive(t, tarball(t,
+		file("oci-layout", `{"imageLayoutVersion": "1.0.0"}`),
+		jsonFile(t, "index.json", map[string]interface{}{
+			"manifests": []map[string]interface{}{{
+				"mediaType":   "application/vnd.oci.image.index.v1+json",
+				"digest":      nestedDigest,
+				"annotations": map[string]string{"org.opencontainers.image.ref.name": "app:1"},
+			}},
+		}),
+		nested, manifest, layerBlob,
+	))
+
+	files, err := Files(archive, filter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || files[0].Image != "app:1" || files[0].Path != "/etc/ssl/server.pem" || files[0].Layer != layerDigest {
+		t.Errorf("files = %+v", files)
+	}
+}
+
+func TestPlainArchive(t *testing.T) {
+	archive := writeArchive(t, tarball(t, file("etc/ssl/a.pem", "a"), file("etc/ssl/big.pem", strings.Repeat("x", 100))))
+	files, err := Files(archive, Filter{MaxSize: 10, Keep: func(p string, data []byte) bool { return strings.HasSuffix(p, ".pem") }})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := paths(files); got != ":/etc/ssl/a.pem" {
+		t.Errorf("files = %s, want only the file under the size limit", got)
+	}
+}
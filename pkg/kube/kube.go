@@ -0,0 +1,220 @@
+package kube
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"io"
+	"sort"
+	"strings"
+
+	"sentinel/pkg/certfile"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Item kinds
+const (
+	KindKubeconfig = "kubeconfig"
+	KindSecret     = "secret"
+)
+
+// Certificate data found in a kubeconfig or a Secret manifest
+type Item struct {
+	Kind         string
+	Contexts     []string // kubeconfig contexts using the cluster or user
+	Cluster      string
+	User         string
+	Namespace    string
+	Name         string // Secret name
+	Field        string // client-certificate-data, certificate-authority-data, tls.crt, ca.crt
+	Certificates []*x509.Certificate
+	KeyMatch     string // matched, mismatch, not_found, empty when no key belongs to the field
+}
+
+type kubeconfig struct {
+	Kind     string `yaml:"kind"`
+	Clusters []struct {
+		Name    string `yaml:"name"`
+		Cluster struct {
+			CertificateAuthorityData string `yaml:"certificate-authority-data"`
+		} `yaml:"cluster"`
+	} `yaml:"clusters"`
+	Users []struct {
+		Name string `yaml:"name"`
+		User struct {
+			ClientCertificateData string `yaml:"client-certificate-data"`
+			ClientKeyData         string `yaml:"client-key-data"`
+		} `yaml:"user"`
+	} `yaml:"users"`
+	Contexts []struct {
+		Name    string `yaml:"name"`
+		Context struct {
+			Cluster string `yaml:"cluster"`
+			User    string `yaml:"user"`
+		} `yaml:"context"`
+	} `yaml:"contexts"`
+}
+
+type manifest struct {
+	Kind     string `yaml:"kind"`
+	Type     string `yaml:"type"`
+	Metadata struct {
+		Name      string `yaml:"name"`
+		Namespace string `yaml:"namespace"`
+	} `yaml:"metadata"`
+	Data       map[string]string `yaml:"data"`
+	StringData map[string]string `yaml:"stringData"`
+	Items      []yaml.Node       `yaml:"items"`
+}
+
+// Secret fields holding certificates and the key field belonging to them
+var secretFields = map[string]string{
+	"tls.crt": "tls.key",
+	"ca.crt":  "",
+}
+
+// Parse a kubeconfig or a stream of manifests like Helm rendered output, other YAML yields nothing
+func Parse(data []byte) ([]Item, error) {
+	var items []Item
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	for {
+		var doc yaml.Node
+		err := decoder.Decode(&doc)
+		if errors.Is(err, io.EOF) {
+			return items, nil
+		}
+		if err != nil {
+			return items, err
+		}
+		items = append(items, parseDocument(&doc)...)
+	}
+}
+
+func parseDocument(doc *yaml.Node) []Item {
+	var m manifest
+	if err := doc.Decode(&m); err != nil {
+		return nil
+	}
+
+	switch m.Kind {
+	case "Config", "": // old kubeconfigs have no kind
+		var config kubeconfig
+		if err := doc.Decode(&config); err != nil {
+			return nil
+		}
+		return parseKubeconfig(config)
+	case "Secret":
+		return parseSecret(m)
+	case "List", "SecretList":
+		var items []Item
+		for i := range m.Items {
+			items = append(items, parseDocument(&m.Items[i])...)
+		}
+		return items
+	}
+	return nil
+}
+
+func parseKubeconfig(config kubeconfig) []Item {
+	var items []Item
+	for _, cluster := range config.Clusters {
+		if cluster.Cluster.CertificateAuthorityData == "" {
+			continue
+		}
+		item := Item{Kind: KindKubeconfig, Cluster: cluster.Name, Field: "certificate-authority-data"}
+		for _, context := range config.Contexts {
+			if context.Context.Cluster == cluster.Name {
+				item.Contexts = append(item.Contexts, context.Name)
+			}
+		}
+		item.Certificates = certificates(decodeBase64(cluster.Cluster.CertificateAuthorityData))
+		items = append(items, item)
+	}
+
+	for _, user := range config.Users {
+		if user.User.ClientCertificateData == "" {
+			continue
+		}
+		item := Item{Kind: KindKubeconfig, User: user.Name, Field: "client-certificate-data"}
+		for _, context := range config.Contexts {
+			if context.Context.User == user.Name {
+				item.Contexts = append(item.Contexts, context.Name)
+				item.Cluster = context.Context.Cluster
+			}
+		}
+		item.Certificates = certificates(decodeBase64(user.User.ClientCertificateData))
+		item.KeyMatch = keyMatch(item.Certificates, decodeBase64(user.User.ClientKeyData))
+		items = append(items, item)
+	}
+	return items
+}
+
+func parseSecret(m manifest) []Item {
+	// data is base64 encoded, stringData is written by hand in the clear
+	values := map[string][]byte{}
+	for key, value := range m.Data {
+		values[key] = decodeBase64(value)
+	}
+	for key, value := range m.StringData {
+		values[key] = []byte(value)
+	}
+
+	var fields []string
+	for field := range secretFields {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	var items []Item
+	for _, field := range fields {
+		if len(values[field]) == 0 {
+			continue
+		}
+		item := Item{
+			Kind:         KindSecret,
+			Namespace:    m.Metadata.Namespace,
+			Name:         m.Metadata.Name,
+			Field:        field,
+			Certificates: certificates(values[field]),
+		}
+		if keyField := secretFields[field]; keyField != "" {
+			item.KeyMatch = keyMatch(item.Certificates, values[keyField])
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+func certificates(data []byte) []*x509.Certificate {
+	bundle, err := certfile.Parse(data, nil)
+	if err != nil {
+		return nil
+	}
+	return bundle.Certificates
+}
+
+// Compare the private key with the first certificate of the field
+func keyMatch(certs []*x509.Certificate, key []byte) string {
+	if len(certs) == 0 {
+		return ""
+	}
+	bundle, err := certfile.Parse(key, nil)
+	if err != nil || len(bundle.Keys) == 0 {
+		return "not_found"
+	}
+	if certfile.KeyMatches(certs[0], bundle.Keys[0]) {
+		return "matched"
+	}
+	return "mismatch"
+}
+
+func decodeBase64(value string) []byte {
+	value = strings.Join(strings.Fields(value), "")
+	data, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return nil
+	}
+	return data
+}
@@ -0,0 +1,163 @@
+package kube
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+)
+
+// PEM certificate and PKCS#8 key of a self-signed certificate
+func testPair(t *testing.T, cn string) (string, string) {
+	t.Helper()
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, _ := x509.MarshalPKCS8PrivateKey(key)
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+		string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}))
+}
+
+func b64(s string) string {
+	return base64.StdEncoding.EncodeToString([]byte(s))
+}
+
+// Indent a PEM block for a YAML block scalar
+func block(s string) string {
+	return "|\n      " + strings.ReplaceAll(strings.TrimSpace(s), "\n", "\n      ")
+}
+
+// Items as "kind field name/cluster/user key-match subject" lines
+func summary(items []Item) string {
+	var lines []string
+	for _, item := range items {
+		subject := ""
+		if len(item.Certificates) > 0 {
+			subject = item.Certificates[0].Subject.CommonName
+		}
+		owner := item.Namespace + "/" + item.Name
+		if item.Kind == KindKubeconfig {
+			owner = item.Cluster + "/" + item.User + "@" + strings.Join(item.Contexts, "+")
+		}
+		lines = append(lines, strings.Join([]string{item.Kind, item.Field, owner, item.KeyMatch, subject}, " "))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func TestParse(t *testing.T) {
+	caCert, _ := testPair(t, "cluster-ca")
+	clientCert, clientKey := testPair(t, "admin")
+	serverCert, serverKey := testPair(t, "www.example.com")
+	_, otherKey := testPair(t, "other")
+
+	kubeconfig := `apiVersion: v1
+kind: Config
+clusters:
+- name: prod
+  cluster:
+    server: https://prod.example.com:6443
+    certificate-authority-data: ` + b64(caCert) + `
+- name: staging
+  cluster:
+    server: https://staging.example.com:6443
+contexts:
+- name: prod-admin
+  context: {cluster: prod, user: admin}
+- name: prod-readonly
+  context: {cluster: prod, user: readonly}
+users:
+- name: admin
+  user:
+    client-certificate-data: ` + b64(clientCert) + `
+    client-key-data: ` + b64(clientKey) + `
+- name: readonly
+  user:
+    token: abc
+`
+
+	secret := func(name string, cert string, key string) string {
+		return `apiVersion: v1
+kind: Secret
+type: kubernetes.io/tls
+metadata:
+  name: ` + name + `
+  namespace: web
+data:
+  tls.crt: ` + b64(cert) + `
+  tls.key: ` + b64(key) + `
+`
+	}
+
+	stringData := `apiVersion: v1
+kind: Secret
+metadata:
+  name: handwritten
+  namespace: web
+stringData:
+  tls.crt: ` + block(serverCert) + `
+  tls.key: ` + block(serverKey) + `
+  ca.crt: ` + block(caCert) + `
+`
+
+	list := `apiVersion: v1
+kind: List
+items:
+- apiVersion: v1
+  kind: Secret
+  metadata: {name: listed, namespace: web}
+  data:
+    tls.crt: ` + b64(serverCert) + `
+- apiVersion: v1
+  kind: ConfigMap
+  metadata: {name: settings}
+  data:
+    tls.crt: ` + b64(serverCert) + `
+`
+
+	helm := "---\n# Source: web/templates/secret.yaml\n" + secret("web-tls", serverCert, serverKey) +
+		"---\n# Source: web/templates/configmap.yaml\napiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: web\ndata:\n  key: value\n" +
+		"---\n# Source: web/templates/old.yaml\n" + secret("old-tls", serverCert, otherKey)
+
+	tests := []struct {
+		name    string
+		data    string
+		want    string
+		wantErr bool
+	}{
+		{"kubeconfig", kubeconfig, "kubeconfig certificate-authority-data prod/@prod-admin+prod-readonly  cluster-ca\n" +
+			"kubeconfig client-certificate-data prod/admin@prod-admin matched admin", false},
+		{"Secret data", secret("web-tls", serverCert, serverKey), "secret tls.crt web/web-tls matched www.example.com", false},
+		{"mismatched tls.key", secret("web-tls", serverCert, otherKey), "secret tls.crt web/web-tls mismatch www.example.com", false},
+		{"Secret stringData", stringData, "secret ca.crt web/handwritten  cluster-ca\nsecret tls.crt web/handwritten matched www.example.com", false},
+		{"List", list, "secret tls.crt web/listed not_found www.example.com", false},
+		{"Helm output", helm, "secret tls.crt web/web-tls matched www.example.com\nsecret tls.crt web/old-tls mismatch www.example.com", false},
+		{"other YAML", "server:\n  port: 443\n", "", false},
+		{"invalid YAML", "kind: Secret\n  data: [", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			items, err := Parse([]byte(tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := summary(items); got != tt.want {
+				t.Errorf("items:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
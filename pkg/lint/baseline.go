@@ -0,0 +1,219 @@
+package lint
+
+import (
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"sentinel/models"
+)
+
+// Baseline requirements of the CA/Browser Forum for TLS server certificates
+func init() {
+	Register(Lint{
+		Name:        "serial_number_entropy",
+		Description: "Serial numbers must contain at least 64 bits of CSPRNG output (BR 7.1).",
+		Severity:    models.SeverityWarning,
+		AppliesTo:   Subscriber,
+		Check: func(cert *x509.Certificate) []string {
+			// 64 random bits have a leading zero bit half of the time, only the encoded length tells them apart
+			if octets := serialOctets(cert); octets < 8 {
+				return []string{fmt.Sprintf("Serial number is %d octets, at least 8 octets with 64 bits of entropy required.", octets)}
+			}
+			return nil
+		},
+	})
+
+	Register(Lint{
+		Name:        "serial_number_length",
+		Description: "Serial numbers must be positive and at most 20 octets (RFC 5280 4.1.2.2).",
+		Severity:    models.SeverityWarning,
+		AppliesTo:   Subscriber | CA,
+		Check: func(cert *x509.Certificate) []string {
+			if cert.SerialNumber.Sign() <= 0 {
+				return []string{"Serial number is not positive."}
+			}
+			if octets := serialOctets(cert); octets > 20 {
+				return []string{fmt.Sprintf("Serial number is %d octets, at most 20 allowed.", octets)}
+			}
+			return nil
+		},
+	})
+
+	Register(Lint{
+		Name:        "subscriber_required_extensions",
+		Description: "Subscriber certificates must contain SAN, AKI, EKU, certificate policies and a revocation pointer (BR 7.1.2.7).",
+		Severity:    models.SeverityWarning,
+		AppliesTo:   Subscriber,
+		Check: func(cert *x509.Certificate) []string {
+			var messages []string
+			if len(cert.DNSNames) == 0 && len(cert.IPAddresses) == 0 {
+				messages = append(messages, "Subject alternative name extension is missing.")
+			}
+			if len(cert.AuthorityKeyId) == 0 {
+				messages = append(messages, "Authority key identifier extension is missing.")
+			}
+			if len(cert.ExtKeyUsage) == 0 && len(cert.UnknownExtKeyUsage) == 0 {
+				messages = append(messages, "Extended key usage extension is missing.")
+			}
+			if len(cert.PolicyIdentifiers) == 0 {
+				messages = append(messages, "Certificate policies extension is missing.")
+			}
+			if len(cert.OCSPServer) == 0 && len(cert.CRLDistributionPoints) == 0 {
+				messages = append(messages, "Neither an OCSP responder nor a CRL distribution point is present.")
+			}
+			return messages
+		},
+	})
+
+	Register(Lint{
+		Name:        "ca_required_extensions",
+		Description: "CA certificates must contain critical basic constraints, key usage and a subject key identifier (BR 7.1.2.10).",
+		Severity:    models.SeverityWarning,
+		AppliesTo:   CA,
+		Check: func(cert *x509.Certificate) []string {
+			var messages []string
+			if !cert.BasicConstraintsValid || !cert.IsCA {
+				messages = append(messages, "Basic constraints extension with cA=true is missing.")
+			}
+			if cert.KeyUsage == 0 {
+				messages = append(messages, "Key usage extension is missing.")
+			}
+			if len(cert.SubjectKeyId) == 0 {
+				messages = append(messages, "Subject key identifier extension is missing.")
+			}
+			return messages
+		},
+	})
+
+	Register(Lint{
+		Name:        "subject_forbidden_fields",
+		Description: "Subject must not contain organizational unit or metadata only values like \".\" or \"-\" (BR 7.1.4.2).",
+		Severity:    models.SeverityWarning,
+		AppliesTo:   Subscriber,
+		Check: func(cert *x509.Certificate) []string {
+			var messages []string
+			if len(cert.Subject.OrganizationalUnit) > 0 {
+				messages = append(messages, "Subject contains an organizational unit.")
+			}
+			for _, attr := range cert.Subject.Names {
+				value, ok := attr.Value.(string)
+				if ok && strings.Trim(value, " .-") == "" {
+					messages = append(messages, fmt.Sprintf("Subject attribute %s only contains metadata %q.", attr.Type, value))
+				}
+			}
+			return messages
+		},
+	})
+
+	Register(Lint{
+		Name:        "san_cn_consistency",
+		Description: "Common name, if present, must be one of the subject alternative names (BR 7.1.4.3).",
+		Severity:    models.SeverityWarning,
+		AppliesTo:   Subscriber,
+		Check: func(cert *x509.Certificate) []string {
+			cn := cert.Subject.CommonName
+			if cn == "" {
+				return nil
+			}
+			for _, name := range cert.DNSNames {
+				if strings.EqualFold(name, cn) {
+					return nil
+				}
+			}
+			if ip := net.ParseIP(cn); ip != nil {
+				for _, v := range cert.IPAddresses {
+					if v.Equal(ip) {
+						return nil
+					}
+				}
+			}
+			return []string{fmt.Sprintf("Common name %q is not in the subject alternative names.", cn)}
+		},
+	})
+
+	Register(Lint{
+		Name:        "san_dns_name_syntax",
+		Description: "DNS names must be valid host names without underscores (BR 7.1.4.2.1).",
+		Severity:    models.SeverityWarning,
+		AppliesTo:   Subscriber,
+		Check: func(cert *x509.Certificate) []string {
+			var messages []string
+			for _, name := range cert.DNSNames {
+				if strings.Contains(name, "_") {
+					messages = append(messages, fmt.Sprintf("DNS name %q contains an underscore.", name))
+				}
+				if strings.Count(name, "*") > 1 || (strings.Contains(name, "*") && !strings.HasPrefix(name, "*.")) {
+					messages = append(messages, fmt.Sprintf("DNS name %q has a misplaced wildcard.", name))
+				}
+			}
+			return messages
+		},
+	})
+
+	Register(Lint{
+		Name:        "validity_period",
+		Description: "Subscriber certificates must not be valid for more than 398 days (BR 6.3.2).",
+		Severity:    models.SeverityWarning,
+		AppliesTo:   Subscriber,
+		Check: func(cert *x509.Certificate) []string {
+			// NotAfter is inclusive, a 398 day certificate lasts 398 days and one second
+			validity := cert.NotAfter.Sub(cert.NotBefore) + time.Second
+			if validity > 398*24*time.Hour {
+				return []string{fmt.Sprintf("Validity period is %d days, at most 398 days allowed.", int(validity.Hours()/24))}
+			}
+			return nil
+		},
+	})
+
+	Register(Lint{
+		Name:        "subscriber_key_usage",
+		Description: "Subscriber key usage must fit the key type and must not allow certificate or CRL signing (BR 7.1.2.7.11).",
+		Severity:    models.SeverityWarning,
+		AppliesTo:   Subscriber,
+		Check: func(cert *x509.Certificate) []string {
+			var messages []string
+			if cert.KeyUsage&(x509.KeyUsageCertSign|x509.KeyUsageCRLSign) != 0 {
+				messages = append(messages, "Key usage allows certificate or CRL signing.")
+			}
+			switch cert.PublicKey.(type) {
+			case *ecdsa.PublicKey:
+				if cert.KeyUsage&(x509.KeyUsageKeyEncipherment|x509.KeyUsageDataEncipherment) != 0 {
+					messages = append(messages, "ECDSA key with key or data encipherment usage.")
+				}
+			case *rsa.PublicKey:
+				if cert.KeyUsage != 0 && cert.KeyUsage&(x509.KeyUsageDigitalSignature|x509.KeyUsageKeyEncipherment) == 0 {
+					messages = append(messages, "RSA key without digital signature or key encipherment usage.")
+				}
+			}
+			for _, usage := range cert.ExtKeyUsage {
+				if usage == x509.ExtKeyUsageAny {
+					messages = append(messages, "Extended key usage contains anyExtendedKeyUsage.")
+				}
+			}
+			return messages
+		},
+	})
+
+	Register(Lint{
+		Name:        "ca_key_usage",
+		Description: "CA certificates must allow certificate signing (BR 7.1.2.10.7).",
+		Severity:    models.SeverityWarning,
+		AppliesTo:   CA,
+		Check: func(cert *x509.Certificate) []string {
+			if cert.KeyUsage != 0 && cert.KeyUsage&x509.KeyUsageCertSign == 0 {
+				return []string{"Key usage does not allow certificate signing."}
+			}
+			return nil
+		},
+	})
+}
+
+// Content octets of the DER encoded serial number, positive integers with the high bit set need a leading zero octet
+func serialOctets(cert *x509.Certificate) int {
+	return cert.SerialNumber.BitLen()/8 + 1
+}
@@ -0,0 +1,78 @@
+package lint
+
+import (
+	"crypto/x509"
+	"sort"
+)
+
+// Certificate kinds a lint applies to
+const (
+	Subscriber = 1 << iota // leaf certificates
+	CA                     // intermediate and root certificates
+)
+
+// Lint is a single baseline requirements check
+type Lint struct {
+	Name        string
+	Description string
+	Severity    string // info, warning, critical
+	AppliesTo   int    // Subscriber, CA or both
+	// Check returns a message for every problem found, nil when the certificate passes
+	Check func(cert *x509.Certificate) []string
+}
+
+// Lint problem of a certificate
+type Result struct {
+	Lint     string
+	Severity string
+	Message  string
+}
+
+var registry = map[string]Lint{}
+
+// Register makes a lint available to Run, lints register themselves in init
+func Register(l Lint) {
+	if _, ok := registry[l.Name]; ok {
+		panic("lint: duplicate lint " + l.Name)
+	}
+	registry[l.Name] = l
+}
+
+// Names of the registered lints
+func Names() []string {
+	var names []string
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Run every registered and not suppressed lint that applies to the certificate
+func Run(cert *x509.Certificate, isCA bool, suppressed []string) []Result {
+	kind := Subscriber
+	if isCA {
+		kind = CA
+	}
+
+	var results []Result
+	for _, name := range Names() {
+		l := registry[name]
+		if l.AppliesTo&kind == 0 || contains(suppressed, name) {
+			continue
+		}
+		for _, message := range l.Check(cert) {
+			results = append(results, Result{Lint: name, Severity: l.Severity, Message: message})
+		}
+	}
+	return results
+}
+
+func contains(list []string, v string) bool {
+	for _, item := range list {
+		if item == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,155 @@
+package lint
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+)
+
+var testKey, _ = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+// Subscriber template passing every baseline lint
+func subscriber() *x509.Certificate {
+	serial, _ := new(big.Int).SetString("7f3a9c1e5b2d4f6081a3c5e7092b4d6f", 16)
+	now := time.Now()
+	return &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: "www.example.com"},
+		NotBefore:             now,
+		NotAfter:              now.Add(90 * 24 * time.Hour),
+		DNSNames:              []string{"www.example.com", "example.com"},
+		AuthorityKeyId:        []byte{1, 2, 3, 4},
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		PolicyIdentifiers:     []asn1.ObjectIdentifier{{2, 23, 140, 1, 2, 1}},
+		OCSPServer:            []string{"http://ocsp.example.com"},
+		BasicConstraintsValid: true,
+	}
+}
+
+func intermediate() *x509.Certificate {
+	now := time.Now()
+	return &x509.Certificate{
+		SerialNumber:          big.NewInt(1 << 62),
+		Subject:               pkix.Name{CommonName: "Example CA"},
+		NotBefore:             now,
+		NotAfter:              now.Add(5 * 365 * 24 * time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		SubjectKeyId:          []byte{5, 6, 7, 8},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+}
+
+func create(t *testing.T, tmpl *x509.Certificate) *x509.Certificate {
+	t.Helper()
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &testKey.PublicKey, testKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert
+}
+
+func serial(hex string) *big.Int {
+	n, _ := new(big.Int).SetString(hex, 16)
+	return n
+}
+
+func TestBaselineLints(t *testing.T) {
+	tests := []struct {
+		name   string
+		isCA   bool
+		modify func(c *x509.Certificate)
+		fired  []string
+	}{
+		{"compliant subscriber", false, func(c *x509.Certificate) {}, nil},
+		{"compliant CA", true, func(c *x509.Certificate) {}, nil},
+		{"64 random bits with a leading zero bit", false, func(c *x509.Certificate) { c.SerialNumber = serial("7fffffffffffffff") }, nil},
+		{"64 random bits with the high bit set", false, func(c *x509.Certificate) { c.SerialNumber = serial("ffffffffffffffff") }, nil},
+		{"7 octet serial", false, func(c *x509.Certificate) { c.SerialNumber = serial("7fffffffffffff") }, []string{"serial_number_entropy"}},
+		{"21 octet serial", false, func(c *x509.Certificate) { c.SerialNumber = serial("ff" + "0102030405060708090a0b0c0d0e0f10111213") }, []string{"serial_number_length"}},
+		{"20 octet serial", false, func(c *x509.Certificate) { c.SerialNumber = serial("7f" + "0102030405060708090a0b0c0d0e0f10111213") }, nil},
+		{"missing extensions", false, func(c *x509.Certificate) {
+			c.AuthorityKeyId = nil
+			c.PolicyIdentifiers = nil
+			c.OCSPServer = nil
+		}, []string{"subscriber_required_extensions"}},
+		{"organizational unit", false, func(c *x509.Certificate) { c.Subject.OrganizationalUnit = []string{"IT"} }, []string{"subject_forbidden_fields"}},
+		{"common name outside SAN", false, func(c *x509.Certificate) { c.Subject.CommonName = "other.example.com" }, []string{"san_cn_consistency"}},
+		{"IP common name in SAN", false, func(c *x509.Certificate) {
+			c.Subject.CommonName = "192.0.2.1"
+			c.IPAddresses = []net.IP{net.ParseIP("192.0.2.1")}
+		}, nil},
+		{"underscore and misplaced wildcard", false, func(c *x509.Certificate) {
+			c.DNSNames = append(c.DNSNames, "a_b.example.com", "www.*.example.com")
+		}, []string{"san_dns_name_syntax"}},
+		{"398 days", false, func(c *x509.Certificate) { c.NotAfter = c.NotBefore.Add(398*24*time.Hour - time.Second) }, nil},
+		{"399 days", false, func(c *x509.Certificate) { c.NotAfter = c.NotBefore.Add(399 * 24 * time.Hour) }, []string{"validity_period"}},
+		{"subscriber with cert signing", false, func(c *x509.Certificate) { c.KeyUsage |= x509.KeyUsageCertSign }, []string{"subscriber_key_usage"}},
+		{"ECDSA with key encipherment", false, func(c *x509.Certificate) { c.KeyUsage |= x509.KeyUsageKeyEncipherment }, []string{"subscriber_key_usage"}},
+		{"CA without cert signing", true, func(c *x509.Certificate) { c.KeyUsage = x509.KeyUsageCRLSign }, []string{"ca_key_usage"}},
+		{"CA without key usage", true, func(c *x509.Certificate) { c.KeyUsage = 0 }, []string{"ca_required_extensions"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl := subscriber()
+			if tt.isCA {
+				tmpl = intermediate()
+			}
+			tt.modify(tmpl)
+
+			fired := map[string]bool{}
+			for _, result := range Run(create(t, tmpl), tt.isCA, nil) {
+				fired[result.Lint] = true
+			}
+			for _, name := range tt.fired {
+				if !fired[name] {
+					t.Errorf("lint %s did not fire", name)
+				}
+				delete(fired, name)
+			}
+			for name := range fired {
+				t.Errorf("unexpected lint %s", name)
+			}
+		})
+	}
+}
+
+func TestRunSuppressed(t *testing.T) {
+	tmpl := subscriber()
+	tmpl.SerialNumber = big.NewInt(1)
+	cert := create(t, tmpl)
+
+	if results := Run(cert, false, nil); len(results) != 1 || results[0].Lint != "serial_number_entropy" || results[0].Severity == "" {
+		t.Fatalf("results = %+v, want one serial_number_entropy result", results)
+	}
+	if results := Run(cert, false, []string{"serial_number_entropy"}); len(results) != 0 {
+		t.Errorf("suppressed lint still reported: %+v", results)
+	}
+	// Subscriber only lints do not run on CA certificates
+	for _, result := range Run(cert, true, nil) {
+		if result.Lint == "serial_number_entropy" {
+			t.Error("subscriber lint ran on a CA certificate")
+		}
+	}
+}
+
+func TestRegisterDuplicate(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("registering a lint twice did not panic")
+		}
+	}()
+	Register(Lint{Name: "validity_period"})
+}
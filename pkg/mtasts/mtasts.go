@@ -0,0 +1,136 @@
+package mtasts
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Policy modes
+const (
+	ModeEnforce = "enforce"
+	ModeTesting = "testing"
+	ModeNone    = "none"
+)
+
+// Longest max_age a policy may declare, one year (RFC 8461, 3.2)
+const MaxAgeLimit = 31557600
+
+// Policy files larger than this are rejected by senders (RFC 8461, 3.3)
+const maxPolicySize = 64 << 10
+
+// MTA-STS policy (RFC 8461, 3.2)
+type Policy struct {
+	Version string
+	Mode    string
+	MX      []string // host names, "*." matches a single leftmost label
+	MaxAge  int      // seconds
+}
+
+// Key value pairs of a TXT record like "v=STSv1; id=20240101" or "v=TLSRPTv1; rua=mailto:..."
+func ParseRecord(record string) map[string]string {
+	fields := map[string]string{}
+	for _, part := range strings.Split(record, ";") {
+		key, value, found := strings.Cut(strings.TrimSpace(part), "=")
+		if found {
+			fields[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		}
+	}
+	return fields
+}
+
+// Parse the policy body, unknown keys are ignored
+func ParsePolicy(body string) (Policy, error) {
+	var policy Policy
+	maxAge := ""
+	for _, line := range strings.Split(body, "\n") {
+		key, value, found := strings.Cut(strings.TrimRight(line, "\r"), ":")
+		if !found {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch strings.TrimSpace(key) {
+		case "version":
+			policy.Version = value
+		case "mode":
+			policy.Mode = value
+		case "mx":
+			policy.MX = append(policy.MX, strings.ToLower(value))
+		case "max_age":
+			maxAge = value
+		}
+	}
+
+	if policy.Version != "STSv1" {
+		return policy, fmt.Errorf("policy version %q is not STSv1", policy.Version)
+	}
+	switch policy.Mode {
+	case ModeEnforce, ModeTesting, ModeNone:
+	default:
+		return policy, fmt.Errorf("policy mode %q is not enforce, testing or none", policy.Mode)
+	}
+	age, err := strconv.Atoi(maxAge)
+	if err != nil || age < 0 {
+		return policy, fmt.Errorf("policy max_age %q is not a number of seconds", maxAge)
+	}
+	policy.MaxAge = age
+	if len(policy.MX) == 0 && policy.Mode != ModeNone {
+		return policy, errors.New("policy lists no mx")
+	}
+	return policy, nil
+}
+
+// Whether the MX host name is covered by the policy
+func (p Policy) Matches(host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	for _, pattern := range p.MX {
+		if MatchMX(pattern, host) {
+			return true
+		}
+	}
+	return false
+}
+
+// Match a host against a policy mx pattern, a wildcard covers exactly one label (RFC 8461, 4.1)
+func MatchMX(pattern string, host string) bool {
+	pattern = strings.ToLower(strings.TrimSuffix(pattern, "."))
+	if suffix, found := strings.CutPrefix(pattern, "*."); found {
+		label, rest, found := strings.Cut(host, ".")
+		return found && label != "" && rest == suffix
+	}
+	return pattern == host
+}
+
+// URL the policy of the domain is served at
+func PolicyURL(domain string) string {
+	return "https://mta-sts." + strings.TrimSuffix(domain, ".") + "/.well-known/mta-sts.txt"
+}
+
+// Fetch the policy of the domain. The client has to verify the certificate of the policy host
+// and must not follow redirects, senders do neither (RFC 8461, 3.3).
+func Fetch(client *http.Client, domain string) (Policy, error) {
+	resp, err := client.Get(PolicyURL(domain))
+	if err != nil {
+		return Policy{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return Policy{}, fmt.Errorf("policy request returned %s", resp.Status)
+	}
+	if mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type")); mediaType != "text/plain" {
+		return Policy{}, fmt.Errorf("policy served as %q instead of text/plain", resp.Header.Get("Content-Type"))
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxPolicySize+1))
+	if err != nil {
+		return Policy{}, err
+	}
+	if len(body) > maxPolicySize {
+		return Policy{}, errors.New("policy is larger than 64 KB")
+	}
+	return ParsePolicy(string(body))
+}
@@ -0,0 +1,139 @@
+package mtasts
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParsePolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		want    Policy
+		wantErr bool
+	}{
+		{"enforce", "version: STSv1\nmode: enforce\nmx: mx1.example.com\nmx: *.Example.net\nmax_age: 604800\n",
+			Policy{Version: "STSv1", Mode: ModeEnforce, MX: []string{"mx1.example.com", "*.example.net"}, MaxAge: 604800}, false},
+		{"CRLF and spacing", "version: STSv1\r\nmode:testing\r\nmx:  mx1.example.com \r\nmax_age: 86400\r\n",
+			Policy{Version: "STSv1", Mode: ModeTesting, MX: []string{"mx1.example.com"}, MaxAge: 86400}, false},
+		{"unknown keys ignored", "version: STSv1\nmode: enforce\nmx: mx1.example.com\nmax_age: 86400\nfoo: bar\n",
+			Policy{Version: "STSv1", Mode: ModeEnforce, MX: []string{"mx1.example.com"}, MaxAge: 86400}, false},
+		{"none without mx", "version: STSv1\nmode: none\nmax_age: 86400\n",
+			Policy{Version: "STSv1", Mode: ModeNone, MaxAge: 86400}, false},
+		{"wrong version", "version: STSv2\nmode: enforce\nmx: mx1.example.com\nmax_age: 86400\n", Policy{}, true},
+		{"unknown mode", "version: STSv1\nmode: strict\nmx: mx1.example.com\nmax_age: 86400\n", Policy{}, true},
+		{"missing max_age", "version: STSv1\nmode: enforce\nmx: mx1.example.com\n", Policy{}, true},
+		{"negative max_age", "version: STSv1\nmode: enforce\nmx: mx1.example.com\nmax_age: -1\n", Policy{}, true},
+		{"enforce without mx", "version: STSv1\nmode: enforce\nmax_age: 86400\n", Policy{}, true},
+		{"HTML error page", "<html><body>Not Found</body></html>", Policy{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy, err := ParsePolicy(tt.body)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if policy.Version != tt.want.Version || policy.Mode != tt.want.Mode || policy.MaxAge != tt.want.MaxAge ||
+				strings.Join(policy.MX, ",") != strings.Join(tt.want.MX, ",") {
+				t.Errorf("policy = %+v, want %+v", policy, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchMX(t *testing.T) {
+	tests := []struct {
+		pattern string
+		host    string
+		want    bool
+	}{
+		{"mx1.example.com", "mx1.example.com", true},
+		{"MX1.example.com.", "mx1.example.com", true},
+		{"mx1.example.com", "mx2.example.com", false},
+		{"*.example.com", "mx1.example.com", true},
+		{"*.example.com", "a.b.example.com", false},
+		{"*.example.com", "example.com", false},
+		{"*.example.com", ".example.com", false},
+		{"*.example.com", "mx1.example.net", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.host, func(t *testing.T) {
+			if got := MatchMX(tt.pattern, tt.host); got != tt.want {
+				t.Errorf("MatchMX(%q, %q) = %v, want %v", tt.pattern, tt.host, got, tt.want)
+			}
+		})
+	}
+
+	policy := Policy{MX: []string{"mx1.example.com", "*.backup.example.com"}}
+	for host, want := range map[string]bool{"MX1.Example.com.": true, "b1.backup.example.com": true, "mx2.example.com": false} {
+		if got := policy.Matches(host); got != want {
+			t.Errorf("Matches(%q) = %v, want %v", host, got, want)
+		}
+	}
+}
+
+func TestParseRecord(t *testing.T) {
+	fields := ParseRecord("v=STSv1; id=20240101T000000;  ")
+	if fields["v"] != "STSv1" || fields["id"] != "20240101T000000" || len(fields) != 2 {
+		t.Errorf("fields = %v", fields)
+	}
+	fields = ParseRecord("v=TLSRPTv1;rua=mailto:tls@example.com,https://report.example.com/v1")
+	if fields["rua"] != "mailto:tls@example.com,https://report.example.com/v1" {
+		t.Errorf("rua = %q", fields["rua"])
+	}
+}
+
+func TestFetch(t *testing.T) {
+	const policy = "version: STSv1\nmode: enforce\nmx: mx1.example.com\nmax_age: 86400\n"
+	tests := []struct {
+		name        string
+		status      int
+		contentType string
+		body        string
+		wantErr     bool
+	}{
+		{"text/plain", http.StatusOK, "text/plain", policy, false},
+		{"with charset", http.StatusOK, "text/plain; charset=utf-8", policy, false},
+		{"wrong media type", http.StatusOK, "text/html", policy, true},
+		{"not found", http.StatusNotFound, "text/plain", policy, true},
+		{"too large", http.StatusOK, "text/plain", policy + strings.Repeat("#", maxPolicySize), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Host != "mta-sts.example.com" || r.URL.Path != "/.well-known/mta-sts.txt" {
+					http.NotFound(w, r)
+					return
+				}
+				w.Header().Set("Content-Type", tt.contentType)
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			// The policy host resolves to the test server
+			client := server.Client()
+			transport := client.Transport.(*http.Transport)
+			transport.DialContext = func(ctx context.Context, network, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, network, server.Listener.Addr().String())
+			}
+			transport.TLSClientConfig.ServerName = "example.com"
+
+			got, err := Fetch(client, "example.com.")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && (got.Mode != ModeEnforce || !got.Matches("mx1.example.com")) {
+				t.Errorf("policy = %+v", got)
+			}
+		})
+	}
+}
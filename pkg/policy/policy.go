@@ -0,0 +1,118 @@
+package policy
+
+import (
+	"crypto/rsa"
+	"crypto/x509"
+	"fmt"
+	"strings"
+	"time"
+
+	"sentinel/models"
+)
+
+// Policy violation
+type Violation struct {
+	Policy   string
+	Severity string
+	Message  string
+}
+
+// Evaluate every policy that applies to the target against the presented chain
+func Evaluate(policies []models.Policy, target models.Target, hostname string, certs []*x509.Certificate) []Violation {
+	var violations []Violation
+	for _, p := range policies {
+		if len(p.Tags) > 0 && !target.HasAnyTag(p.Tags) {
+			continue
+		}
+
+		severity := p.Severity
+		if severity == "" {
+			severity = models.SeverityWarning
+		}
+		for _, message := range check(p, hostname, certs) {
+			violations = append(violations, Violation{Policy: p.Name, Severity: severity, Message: message})
+		}
+	}
+	return violations
+}
+
+func check(p models.Policy, hostname string, certs []*x509.Certificate) []string {
+	var messages []string
+	leaf := certs[0]
+
+	// Key and signature requirements hold for the leaf and the intermediates, roots are not presented for trust
+	for i, cert := range certs {
+		if i > 0 && isSelfSigned(cert) {
+			continue
+		}
+		name := certName(i, cert)
+
+		if key, ok := cert.PublicKey.(*rsa.PublicKey); ok && p.MinRSABits > 0 && key.N.BitLen() < p.MinRSABits {
+			messages = append(messages, fmt.Sprintf("%s has a %d bit RSA key, at least %d bits required.", name, key.N.BitLen(), p.MinRSABits))
+		}
+		if p.ForbidSHA1 && isSHA1(cert.SignatureAlgorithm) {
+			messages = append(messages, fmt.Sprintf("%s is signed with %s.", name, cert.SignatureAlgorithm))
+		}
+	}
+
+	if p.MaxValidityDays > 0 {
+		days := int(leaf.NotAfter.Sub(leaf.NotBefore) / (24 * time.Hour))
+		if days > p.MaxValidityDays {
+			messages = append(messages, fmt.Sprintf("Certificate is valid for %d days, at most %d days allowed.", days, p.MaxValidityDays))
+		}
+	}
+
+	if len(p.AllowedIssuers) > 0 && !issuerAllowed(leaf, p.AllowedIssuers) {
+		messages = append(messages, fmt.Sprintf("Issuer %q is not in the allow-list.", leaf.Issuer.String()))
+	}
+
+	if p.RequireHostnameInSAN && hostname != "" {
+		if err := leaf.VerifyHostname(hostname); err != nil {
+			messages = append(messages, fmt.Sprintf("SAN list does not include %s.", hostname))
+		}
+	}
+
+	if p.ForbidWildcard {
+		for _, name := range leaf.DNSNames {
+			if strings.HasPrefix(name, "*.") {
+				messages = append(messages, fmt.Sprintf("Wildcard certificate (%s) is forbidden.", name))
+				break
+			}
+		}
+	}
+
+	return messages
+}
+
+func issuerAllowed(cert *x509.Certificate, allowed []string) bool {
+	for _, v := range allowed {
+		if strings.EqualFold(v, cert.Issuer.CommonName) || strings.EqualFold(v, cert.Issuer.String()) {
+			return true
+		}
+		for _, org := range cert.Issuer.Organization {
+			if strings.EqualFold(v, org) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func isSHA1(alg x509.SignatureAlgorithm) bool {
+	switch alg {
+	case x509.SHA1WithRSA, x509.DSAWithSHA1, x509.ECDSAWithSHA1:
+		return true
+	}
+	return false
+}
+
+func isSelfSigned(cert *x509.Certificate) bool {
+	return cert.Subject.String() == cert.Issuer.String()
+}
+
+func certName(position int, cert *x509.Certificate) string {
+	if position == 0 {
+		return "Certificate"
+	}
+	return fmt.Sprintf("Intermediate #%d (%s)", position, cert.Subject.CommonName)
+}
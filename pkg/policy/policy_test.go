@@ -0,0 +1,102 @@
+package policy
+
+import (
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"sentinel/models"
+)
+
+func rsaKey(bits int) *rsa.PublicKey {
+	return &rsa.PublicKey{N: new(big.Int).Lsh(big.NewInt(1), uint(bits-1)), E: 65537}
+}
+
+func TestEvaluate(t *testing.T) {
+	issued := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	issuer := pkix.Name{CommonName: "R3", Organization: []string{"Let's Encrypt"}, Country: []string{"US"}}
+	leaf := &x509.Certificate{
+		Subject:            pkix.Name{CommonName: "www.example.com"},
+		Issuer:             issuer,
+		DNSNames:           []string{"www.example.com", "*.api.example.com"},
+		NotBefore:          issued,
+		NotAfter:           issued.Add(90 * 24 * time.Hour),
+		PublicKey:          rsaKey(2048),
+		SignatureAlgorithm: x509.SHA256WithRSA,
+	}
+	intermediate := &x509.Certificate{
+		Subject:            issuer,
+		Issuer:             pkix.Name{CommonName: "Legacy Root"},
+		PublicKey:          rsaKey(1024),
+		SignatureAlgorithm: x509.SHA1WithRSA,
+	}
+	root := &x509.Certificate{
+		Subject:            pkix.Name{CommonName: "Legacy Root"},
+		Issuer:             pkix.Name{CommonName: "Legacy Root"},
+		PublicKey:          rsaKey(1024),
+		SignatureAlgorithm: x509.SHA1WithRSA,
+	}
+	chain := []*x509.Certificate{leaf, intermediate, root}
+	web := models.Target{Address: "www.example.com:443", Tags: []string{"web", "prod"}}
+
+	tests := []struct {
+		name     string
+		policy   models.Policy
+		target   models.Target
+		hostname string
+		want     []string
+	}{
+		{"min RSA bits", models.Policy{MinRSABits: 2048}, web, "", []string{"Intermediate #1 (R3) has a 1024 bit RSA key, at least 2048 bits required."}},
+		{"leaf below min RSA bits", models.Policy{MinRSABits: 3072}, web, "", []string{
+			"Certificate has a 2048 bit RSA key, at least 3072 bits required.",
+			"Intermediate #1 (R3) has a 1024 bit RSA key, at least 3072 bits required.",
+		}},
+		{"SHA-1 intermediate, the root is skipped", models.Policy{ForbidSHA1: true}, web, "", []string{"Intermediate #1 (R3) is signed with SHA1-RSA."}},
+		{"max validity", models.Policy{MaxValidityDays: 60}, web, "", []string{"Certificate is valid for 90 days, at most 60 days allowed."}},
+		{"within max validity", models.Policy{MaxValidityDays: 90}, web, "", nil},
+		{"issuer CN allowed", models.Policy{AllowedIssuers: []string{"r3"}}, web, "", nil},
+		{"issuer organization allowed", models.Policy{AllowedIssuers: []string{"DigiCert Inc", "let's encrypt"}}, web, "", nil},
+		{"issuer DN allowed", models.Policy{AllowedIssuers: []string{"CN=R3,O=Let's Encrypt,C=US"}}, web, "", nil},
+		{"issuer not allowed", models.Policy{AllowedIssuers: []string{"DigiCert Inc"}}, web, "", []string{`Issuer "CN=R3,O=Let's Encrypt,C=US" is not in the allow-list.`}},
+		{"hostname in SAN", models.Policy{RequireHostnameInSAN: true}, web, "www.example.com", nil},
+		{"hostname under the wildcard", models.Policy{RequireHostnameInSAN: true}, web, "v1.api.example.com", nil},
+		{"hostname not in SAN", models.Policy{RequireHostnameInSAN: true}, web, "example.com", []string{"SAN list does not include example.com."}},
+		{"hostname unknown", models.Policy{RequireHostnameInSAN: true}, web, "", nil},
+		{"wildcard forbidden", models.Policy{ForbidWildcard: true}, web, "", []string{"Wildcard certificate (*.api.example.com) is forbidden."}},
+		{"tag scoped policy applies", models.Policy{Tags: []string{"prod"}, ForbidWildcard: true}, web, "", []string{"Wildcard certificate (*.api.example.com) is forbidden."}},
+		{"tag scoped policy skipped", models.Policy{Tags: []string{"internal"}, ForbidWildcard: true}, web, "", nil},
+		{"untagged target", models.Policy{Tags: []string{"prod"}, ForbidWildcard: true}, models.Target{Address: "www.example.com:443"}, "", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.policy.Name = "baseline"
+			violations := Evaluate([]models.Policy{tt.policy}, tt.target, tt.hostname, chain)
+			var messages []string
+			for _, v := range violations {
+				if v.Policy != "baseline" || v.Severity != models.SeverityWarning {
+					t.Errorf("violation %+v, want policy baseline at the default severity", v)
+				}
+				messages = append(messages, v.Message)
+			}
+			if strings.Join(messages, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("violations:\n%s\nwant:\n%s", strings.Join(messages, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
+
+func TestEvaluateSeverity(t *testing.T) {
+	leaf := &x509.Certificate{DNSNames: []string{"*.example.com"}}
+	policies := []models.Policy{
+		{Name: "no-wildcards", Severity: models.SeverityCritical, ForbidWildcard: true},
+		{Name: "sans", Severity: models.SeverityInfo, RequireHostnameInSAN: true},
+	}
+	violations := Evaluate(policies, models.Target{}, "example.com", []*x509.Certificate{leaf})
+	if len(violations) != 2 || violations[0].Severity != models.SeverityCritical || violations[1].Policy != "sans" || violations[1].Severity != models.SeverityInfo {
+		t.Errorf("violations = %+v", violations)
+	}
+}
@@ -0,0 +1,317 @@
+package rdap
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// IANA bootstrap registry of the RDAP servers of every TLD (RFC 9224)
+const IANABootstrap = "https://data.iana.org/rdap/dns.json"
+
+// Maximum size of an RDAP or bootstrap response
+const maxResponseSize = 4 << 20
+
+// Failed lookups are retried after this long at the earliest
+const negativeTTL = time.Hour
+
+// Domain is not registered at the RDAP server
+var ErrNotFound = errors.New("domain not found")
+
+// Registration data of a domain
+type Registration struct {
+	Domain     string
+	Server     string // RDAP base URL that answered
+	Registrar  string
+	Registered time.Time
+	Expiration time.Time
+	Status     []string // RDAP status values like "client transfer prohibited"
+}
+
+// Domain registered under an ICANN suffix that holds the host. Names under
+// private suffixes like herokuapp.com or github.io belong to the platform's
+// registration and names under unlisted TLDs have no registry, both are skipped
+func RegistrableDomain(host string) (string, bool) {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	suffix, icann := publicsuffix.PublicSuffix(host)
+	if !icann || suffix == host {
+		return "", false
+	}
+	domain, err := publicsuffix.EffectiveTLDPlusOne(host)
+	if err != nil {
+		return "", false
+	}
+	return domain, true
+}
+
+// Client options
+type Options struct {
+	Bootstrap string            // bootstrap registry URL, default: IANA
+	Servers   map[string]string // TLD: RDAP base URL, used before the bootstrap registry
+	CacheTTL  time.Duration
+	Interval  time.Duration // minimum time between two requests to the same RDAP server
+	Timeout   time.Duration
+}
+
+// Caching, rate limited RDAP client
+type Client struct {
+	options Options
+	http    *http.Client
+
+	mu        sync.Mutex
+	bootstrap map[string]string // TLD: base URL
+	cache     map[string]cacheEntry
+	last      map[string]time.Time // RDAP server: time of the last request
+	server    map[string]*sync.Mutex
+}
+
+type cacheEntry struct {
+	registration Registration
+	err          error
+	expires      time.Time
+}
+
+// Client used by the checks, replaced on configuration
+var Default = NewClient(Options{})
+
+// Create an RDAP client
+func NewClient(options Options) *Client {
+	if options.Bootstrap == "" {
+		options.Bootstrap = IANABootstrap
+	}
+	if options.CacheTTL <= 0 {
+		options.CacheTTL = 24 * time.Hour
+	}
+	if options.Timeout <= 0 {
+		options.Timeout = 10 * time.Second
+	}
+	servers := map[string]string{}
+	for tld, url := range options.Servers {
+		servers[strings.ToLower(strings.TrimPrefix(tld, "."))] = url
+	}
+	options.Servers = servers
+
+	return &Client{
+		options: options,
+		http:    &http.Client{Timeout: options.Timeout},
+		cache:   map[string]cacheEntry{},
+		last:    map[string]time.Time{},
+		server:  map[string]*sync.Mutex{},
+	}
+}
+
+// Registration data of the registrable domain, answers are cached
+func (c *Client) Lookup(domain string) (Registration, error) {
+	domain = strings.ToLower(strings.TrimSuffix(domain, "."))
+
+	c.mu.Lock()
+	entry, ok := c.cache[domain]
+	c.mu.Unlock()
+	if ok && time.Now().Before(entry.expires) {
+		return entry.registration, entry.err
+	}
+
+	registration, err := c.lookup(domain)
+	ttl := c.options.CacheTTL
+	if err != nil && ttl > negativeTTL {
+		ttl = negativeTTL
+	}
+	c.mu.Lock()
+	c.cache[domain] = cacheEntry{registration: registration, err: err, expires: time.Now().Add(ttl)}
+	c.mu.Unlock()
+	return registration, err
+}
+
+func (c *Client) lookup(domain string) (Registration, error) {
+	tld := domain[strings.LastIndex(domain, ".")+1:]
+	base, err := c.serverFor(tld)
+	if err != nil {
+		return Registration{}, err
+	}
+
+	var doc domainResponse
+	status, err := c.get(strings.TrimSuffix(base, "/")+"/domain/"+domain, &doc)
+	if status == http.StatusNotFound {
+		return Registration{}, ErrNotFound
+	}
+	if err != nil {
+		return Registration{}, err
+	}
+
+	registration := doc.registration()
+	registration.Domain = domain
+	registration.Server = base
+	return registration, nil
+}
+
+// RDAP base URL of the TLD from the configured servers or the bootstrap registry
+func (c *Client) serverFor(tld string) (string, error) {
+	if url, ok := c.options.Servers[tld]; ok {
+		return url, nil
+	}
+
+	c.mu.Lock()
+	loaded := c.bootstrap != nil
+	c.mu.Unlock()
+	if !loaded {
+		var registry struct {
+			Services [][][]string `json:"services"`
+		}
+		if _, err := c.get(c.options.Bootstrap, &registry); err != nil {
+			return "", fmt.Errorf("bootstrap registry: %w", err)
+		}
+		bootstrap := map[string]string{}
+		for _, service := range registry.Services {
+			if len(service) < 2 || len(service[1]) == 0 {
+				continue
+			}
+			// Prefer HTTPS when a service lists several URLs
+			url := service[1][0]
+			for _, u := range service[1] {
+				if strings.HasPrefix(u, "https://") {
+					url = u
+					break
+				}
+			}
+			for _, t := range service[0] {
+				bootstrap[strings.ToLower(t)] = url
+			}
+		}
+		c.mu.Lock()
+		c.bootstrap = bootstrap
+		c.mu.Unlock()
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if url, ok := c.bootstrap[tld]; ok {
+		return url, nil
+	}
+	return "", fmt.Errorf("no RDAP server for .%s", tld)
+}
+
+// GET a JSON document, requests to one server are spaced by the configured interval
+func (c *Client) get(url string, v interface{}) (int, error) {
+	host := url
+	if i := strings.Index(url, "://"); i >= 0 {
+		host = url[i+3:]
+	}
+	if i := strings.Index(host, "/"); i >= 0 {
+		host = host[:i]
+	}
+	c.wait(host)
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Accept", "application/rdap+json, application/json")
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return resp.StatusCode, fmt.Errorf("unexpected status %s from %s", resp.Status, url)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return resp.StatusCode, err
+	}
+	return resp.StatusCode, json.Unmarshal(body, v)
+}
+
+// Block until the server may be queried again
+func (c *Client) wait(host string) {
+	c.mu.Lock()
+	lock, ok := c.server[host]
+	if !ok {
+		lock = &sync.Mutex{}
+		c.server[host] = lock
+	}
+	c.mu.Unlock()
+
+	lock.Lock()
+	defer lock.Unlock()
+	c.mu.Lock()
+	next := c.last[host].Add(c.options.Interval)
+	c.mu.Unlock()
+	if d := time.Until(next); d > 0 {
+		time.Sleep(d)
+	}
+	c.mu.Lock()
+	c.last[host] = time.Now()
+	c.mu.Unlock()
+}
+
+// Parts of the RDAP domain object (RFC 9083) we use
+type domainResponse struct {
+	Status []string `json:"status"`
+	Events []struct {
+		Action string `json:"eventAction"`
+		Date   string `json:"eventDate"`
+	} `json:"events"`
+	Entities []entity `json:"entities"`
+}
+
+type entity struct {
+	Roles      []string          `json:"roles"`
+	VCardArray []json.RawMessage `json:"vcardArray"`
+	PublicIDs  []struct {
+		Type       string `json:"type"`
+		Identifier string `json:"identifier"`
+	} `json:"publicIds"`
+}
+
+func (d domainResponse) registration() Registration {
+	r := Registration{Status: d.Status}
+	for _, event := range d.Events {
+		t, err := time.Parse(time.RFC3339, event.Date)
+		if err != nil {
+			continue
+		}
+		switch event.Action {
+		case "expiration":
+			r.Expiration = t
+		case "registration":
+			r.Registered = t
+		}
+	}
+	for _, e := range d.Entities {
+		for _, role := range e.Roles {
+			if role == "registrar" {
+				r.Registrar = e.name()
+			}
+		}
+	}
+	return r
+}
+
+// Formatted name of the vCard, the IANA registrar ID when there is none
+func (e entity) name() string {
+	if len(e.VCardArray) == 2 {
+		var properties [][]json.RawMessage
+		if json.Unmarshal(e.VCardArray[1], &properties) == nil {
+			for _, property := range properties {
+				var name, value string
+				if len(property) == 4 && json.Unmarshal(property[0], &name) == nil && name == "fn" && json.Unmarshal(property[3], &value) == nil {
+					return value
+				}
+			}
+		}
+	}
+	for _, id := range e.PublicIDs {
+		if id.Type == "IANA Registrar ID" {
+			return "IANA Registrar ID " + id.Identifier
+		}
+	}
+	return ""
+}
@@ -0,0 +1,248 @@
+package rdap
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const domainJSON = `{
+	"objectClassName": "domain",
+	"ldhName": "EXAMPLE.COM",
+	"status": ["client transfer prohibited"],
+	"events": [
+		{"eventAction": "registration", "eventDate": "1995-08-14T04:00:00Z"},
+		{"eventAction": "expiration", "eventDate": "2030-08-13T04:00:00Z"}
+	],
+	"entities": [{
+		"roles": ["registrar"],
+		"vcardArray": ["vcard", [["version", {}, "text", "4.0"], ["fn", {}, "text", "Example Registrar, Inc."]]],
+		"publicIds": [{"type": "IANA Registrar ID", "identifier": "376"}]
+	}]
+}`
+
+// RDAP server and bootstrap registry in one, every request is recorded
+type rdapServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	requests []string
+	times    []time.Time
+}
+
+func newRDAPServer(t *testing.T) *rdapServer {
+	s := &rdapServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.requests = append(s.requests, r.URL.Path)
+		s.times = append(s.times, time.Now())
+		s.mu.Unlock()
+
+		switch r.URL.Path {
+		case "/dns.json":
+			fmt.Fprintf(w, `{"version": "1.0", "services": [
+				[["net"], ["http://other.invalid/"]],
+				[["com", "org"], ["http://plain.invalid/", "%s/rdap/"]]
+			]}`, strings.Replace(s.URL, "http://", "https://", 1))
+		case "/rdap/domain/example.com", "/rdap/domain/example.org":
+			w.Header().Set("Content-Type", "application/rdap+json")
+			fmt.Fprint(w, domainJSON)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *rdapServer) count(path string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for _, p := range s.requests {
+		if p == path {
+			n++
+		}
+	}
+	return n
+}
+
+func TestLookup(t *testing.T) {
+	server := newRDAPServer(t)
+	client := NewClient(Options{Servers: map[string]string{".COM": server.URL + "/rdap/"}})
+
+	// Case and the trailing dot are normalized, the TLD keys of the options as well
+	registration, err := client.Lookup("Example.COM.")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Registration{
+		Domain:     "example.com",
+		Server:     server.URL + "/rdap/",
+		Registrar:  "Example Registrar, Inc.",
+		Registered: time.Date(1995, 8, 14, 4, 0, 0, 0, time.UTC),
+		Expiration: time.Date(2030, 8, 13, 4, 0, 0, 0, time.UTC),
+	}
+	if registration.Domain != want.Domain || registration.Server != want.Server || registration.Registrar != want.Registrar ||
+		!registration.Registered.Equal(want.Registered) || !registration.Expiration.Equal(want.Expiration) {
+		t.Errorf("registration = %+v, want %+v", registration, want)
+	}
+	if len(registration.Status) != 1 || registration.Status[0] != "client transfer prohibited" {
+		t.Errorf("status = %q", registration.Status)
+	}
+	if server.count("/dns.json") != 0 {
+		t.Error("bootstrap registry was loaded for a configured server")
+	}
+}
+
+func TestBootstrap(t *testing.T) {
+	server := newRDAPServer(t)
+	client := NewClient(Options{Bootstrap: server.URL + "/dns.json"})
+
+	if _, err := client.serverFor("com"); err != nil {
+		t.Fatal(err)
+	}
+	// The HTTPS URL of a service is preferred over the one listed first
+	if url, _ := client.serverFor("org"); url != strings.Replace(server.URL, "http://", "https://", 1)+"/rdap/" {
+		t.Errorf("server of .org = %q, want the HTTPS URL", url)
+	}
+	if url, _ := client.serverFor("net"); url != "http://other.invalid/" {
+		t.Errorf("server of .net = %q", url)
+	}
+	if _, err := client.serverFor("dev"); err == nil {
+		t.Error("TLD missing from the registry resolved")
+	}
+	if n := server.count("/dns.json"); n != 1 {
+		t.Errorf("bootstrap registry fetched %d times, want once", n)
+	}
+}
+
+func TestBootstrapLookup(t *testing.T) {
+	server := newRDAPServer(t)
+	client := NewClient(Options{Bootstrap: server.URL + "/dns.json"})
+	// Resolve the TLD to the plain HTTP test server like the registry would
+	client.bootstrap = map[string]string{"com": server.URL + "/rdap/"}
+
+	registration, err := client.Lookup("example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if registration.Server != server.URL+"/rdap/" {
+		t.Errorf("server = %q", registration.Server)
+	}
+
+	unreachable := NewClient(Options{Bootstrap: server.URL + "/missing.json"})
+	if _, err := unreachable.Lookup("example.com"); err == nil || !strings.Contains(err.Error(), "bootstrap registry") {
+		t.Errorf("err = %v, want a bootstrap registry error", err)
+	}
+}
+
+func TestNotFound(t *testing.T) {
+	server := newRDAPServer(t)
+	client := NewClient(Options{Servers: map[string]string{"com": server.URL + "/rdap"}})
+
+	if _, err := client.Lookup("unregistered.com"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("err = %v, want ErrNotFound", err)
+	}
+	if _, err := client.Lookup("unregistered.com"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("cached err = %v, want ErrNotFound", err)
+	}
+	if n := server.count("/rdap/domain/unregistered.com"); n != 1 {
+		t.Errorf("server queried %d times, want the negative answer cached", n)
+	}
+}
+
+func TestCacheTTL(t *testing.T) {
+	server := newRDAPServer(t)
+	client := NewClient(Options{Servers: map[string]string{"com": server.URL + "/rdap/"}, CacheTTL: 48 * time.Hour})
+
+	client.Lookup("example.com")
+	client.Lookup("unregistered.com")
+	tests := []struct {
+		domain string
+		ttl    time.Duration
+	}{
+		{"example.com", 48 * time.Hour},
+		{"unregistered.com", negativeTTL},
+	}
+	for _, tt := range tests {
+		entry := client.cache[tt.domain]
+		if ttl := time.Until(entry.expires); ttl > tt.ttl || ttl < tt.ttl-time.Minute {
+			t.Errorf("%s cached for %s, want %s", tt.domain, ttl, tt.ttl)
+		}
+	}
+
+	// A short cache lifetime also bounds the negative cache
+	short := NewClient(Options{Servers: map[string]string{"com": server.URL + "/rdap/"}, CacheTTL: time.Minute})
+	short.Lookup("unregistered.com")
+	if ttl := time.Until(short.cache["unregistered.com"].expires); ttl > time.Minute {
+		t.Errorf("negative answer cached for %s, longer than the cache lifetime", ttl)
+	}
+
+	// Expired entries are looked up again
+	entry := client.cache["example.com"]
+	entry.expires = time.Now().Add(-time.Second)
+	client.cache["example.com"] = entry
+	client.Lookup("example.com")
+	if n := server.count("/rdap/domain/example.com"); n != 2 {
+		t.Errorf("server queried %d times, want a new query after expiry", n)
+	}
+}
+
+func TestInterval(t *testing.T) {
+	server := newRDAPServer(t)
+	interval := 150 * time.Millisecond
+	client := NewClient(Options{Servers: map[string]string{"com": server.URL + "/rdap/", "org": server.URL + "/rdap/"}, Interval: interval})
+
+	var wg sync.WaitGroup
+	for _, domain := range []string{"example.com", "example.org", "unregistered.com"} {
+		wg.Add(1)
+		go func(domain string) {
+			defer wg.Done()
+			client.Lookup(domain)
+		}(domain)
+	}
+	wg.Wait()
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	if len(server.times) != 3 {
+		t.Fatalf("%d requests, want 3", len(server.times))
+	}
+	for i := 1; i < len(server.times); i++ {
+		// Allow for clock granularity between the client and the handler
+		if gap := server.times[i].Sub(server.times[i-1]); gap < interval-10*time.Millisecond {
+			t.Errorf("requests %d and %d were %s apart, want at least %s", i-1, i, gap, interval)
+		}
+	}
+}
+
+func TestRegistrableDomain(t *testing.T) {
+	tests := []struct {
+		host string
+		want string
+		ok   bool
+	}{
+		{"www.example.com", "example.com", true},
+		{"WWW.Example.COM.", "example.com", true},
+		{"example.com", "example.com", true},
+		{"a.b.example.co.uk", "example.co.uk", true},
+		{"app.herokuapp.com", "", false},
+		{"user.github.io", "", false},
+		{"d111111abcdef8.cloudfront.net", "", false},
+		{"co.uk", "", false},
+		{"host.corp", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			got, ok := RegistrableDomain(tt.host)
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("RegistrableDomain(%q) = %q, %v, want %q, %v", tt.host, got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,235 @@
+package revocation
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Cache lifetime of a CRL without nextUpdate
+const defaultCRLTTL = time.Hour
+
+// Approximate memory of one revoked serial entry in the cache (map bucket, string header, time)
+const crlEntryOverhead = 64
+
+// Cache of verified CRLs, replaceable to change the memory bound
+var Cache = NewCRLCache(64 << 20)
+
+// CRL check result
+type CRLResult struct {
+	Status     string
+	URL        string
+	RevokedAt  time.Time
+	ThisUpdate time.Time
+	NextUpdate time.Time
+	Error      string
+}
+
+// Parsed and verified CRL, only the revoked serials are kept
+type crlEntry struct {
+	key        string
+	revoked    map[string]time.Time
+	thisUpdate time.Time
+	expires    time.Time
+	size       int64
+}
+
+// CRL was downloaded but is too big, unparsable or not signed by the issuer
+type invalidCRLError struct {
+	err error
+}
+
+func (e invalidCRLError) Error() string {
+	return e.err.Error()
+}
+
+// LRU cache of parsed CRLs bounded by approximate memory usage
+type CRLCache struct {
+	mu       sync.Mutex
+	maxBytes int64
+	size     int64
+	entries  map[string]*list.Element
+	lru      *list.List
+}
+
+// Create a CRL cache holding at most maxBytes of revoked serials
+func NewCRLCache(maxBytes int64) *CRLCache {
+	return &CRLCache{
+		maxBytes: maxBytes,
+		entries:  make(map[string]*list.Element),
+		lru:      list.New(),
+	}
+}
+
+// Check the certificate serial against the CRL distribution points signed by the issuer
+func CheckCRL(cert, issuer *x509.Certificate) CRLResult {
+	if len(cert.CRLDistributionPoints) == 0 {
+		return CRLResult{Status: StatusNotChecked, Error: "certificate has no CRL distribution point"}
+	}
+	if issuer == nil {
+		return CRLResult{Status: StatusNotChecked, Error: "issuer certificate not found"}
+	}
+
+	// Try every distribution point, the first verified CRL wins
+	result := CRLResult{Status: StatusUnreachable}
+	for _, url := range cert.CRLDistributionPoints {
+		result.URL = url
+		entry, err := Cache.load(url, issuer)
+		if err != nil {
+			// A CRL that was downloaded but cannot be trusted is not the same as a missing one
+			if errors.As(err, &invalidCRLError{}) {
+				result.Status = StatusUnknown
+			}
+			result.Error = err.Error()
+			continue
+		}
+
+		result = CRLResult{
+			Status:     StatusGood,
+			URL:        url,
+			ThisUpdate: entry.thisUpdate,
+			NextUpdate: entry.expires,
+		}
+		if revokedAt, ok := entry.revoked[cert.SerialNumber.String()]; ok {
+			// Revocation is final, a stale CRL still proves it
+			result.Status = StatusRevoked
+			result.RevokedAt = revokedAt
+		} else if time.Now().After(entry.expires) {
+			result.Status = StatusUnknown
+			result.Error = fmt.Sprintf("CRL from %s is stale, its next update was due %s", url, entry.expires.Format(time.RFC3339))
+			continue
+		}
+		return result
+	}
+	return result
+}
+
+// Size of the cached CRLs in bytes
+func (c *CRLCache) Size() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.size
+}
+
+// Get a cached CRL or download, verify and cache it
+func (c *CRLCache) load(url string, issuer *x509.Certificate) (*crlEntry, error) {
+	// The same URL can be served for different issuers, verification is bound to the issuer key
+	spki := sha256.Sum256(issuer.RawSubjectPublicKeyInfo)
+	key := url + "#" + hex.EncodeToString(spki[:])
+
+	if entry := c.get(key); entry != nil {
+		return entry, nil
+	}
+
+	entry, err := fetchCRL(url, issuer, c.maxBytes)
+	if err != nil {
+		return nil, err
+	}
+	entry.key = key
+	if time.Now().Before(entry.expires) {
+		c.put(entry)
+	}
+	return entry, nil
+}
+
+func (c *CRLCache) get(key string) *crlEntry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.entries[key]
+	if !ok {
+		return nil
+	}
+	entry := el.Value.(*crlEntry)
+	if time.Now().After(entry.expires) {
+		c.remove(el)
+		return nil
+	}
+	c.lru.MoveToFront(el)
+	return entry
+}
+
+func (c *CRLCache) put(entry *crlEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// The download is capped at the cache size but the index can still outgrow it
+	if entry.size > c.maxBytes {
+		return
+	}
+	if el, ok := c.entries[entry.key]; ok {
+		c.remove(el)
+	}
+	for c.size+entry.size > c.maxBytes && c.lru.Len() > 0 {
+		c.remove(c.lru.Back())
+	}
+	c.entries[entry.key] = c.lru.PushFront(entry)
+	c.size += entry.size
+}
+
+func (c *CRLCache) remove(el *list.Element) {
+	entry := c.lru.Remove(el).(*crlEntry)
+	delete(c.entries, entry.key)
+	c.size -= entry.size
+}
+
+// Download a CRL of at most limit bytes, verify its signature against the issuer and index the revoked serials
+func fetchCRL(url string, issuer *x509.Certificate, limit int64) (*crlEntry, error) {
+	resp, err := Client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s from %s", resp.Status, url)
+	}
+
+	// Memory stays bounded by the cache size, a bigger CRL is never read in full
+	if resp.ContentLength > limit {
+		return nil, invalidCRLError{fmt.Errorf("CRL from %s is larger than the %d bytes CRL cache", url, limit)}
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(body)) > limit {
+		return nil, invalidCRLError{fmt.Errorf("CRL from %s is larger than the %d bytes CRL cache", url, limit)}
+	}
+	if block, _ := pem.Decode(body); block != nil {
+		body = block.Bytes
+	}
+
+	crl, err := x509.ParseRevocationList(body)
+	if err != nil {
+		return nil, invalidCRLError{err}
+	}
+	if err := crl.CheckSignatureFrom(issuer); err != nil {
+		return nil, invalidCRLError{errors.New("CRL signature verification failed: " + err.Error())}
+	}
+
+	expires := crl.NextUpdate
+	if expires.IsZero() {
+		expires = time.Now().Add(defaultCRLTTL)
+	}
+
+	entry := &crlEntry{
+		revoked:    make(map[string]time.Time, len(crl.RevokedCertificateEntries)),
+		thisUpdate: crl.ThisUpdate,
+		expires:    expires,
+	}
+	for _, revoked := range crl.RevokedCertificateEntries {
+		serial := revoked.SerialNumber.String()
+		entry.revoked[serial] = revoked.RevocationTime
+		entry.size += int64(len(serial)) + crlEntryOverhead
+	}
+	return entry, nil
+}
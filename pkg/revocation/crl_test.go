@@ -0,0 +1,125 @@
+package revocation
+
+import (
+	"crypto/rand"
+	"crypto/x509"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func (ca testCA) crl(t *testing.T, nextUpdate time.Time, revoked ...int64) []byte {
+	t.Helper()
+	tmpl := &x509.RevocationList{
+		Number:     big.NewInt(1),
+		ThisUpdate: time.Now().Add(-2 * time.Hour),
+		NextUpdate: nextUpdate,
+	}
+	for _, serial := range revoked {
+		tmpl.RevokedCertificateEntries = append(tmpl.RevokedCertificateEntries, x509.RevocationListEntry{
+			SerialNumber:   big.NewInt(serial),
+			RevocationTime: time.Now().Add(-time.Hour),
+		})
+	}
+	der, err := x509.CreateRevocationList(rand.Reader, tmpl, ca.cert, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return der
+}
+
+// Local CRL distribution point counting its downloads
+func crlServer(t *testing.T, body []byte, hits *int) string {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*hits++
+		w.Write(body)
+	}))
+	t.Cleanup(srv.Close)
+	return srv.URL
+}
+
+func TestCheckCRL(t *testing.T) {
+	defer func(cache *CRLCache) { Cache = cache }(Cache)
+	ca := newTestCA(t)
+	other := newTestCA(t)
+	fresh := time.Now().Add(time.Hour)
+	stale := time.Now().Add(-time.Hour)
+
+	tests := []struct {
+		name   string
+		body   []byte
+		serial int64
+		status string
+		cached bool
+	}{
+		{"good", ca.crl(t, fresh, 7), 8, StatusGood, true},
+		{"revoked", ca.crl(t, fresh, 7), 7, StatusRevoked, true},
+		{"stale is not good", ca.crl(t, stale, 7), 8, StatusUnknown, false},
+		{"stale still proves revocation", ca.crl(t, stale, 7), 7, StatusRevoked, false},
+		{"foreign signature", other.crl(t, fresh), 8, StatusUnknown, false},
+		{"unparsable", []byte("not a CRL"), 8, StatusUnknown, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			Cache = NewCRLCache(1 << 20)
+			hits := 0
+			leaf := ca.issue(t, tt.serial, nil, []string{crlServer(t, tt.body, &hits)})
+
+			result := CheckCRL(leaf, ca.cert)
+			if result.Status != tt.status {
+				t.Fatalf("status = %q, want %q (error %q)", result.Status, tt.status, result.Error)
+			}
+			CheckCRL(leaf, ca.cert)
+			if cached := hits == 1; cached != tt.cached {
+				t.Errorf("downloads = %d, want cached=%v", hits, tt.cached)
+			}
+		})
+	}
+}
+
+func TestCheckCRLSizeLimit(t *testing.T) {
+	defer func(cache *CRLCache) { Cache = cache }(Cache)
+	ca := newTestCA(t)
+	body := ca.crl(t, time.Now().Add(time.Hour), 1, 2, 3, 4, 5, 6, 7, 8, 9, 10)
+	hits := 0
+	leaf := ca.issue(t, 11, nil, []string{crlServer(t, body, &hits)})
+
+	Cache = NewCRLCache(int64(len(body)) - 1)
+	result := CheckCRL(leaf, ca.cert)
+	if result.Status != StatusUnknown || !strings.Contains(result.Error, "larger than") {
+		t.Errorf("oversized CRL: got %q (%q), want unknown", result.Status, result.Error)
+	}
+
+	Cache = NewCRLCache(int64(len(body)))
+	if result := CheckCRL(leaf, ca.cert); result.Status != StatusGood {
+		t.Errorf("CRL within the limit: got %q (%q), want good", result.Status, result.Error)
+	}
+	if Cache.Size() > int64(len(body)) {
+		t.Errorf("cache size %d exceeds the bound %d", Cache.Size(), len(body))
+	}
+}
+
+func TestCRLCacheEviction(t *testing.T) {
+	cache := NewCRLCache(100)
+	for _, key := range []string{"a", "b", "c"} {
+		cache.put(&crlEntry{key: key, expires: time.Now().Add(time.Hour), size: 40})
+	}
+	if cache.Size() != 80 {
+		t.Errorf("size = %d, want 80", cache.Size())
+	}
+	if cache.get("a") != nil {
+		t.Error("least recently used entry was not evicted")
+	}
+	if cache.get("b") == nil || cache.get("c") == nil {
+		t.Error("recent entries were evicted")
+	}
+
+	cache.put(&crlEntry{key: "big", expires: time.Now().Add(time.Hour), size: 101})
+	if cache.get("big") != nil {
+		t.Error("entry bigger than the cache was stored")
+	}
+}
@@ -0,0 +1,200 @@
+package revocation
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
+)
+
+// Revocation states
+const (
+	StatusGood        = "good"
+	StatusRevoked     = "revoked"
+	StatusUnknown     = "unknown"
+	StatusUnreachable = "unreachable"
+	StatusError       = "error" // responder answered with an error status or an unparsable response
+	StatusNotChecked  = "not_checked"
+)
+
+// Maximum size of an OCSP response or issuer certificate download
+const maxResponseSize = 1 << 20
+
+// HTTP client used for responder and AIA requests, replaceable for local responders
+var Client = &http.Client{Timeout: 10 * time.Second}
+
+// OCSP responder check result
+type OCSPResult struct {
+	Status           string
+	Responder        string
+	RevokedAt        time.Time
+	RevocationReason int
+	ThisUpdate       time.Time
+	NextUpdate       time.Time
+	Error            string
+}
+
+// Stapled OCSP response result
+type StapleResult struct {
+	Stapled    bool
+	Fresh      bool
+	Status     string
+	ThisUpdate time.Time
+	NextUpdate time.Time
+	Error      string
+}
+
+// Check leaf revocation through the OCSP responders of the AIA extension
+func CheckOCSP(leaf, issuer *x509.Certificate) OCSPResult {
+	if len(leaf.OCSPServer) == 0 {
+		return OCSPResult{Status: StatusNotChecked, Error: "certificate has no OCSP responder"}
+	}
+	if issuer == nil {
+		return OCSPResult{Status: StatusNotChecked, Error: "issuer certificate not found"}
+	}
+
+	req, err := ocsp.CreateRequest(leaf, issuer, nil)
+	if err != nil {
+		return OCSPResult{Status: StatusNotChecked, Error: err.Error()}
+	}
+
+	// Try every responder, the first parsable answer wins
+	result := OCSPResult{Status: StatusUnreachable}
+	for _, responder := range leaf.OCSPServer {
+		raw, err := postOCSP(responder, req)
+		if err != nil {
+			// An answering responder is more telling than a later unreachable one
+			if result.Status == StatusUnreachable {
+				result.Responder = responder
+				result.Error = err.Error()
+			}
+			continue
+		}
+
+		// tryLater, unauthorized and malformed answers are errors, not an unknown certificate
+		resp, err := ocsp.ParseResponseForCert(raw, leaf, issuer)
+		if err != nil {
+			result = OCSPResult{Status: StatusError, Responder: responder, Error: err.Error()}
+			continue
+		}
+		return responseToResult(resp, responder)
+	}
+	return result
+}
+
+// Check the OCSP response stapled by the server during the handshake
+func CheckStaple(raw []byte, leaf, issuer *x509.Certificate) StapleResult {
+	if len(raw) == 0 {
+		return StapleResult{Status: StatusNotChecked}
+	}
+	if issuer == nil {
+		return StapleResult{Stapled: true, Status: StatusNotChecked, Error: "issuer certificate not found"}
+	}
+
+	resp, err := ocsp.ParseResponseForCert(raw, leaf, issuer)
+	if err != nil {
+		return StapleResult{Stapled: true, Status: StatusError, Error: err.Error()}
+	}
+
+	now := time.Now()
+	return StapleResult{
+		Stapled:    true,
+		Fresh:      !resp.ThisUpdate.After(now) && (resp.NextUpdate.IsZero() || now.Before(resp.NextUpdate)),
+		Status:     statusName(resp.Status),
+		ThisUpdate: resp.ThisUpdate,
+		NextUpdate: resp.NextUpdate,
+	}
+}
+
+// Find the issuer of the leaf in the chains, fall back to the AIA CA Issuers URL
+func FindIssuer(leaf *x509.Certificate, chains ...[]*x509.Certificate) *x509.Certificate {
+	for _, chain := range chains {
+		for _, cert := range chain {
+			if cert != leaf && leaf.CheckSignatureFrom(cert) == nil {
+				return cert
+			}
+		}
+	}
+
+	for _, url := range leaf.IssuingCertificateURL {
+		cert, err := FetchCertificate(url)
+		if err == nil && leaf.CheckSignatureFrom(cert) == nil {
+			return cert
+		}
+	}
+	return nil
+}
+
+// Download a DER or PEM encoded certificate
+func FetchCertificate(url string) (*x509.Certificate, error) {
+	resp, err := Client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s from %s", resp.Status, url)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return nil, err
+	}
+	if block, _ := pem.Decode(body); block != nil {
+		body = block.Bytes
+	}
+	return x509.ParseCertificate(body)
+}
+
+func postOCSP(responder string, req []byte) ([]byte, error) {
+	resp, err := Client.Post(responder, "application/ocsp-request", bytes.NewReader(req))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s from %s", resp.Status, responder)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return nil, err
+	}
+	if len(body) == 0 {
+		return nil, errors.New("empty OCSP response from " + responder)
+	}
+	return body, nil
+}
+
+func responseToResult(resp *ocsp.Response, responder string) OCSPResult {
+	result := OCSPResult{
+		Status:     statusName(resp.Status),
+		Responder:  responder,
+		ThisUpdate: resp.ThisUpdate,
+		NextUpdate: resp.NextUpdate,
+	}
+	if resp.Status == ocsp.Revoked {
+		result.RevokedAt = resp.RevokedAt
+		result.RevocationReason = resp.RevocationReason
+	}
+	return result
+}
+
+func statusName(status int) string {
+	switch status {
+	case ocsp.Good:
+		return StatusGood
+	case ocsp.Revoked:
+		return StatusRevoked
+	default:
+		return StatusUnknown
+	}
+}
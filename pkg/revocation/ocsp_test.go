@@ -0,0 +1,236 @@
+package revocation
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
+)
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newTestCA(t *testing.T) testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return testCA{cert: cert, key: key}
+}
+
+// Issue a leaf pointing at the given OCSP responders and CRL distribution points
+func (ca testCA) issue(t *testing.T, serial int64, ocspServers, crlURLs []string) *x509.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(serial),
+		Subject:               pkix.Name{CommonName: "leaf.example"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		OCSPServer:            ocspServers,
+		CRLDistributionPoints: crlURLs,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert
+}
+
+func (ca testCA) ocspResponse(t *testing.T, leaf *x509.Certificate, status int) []byte {
+	t.Helper()
+	tmpl := ocsp.Response{
+		Status:       status,
+		SerialNumber: leaf.SerialNumber,
+		ThisUpdate:   time.Now().Add(-time.Minute),
+		NextUpdate:   time.Now().Add(time.Hour),
+	}
+	if status == ocsp.Revoked {
+		tmpl.RevokedAt = time.Now().Add(-time.Hour).UTC().Truncate(time.Second)
+		tmpl.RevocationReason = ocsp.KeyCompromise
+	}
+	raw, err := ocsp.CreateResponse(ca.cert, ca.cert, tmpl, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return raw
+}
+
+// Local OCSP responder answering every request with the given body
+func ocspResponder(t *testing.T, body []byte) string {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		raw, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Error(err)
+		}
+		if _, err := ocsp.ParseRequest(raw); err != nil {
+			t.Errorf("responder got an invalid request: %v", err)
+		}
+		w.Header().Set("Content-Type", "application/ocsp-response")
+		w.Write(body)
+	}))
+	t.Cleanup(srv.Close)
+	return srv.URL
+}
+
+// Address nothing listens on
+func closedURL(t *testing.T) string {
+	t.Helper()
+	srv := httptest.NewServer(http.NotFoundHandler())
+	srv.Close()
+	return srv.URL
+}
+
+func TestCheckOCSP(t *testing.T) {
+	ca := newTestCA(t)
+	probe := ca.issue(t, 2, nil, nil)
+
+	tests := []struct {
+		name   string
+		body   []byte
+		status string
+	}{
+		{"good", ca.ocspResponse(t, probe, ocsp.Good), StatusGood},
+		{"revoked", ca.ocspResponse(t, probe, ocsp.Revoked), StatusRevoked},
+		{"unknown", ca.ocspResponse(t, probe, ocsp.Unknown), StatusUnknown},
+		{"tryLater", ocsp.TryLaterErrorResponse, StatusError},
+		{"unauthorized", ocsp.UnauthorizedErrorResponse, StatusError},
+		{"malformed", []byte("not an OCSP response"), StatusError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			url := ocspResponder(t, tt.body)
+			leaf := ca.issue(t, 2, []string{url}, nil)
+
+			result := CheckOCSP(leaf, ca.cert)
+			if result.Status != tt.status {
+				t.Fatalf("status = %q, want %q (error %q)", result.Status, tt.status, result.Error)
+			}
+			if result.Responder != url {
+				t.Errorf("responder = %q, want %q", result.Responder, url)
+			}
+			if tt.status == StatusError && result.Error == "" {
+				t.Error("error status without an error message")
+			}
+			if tt.status == StatusRevoked && (result.RevokedAt.IsZero() || result.RevocationReason != ocsp.KeyCompromise) {
+				t.Errorf("revocation details missing: %+v", result)
+			}
+		})
+	}
+}
+
+func TestCheckOCSPResponders(t *testing.T) {
+	ca := newTestCA(t)
+	probe := ca.issue(t, 3, nil, nil)
+	good := ocspResponder(t, ca.ocspResponse(t, probe, ocsp.Good))
+	tryLater := ocspResponder(t, ocsp.TryLaterErrorResponse)
+	down := closedURL(t)
+
+	tests := []struct {
+		name       string
+		responders []string
+		status     string
+	}{
+		{"falls back to the next responder", []string{down, good}, StatusGood},
+		{"next responder answers after an error", []string{tryLater, good}, StatusGood},
+		{"error is kept over unreachable", []string{tryLater, down}, StatusError},
+		{"unreachable then error", []string{down, tryLater}, StatusError},
+		{"all unreachable", []string{down}, StatusUnreachable},
+		{"no responder", nil, StatusNotChecked},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			leaf := ca.issue(t, 3, tt.responders, nil)
+			if result := CheckOCSP(leaf, ca.cert); result.Status != tt.status {
+				t.Errorf("status = %q, want %q (error %q)", result.Status, tt.status, result.Error)
+			}
+		})
+	}
+
+	leaf := ca.issue(t, 3, []string{good}, nil)
+	if result := CheckOCSP(leaf, nil); result.Status != StatusNotChecked {
+		t.Errorf("missing issuer: status = %q, want %q", result.Status, StatusNotChecked)
+	}
+}
+
+func TestCheckStaple(t *testing.T) {
+	ca := newTestCA(t)
+	leaf := ca.issue(t, 4, nil, nil)
+
+	tests := []struct {
+		name    string
+		raw     []byte
+		stapled bool
+		fresh   bool
+		status  string
+	}{
+		{"none", nil, false, false, StatusNotChecked},
+		{"good", ca.ocspResponse(t, leaf, ocsp.Good), true, true, StatusGood},
+		{"revoked", ca.ocspResponse(t, leaf, ocsp.Revoked), true, true, StatusRevoked},
+		{"tryLater", ocsp.TryLaterErrorResponse, true, false, StatusError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := CheckStaple(tt.raw, leaf, ca.cert)
+			if result.Stapled != tt.stapled || result.Fresh != tt.fresh || result.Status != tt.status {
+				t.Errorf("got %+v, want stapled=%v fresh=%v status=%q", result, tt.stapled, tt.fresh, tt.status)
+			}
+		})
+	}
+
+	// A response for another certificate must not be accepted
+	other := ca.issue(t, 5, nil, nil)
+	if result := CheckStaple(ca.ocspResponse(t, other, ocsp.Good), leaf, ca.cert); result.Status != StatusError {
+		t.Errorf("foreign staple: status = %q, want %q", result.Status, StatusError)
+	}
+}
+
+func TestFindIssuer(t *testing.T) {
+	ca := newTestCA(t)
+	other := newTestCA(t)
+	leaf := ca.issue(t, 6, nil, nil)
+
+	if got := FindIssuer(leaf, []*x509.Certificate{leaf, other.cert, ca.cert}); got != ca.cert {
+		t.Errorf("issuer from chain = %v, want the test CA", got)
+	}
+	if got := FindIssuer(leaf, []*x509.Certificate{leaf, other.cert}); got != nil {
+		t.Errorf("issuer without CA = %v, want nil", got.Subject)
+	}
+}
@@ -0,0 +1,80 @@
+package sshcert
+
+import (
+	"errors"
+	"net"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// Certificate types
+const (
+	TypeUser = "user"
+	TypeHost = "host"
+)
+
+// Certificate host key algorithms are offered first so servers with a host certificate present it
+var hostKeyAlgorithms = []string{
+	ssh.CertAlgoED25519v01, ssh.CertAlgoECDSA256v01, ssh.CertAlgoECDSA384v01, ssh.CertAlgoECDSA521v01,
+	ssh.CertAlgoRSASHA512v01, ssh.CertAlgoRSASHA256v01, ssh.CertAlgoRSAv01,
+	ssh.KeyAlgoED25519, ssh.KeyAlgoECDSA256, ssh.KeyAlgoECDSA384, ssh.KeyAlgoECDSA521,
+	ssh.KeyAlgoRSASHA512, ssh.KeyAlgoRSASHA256, ssh.KeyAlgoRSA,
+}
+
+// Returned by the host key callback to stop before authentication
+var errHostKeyCaptured = errors.New("host key captured")
+
+// Parse an OpenSSH certificate in authorized_keys format like id_ed25519-cert.pub
+func Parse(data []byte) (*ssh.Certificate, error) {
+	key, _, _, _, err := ssh.ParseAuthorizedKey(data)
+	if err != nil {
+		return nil, err
+	}
+	cert, ok := key.(*ssh.Certificate)
+	if !ok {
+		return nil, errors.New("public key is not a certificate")
+	}
+	return cert, nil
+}
+
+// Run the key exchange on conn and return the host key, which is a certificate when the server has one
+func FetchHostKey(conn net.Conn, address string, timeout time.Duration) (ssh.PublicKey, error) {
+	conn.SetDeadline(time.Now().Add(timeout))
+	defer conn.SetDeadline(time.Time{})
+
+	var hostKey ssh.PublicKey
+	_, _, _, err := ssh.NewClientConn(conn, address, &ssh.ClientConfig{
+		User:              "sentinel",
+		HostKeyAlgorithms: hostKeyAlgorithms,
+		HostKeyCallback: func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+			hostKey = key
+			return errHostKeyCaptured
+		},
+		Timeout: timeout,
+	})
+	if hostKey != nil {
+		return hostKey, nil
+	}
+	return nil, err
+}
+
+// user or host
+func CertType(cert *ssh.Certificate) string {
+	if cert.CertType == ssh.HostCert {
+		return TypeHost
+	}
+	return TypeUser
+}
+
+// Validity period, a zero time means the certificate has no bound on that side
+func Validity(cert *ssh.Certificate) (time.Time, time.Time) {
+	var after, before time.Time
+	if cert.ValidAfter != 0 {
+		after = time.Unix(int64(cert.ValidAfter), 0)
+	}
+	if cert.ValidBefore != ssh.CertTimeInfinity && cert.ValidBefore < 1<<63 {
+		before = time.Unix(int64(cert.ValidBefore), 0)
+	}
+	return after, before
+}
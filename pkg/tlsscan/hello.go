@@ -0,0 +1,340 @@
+package tlsscan
+
+import (
+	"bytes"
+	"crypto/ecdh"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+)
+
+// Record and handshake types
+const (
+	recordHandshake = 22
+	recordAlert     = 21
+
+	handshakeClientHello = 1
+	handshakeServerHello = 2
+)
+
+// Extensions
+const (
+	extServerName          = 0
+	extSupportedGroups     = 10
+	extECPointFormats      = 11
+	extSignatureAlgorithms = 13
+	extExtendedMaster      = 23
+	extSupportedVersions   = 43
+	extKeyShare            = 51
+	extRenegotiationInfo   = 0xff01
+)
+
+// ServerHello.random of a HelloRetryRequest (RFC 8446, 4.1.3)
+var helloRetryRandom = []byte{
+	0xcf, 0x21, 0xad, 0x74, 0xe5, 0x9a, 0x61, 0x11, 0xbe, 0x1d, 0x8c, 0x02, 0x1e, 0x65, 0xb8, 0x91,
+	0xc2, 0xa2, 0x11, 0x16, 0x7a, 0xbb, 0x8c, 0x5e, 0x07, 0x9e, 0x09, 0xe2, 0xc8, 0xa8, 0x33, 0x9c,
+}
+
+var signatureAlgorithms = []uint16{
+	0x0403, 0x0503, 0x0603, 0x0804, 0x0805, 0x0806, 0x0807, 0x0808,
+	0x0401, 0x0501, 0x0601, 0x0203, 0x0201,
+}
+
+// Maximum size of a handshake message we are willing to buffer
+const maxHandshakeSize = 1 << 16
+
+// Server refused the hello with an alert
+var errAlert = errors.New("handshake alert")
+
+// Hello parameters of a single probe
+type hello struct {
+	version             uint16
+	versions            []uint16 // supported_versions of a TLS 1.3 hello, default: TLS 1.3 only
+	suites              []uint16
+	groups              []uint16
+	keyShares           []uint16 // groups to send a key share for, TLS 1.3 only
+	signatureAlgorithms []uint16 // default: every algorithm we know
+	serverName          string
+}
+
+// Interesting parts of the ServerHello
+type serverHello struct {
+	version    uint16
+	suite      uint16
+	group      uint16
+	retry      bool
+	extensions []uint16
+}
+
+// Send a ClientHello and read the ServerHello, the connection is left to the caller
+func (h hello) do(conn net.Conn) (*serverHello, error) {
+	msg, err := h.marshal()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := conn.Write(msg); err != nil {
+		return nil, err
+	}
+	return readServerHello(conn)
+}
+
+func (h hello) marshal() ([]byte, error) {
+	tls13 := h.version >= VersionTLS13
+
+	var body bytes.Buffer
+	legacyVersion := h.version
+	if tls13 {
+		legacyVersion = VersionTLS12
+	}
+	writeUint16(&body, legacyVersion)
+
+	random := make([]byte, 32)
+	rand.Read(random)
+	body.Write(random)
+
+	// Session ID, TLS 1.3 middlebox compatibility mode needs a non empty one
+	if tls13 {
+		sessionID := make([]byte, 32)
+		rand.Read(sessionID)
+		body.WriteByte(32)
+		body.Write(sessionID)
+	} else {
+		body.WriteByte(0)
+	}
+
+	writeUint16(&body, uint16(len(h.suites)*2))
+	for _, suite := range h.suites {
+		writeUint16(&body, suite)
+	}
+	body.Write([]byte{1, 0}) // null compression
+
+	if h.version > VersionSSL30 {
+		extensions, err := h.marshalExtensions(tls13)
+		if err != nil {
+			return nil, err
+		}
+		writeUint16(&body, uint16(len(extensions)))
+		body.Write(extensions)
+	}
+
+	var handshake bytes.Buffer
+	handshake.WriteByte(handshakeClientHello)
+	writeUint24(&handshake, body.Len())
+	handshake.Write(body.Bytes())
+
+	var record bytes.Buffer
+	record.WriteByte(recordHandshake)
+	recordVersion := h.version
+	if recordVersion > VersionTLS10 {
+		recordVersion = VersionTLS10
+	}
+	writeUint16(&record, recordVersion)
+	writeUint16(&record, uint16(handshake.Len()))
+	record.Write(handshake.Bytes())
+	return record.Bytes(), nil
+}
+
+func (h hello) marshalExtensions(tls13 bool) ([]byte, error) {
+	var ext bytes.Buffer
+
+	if h.serverName != "" && net.ParseIP(h.serverName) == nil {
+		name := []byte(h.serverName)
+		var sni bytes.Buffer
+		writeUint16(&sni, uint16(len(name)+3))
+		sni.WriteByte(0) // host_name
+		writeUint16(&sni, uint16(len(name)))
+		sni.Write(name)
+		writeExtension(&ext, extServerName, sni.Bytes())
+	}
+
+	var groups bytes.Buffer
+	writeUint16(&groups, uint16(len(h.groups)*2))
+	for _, group := range h.groups {
+		writeUint16(&groups, group)
+	}
+	writeExtension(&ext, extSupportedGroups, groups.Bytes())
+	writeExtension(&ext, extECPointFormats, []byte{1, 0})
+
+	algorithmList := h.signatureAlgorithms
+	if algorithmList == nil {
+		algorithmList = signatureAlgorithms
+	}
+	var algorithms bytes.Buffer
+	writeUint16(&algorithms, uint16(len(algorithmList)*2))
+	for _, alg := range algorithmList {
+		writeUint16(&algorithms, alg)
+	}
+	writeExtension(&ext, extSignatureAlgorithms, algorithms.Bytes())
+	writeExtension(&ext, extExtendedMaster, nil)
+	writeExtension(&ext, extRenegotiationInfo, []byte{0})
+
+	if tls13 {
+		versionList := h.versions
+		if len(versionList) == 0 {
+			versionList = []uint16{VersionTLS13}
+		}
+		var versions bytes.Buffer
+		versions.WriteByte(byte(len(versionList) * 2))
+		for _, version := range versionList {
+			writeUint16(&versions, version)
+		}
+		writeExtension(&ext, extSupportedVersions, versions.Bytes())
+
+		var shares bytes.Buffer
+		for _, group := range h.keyShares {
+			key, err := keyShare(group)
+			if err != nil {
+				return nil, err
+			}
+			writeUint16(&shares, group)
+			writeUint16(&shares, uint16(len(key)))
+			shares.Write(key)
+		}
+		var keyShares bytes.Buffer
+		writeUint16(&keyShares, uint16(shares.Len()))
+		keyShares.Write(shares.Bytes())
+		writeExtension(&ext, extKeyShare, keyShares.Bytes())
+	}
+	return ext.Bytes(), nil
+}
+
+// Fresh public key for a key share, only ECDH groups are supported
+func keyShare(group uint16) ([]byte, error) {
+	var curve ecdh.Curve
+	switch group {
+	case GroupX25519:
+		curve = ecdh.X25519()
+	case GroupSecp256r1:
+		curve = ecdh.P256()
+	case GroupSecp384r1:
+		curve = ecdh.P384()
+	case GroupSecp521r1:
+		curve = ecdh.P521()
+	default:
+		return nil, fmt.Errorf("no key share for group %s", GroupName(group))
+	}
+	key, err := curve.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	return key.PublicKey().Bytes(), nil
+}
+
+// Can a key share be generated for the group
+func hasKeyShare(group uint16) bool {
+	switch group {
+	case GroupX25519, GroupSecp256r1, GroupSecp384r1, GroupSecp521r1:
+		return true
+	}
+	return false
+}
+
+func readServerHello(r io.Reader) (*serverHello, error) {
+	var handshake []byte
+	for {
+		header := make([]byte, 5)
+		if _, err := io.ReadFull(r, header); err != nil {
+			return nil, err
+		}
+		length := int(binary.BigEndian.Uint16(header[3:]))
+		payload := make([]byte, length)
+		if _, err := io.ReadFull(r, payload); err != nil {
+			return nil, err
+		}
+
+		switch header[0] {
+		case recordAlert:
+			return nil, errAlert
+		case recordHandshake:
+			handshake = append(handshake, payload...)
+		default:
+			return nil, fmt.Errorf("unexpected record type %d", header[0])
+		}
+
+		if len(handshake) > maxHandshakeSize {
+			return nil, errors.New("handshake message too large")
+		}
+		if len(handshake) >= 4 {
+			size := int(handshake[1])<<16 | int(handshake[2])<<8 | int(handshake[3])
+			if len(handshake) >= 4+size {
+				if handshake[0] != handshakeServerHello {
+					return nil, fmt.Errorf("unexpected handshake type %d", handshake[0])
+				}
+				return parseServerHello(handshake[4 : 4+size])
+			}
+		}
+	}
+}
+
+func parseServerHello(b []byte) (*serverHello, error) {
+	errShort := errors.New("malformed ServerHello")
+	if len(b) < 38 {
+		return nil, errShort
+	}
+
+	sh := &serverHello{version: binary.BigEndian.Uint16(b)}
+	sh.retry = bytes.Equal(b[2:34], helloRetryRandom)
+	b = b[34:]
+
+	sessionLength := int(b[0])
+	if len(b) < 1+sessionLength+3 {
+		return nil, errShort
+	}
+	b = b[1+sessionLength:]
+	sh.suite = binary.BigEndian.Uint16(b)
+	b = b[3:] // suite and compression method
+
+	if len(b) < 2 {
+		return sh, nil // no extensions
+	}
+	extLength := int(binary.BigEndian.Uint16(b))
+	b = b[2:]
+	if len(b) < extLength {
+		return nil, errShort
+	}
+	b = b[:extLength]
+
+	for len(b) >= 4 {
+		extType := binary.BigEndian.Uint16(b)
+		length := int(binary.BigEndian.Uint16(b[2:]))
+		if len(b) < 4+length {
+			return nil, errShort
+		}
+		data := b[4 : 4+length]
+		sh.extensions = append(sh.extensions, extType)
+
+		switch extType {
+		case extSupportedVersions:
+			if len(data) >= 2 {
+				sh.version = binary.BigEndian.Uint16(data)
+			}
+		case extKeyShare:
+			// ServerHello carries a KeyShareEntry, HelloRetryRequest only the selected group
+			if len(data) >= 2 {
+				sh.group = binary.BigEndian.Uint16(data)
+			}
+		}
+		b = b[4+length:]
+	}
+	return sh, nil
+}
+
+func writeExtension(b *bytes.Buffer, extType uint16, data []byte) {
+	writeUint16(b, extType)
+	writeUint16(b, uint16(len(data)))
+	b.Write(data)
+}
+
+func writeUint16(b *bytes.Buffer, v uint16) {
+	b.WriteByte(byte(v >> 8))
+	b.WriteByte(byte(v))
+}
+
+func writeUint24(b *bytes.Buffer, v int) {
+	b.WriteByte(byte(v >> 16))
+	b.WriteByte(byte(v >> 8))
+	b.WriteByte(byte(v))
+}
@@ -0,0 +1,185 @@
+package tlsscan
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+)
+
+// ServerHello body with the given random, suite and extensions
+func serverHelloBody(version uint16, random []byte, suite uint16, extensions []byte) []byte {
+	var b bytes.Buffer
+	writeUint16(&b, version)
+	b.Write(random)
+	b.WriteByte(32)
+	b.Write(make([]byte, 32)) // session ID
+	writeUint16(&b, suite)
+	b.WriteByte(0) // compression
+	if extensions != nil {
+		writeUint16(&b, uint16(len(extensions)))
+		b.Write(extensions)
+	}
+	return b.Bytes()
+}
+
+func extension(extType uint16, data ...byte) []byte {
+	var b bytes.Buffer
+	writeExtension(&b, extType, data)
+	return b.Bytes()
+}
+
+// Handshake message in records of at most fragment bytes
+func records(body []byte, fragment int) []byte {
+	var handshake bytes.Buffer
+	handshake.WriteByte(handshakeServerHello)
+	writeUint24(&handshake, len(body))
+	handshake.Write(body)
+
+	var out bytes.Buffer
+	msg := handshake.Bytes()
+	for len(msg) > 0 {
+		n := fragment
+		if n > len(msg) {
+			n = len(msg)
+		}
+		out.WriteByte(recordHandshake)
+		writeUint16(&out, VersionTLS12)
+		writeUint16(&out, uint16(n))
+		out.Write(msg[:n])
+		msg = msg[n:]
+	}
+	return out.Bytes()
+}
+
+func TestParseServerHello(t *testing.T) {
+	random := bytes.Repeat([]byte{7}, 32)
+	tls13 := append(extension(extSupportedVersions, 0x03, 0x04), extension(extKeyShare, 0x00, 0x1d, 0x00, 0x02, 0xaa, 0xbb)...)
+
+	tests := []struct {
+		name       string
+		body       []byte
+		want       serverHello
+		extensions int
+		wantErr    bool
+	}{
+		{"TLS 1.2 without extensions", serverHelloBody(VersionTLS12, random, 0xc02f, nil), serverHello{version: VersionTLS12, suite: 0xc02f}, 0, false},
+		{"TLS 1.2 with renegotiation info", serverHelloBody(VersionTLS12, random, 0xc02f, extension(extRenegotiationInfo, 0)), serverHello{version: VersionTLS12, suite: 0xc02f}, 1, false},
+		{"TLS 1.3", serverHelloBody(VersionTLS12, random, 0x1301, tls13), serverHello{version: VersionTLS13, suite: 0x1301, group: GroupX25519}, 2, false},
+		{"HelloRetryRequest", serverHelloBody(VersionTLS12, helloRetryRandom, 0x1302, append(extension(extSupportedVersions, 0x03, 0x04), extension(extKeyShare, 0x00, 0x17)...)),
+			serverHello{version: VersionTLS13, suite: 0x1302, group: GroupSecp256r1, retry: true}, 2, false},
+		{"too short", make([]byte, 37), serverHello{}, 0, true},
+		{"session ID past the end", append(serverHelloBody(VersionTLS12, random, 0xc02f, nil)[:34], 32, 0, 0, 0, 0, 0), serverHello{}, 0, true},
+		{"extensions past the end", serverHelloBody(VersionTLS12, random, 0xc02f, tls13)[:80], serverHello{}, 0, true},
+		{"extension past the end", serverHelloBody(VersionTLS12, random, 0xc02f, []byte{0x00, 0x2b, 0x00, 0x09, 0x03}), serverHello{}, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sh, err := parseServerHello(tt.body)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if sh.version != tt.want.version || sh.suite != tt.want.suite || sh.group != tt.want.group || sh.retry != tt.want.retry {
+				t.Errorf("got %+v, want %+v", *sh, tt.want)
+			}
+			if len(sh.extensions) != tt.extensions {
+				t.Errorf("extensions = %v, want %d", sh.extensions, tt.extensions)
+			}
+		})
+	}
+}
+
+func TestReadServerHello(t *testing.T) {
+	body := serverHelloBody(VersionTLS12, bytes.Repeat([]byte{1}, 32), 0xc030, extension(extExtendedMaster))
+	alert := []byte{recordAlert, 0x03, 0x03, 0x00, 0x02, 0x02, 0x28}
+	clientHello := records(body, 1000)
+	clientHello[5] = handshakeClientHello
+
+	tests := []struct {
+		name    string
+		data    []byte
+		wantErr bool
+	}{
+		{"single record", records(body, 1000), false},
+		{"fragmented over records", records(body, 10), false},
+		{"alert", alert, true},
+		{"unexpected record type", append([]byte{23, 0x03, 0x03, 0x00, 0x01}, 0), true},
+		{"unexpected handshake type", clientHello, true},
+		{"truncated", records(body, 1000)[:40], true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sh, err := readServerHello(bytes.NewReader(tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && sh.suite != 0xc030 {
+				t.Errorf("suite = %#04x, want 0xc030", sh.suite)
+			}
+		})
+	}
+	if _, err := readServerHello(bytes.NewReader(alert)); err != errAlert {
+		t.Errorf("alert err = %v, want errAlert", err)
+	}
+}
+
+// The hello we marshal must be accepted by a real TLS stack
+func TestHelloHandshake(t *testing.T) {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := &tls.Config{Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}}}
+
+	tests := []struct {
+		name  string
+		hello hello
+		want  serverHello
+	}{
+		{"TLS 1.2", hello{version: VersionTLS12, suites: []uint16{0xc02b}, groups: []uint16{GroupSecp256r1}, serverName: "localhost"},
+			serverHello{version: VersionTLS12, suite: 0xc02b}},
+		{"TLS 1.3", hello{version: VersionTLS13, suites: []uint16{0x1301}, groups: []uint16{GroupX25519}, keyShares: []uint16{GroupX25519}, serverName: "localhost"},
+			serverHello{version: VersionTLS13, suite: 0x1301, group: GroupX25519}},
+		{"TLS 1.3 retry", hello{version: VersionTLS13, suites: []uint16{0x1301}, groups: []uint16{GroupSecp384r1, GroupX25519}, keyShares: []uint16{GroupSecp384r1}, serverName: "localhost"},
+			serverHello{version: VersionTLS13, suite: 0x1301, group: GroupX25519, retry: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, server := net.Pipe()
+			defer client.Close()
+			serverConfig := config.Clone()
+			serverConfig.CurvePreferences = []tls.CurveID{tls.X25519, tls.CurveP256}
+			go func() {
+				tls.Server(server, serverConfig).Handshake()
+				server.Close()
+			}()
+
+			client.SetDeadline(time.Now().Add(5 * time.Second))
+			sh, err := tt.hello.do(client)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if sh.version != tt.want.version || sh.suite != tt.want.suite || sh.group != tt.want.group || sh.retry != tt.want.retry {
+				t.Errorf("got %+v, want %+v", *sh, tt.want)
+			}
+		})
+	}
+}
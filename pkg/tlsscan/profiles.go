@@ -0,0 +1,112 @@
+package tlsscan
+
+// Signature algorithms of clients without RSA-PSS support
+var pkcs1Algorithms = []uint16{0x0601, 0x0603, 0x0501, 0x0503, 0x0401, 0x0403, 0x0201, 0x0203}
+
+// Signature algorithms of current browsers
+var modernAlgorithms = []uint16{0x0403, 0x0804, 0x0401, 0x0503, 0x0805, 0x0501, 0x0806, 0x0601}
+
+// Built-in client profiles, approximations of the ClientHello these clients send by default
+var Profiles = []Profile{
+	{
+		Name:       "Chrome 120",
+		MinVersion: VersionTLS12,
+		MaxVersion: VersionTLS13,
+		Suites: []uint16{
+			0x1301, 0x1302, 0x1303,
+			0xc02b, 0xc02f, 0xc02c, 0xc030, 0xcca9, 0xcca8, 0xc013, 0xc014, 0x009c, 0x009d, 0x002f, 0x0035,
+		},
+		Groups:              []uint16{GroupX25519, GroupSecp256r1, GroupSecp384r1},
+		SignatureAlgorithms: modernAlgorithms,
+		SNI:                 true,
+	},
+	{
+		Name:       "Firefox 115",
+		MinVersion: VersionTLS12,
+		MaxVersion: VersionTLS13,
+		Suites: []uint16{
+			0x1301, 0x1303, 0x1302,
+			0xc02b, 0xc02f, 0xcca9, 0xcca8, 0xc02c, 0xc030, 0xc00a, 0xc009, 0xc013, 0xc014, 0x009c, 0x009d, 0x002f, 0x0035,
+		},
+		Groups:              []uint16{GroupX25519, GroupSecp256r1, GroupSecp384r1, GroupSecp521r1, GroupFFDHE2048, GroupFFDHE3072},
+		SignatureAlgorithms: append([]uint16{0x0603, 0x0203}, modernAlgorithms...),
+		SNI:                 true,
+	},
+	{
+		Name:       "Android 7.0",
+		MinVersion: VersionTLS10,
+		MaxVersion: VersionTLS12,
+		Suites: []uint16{
+			0xc02b, 0xc02c, 0xcca9, 0xc02f, 0xc030, 0xcca8, 0xc009, 0xc00a, 0xc013, 0xc014, 0x009c, 0x009d, 0x002f, 0x0035,
+		},
+		Groups:              []uint16{GroupX25519, GroupSecp256r1, GroupSecp384r1},
+		SignatureAlgorithms: pkcs1Algorithms,
+		SNI:                 true,
+	},
+	{
+		Name:       "Android 4.4",
+		MinVersion: VersionTLS10,
+		MaxVersion: VersionTLS12,
+		Suites: []uint16{
+			0xc02b, 0xc02f, 0x009e, 0xc00a, 0xc009, 0xc013, 0xc014, 0xc007, 0xc011, 0x0033, 0x0039, 0x009c, 0x002f, 0x0035, 0x0005, 0x0004,
+		},
+		Groups:              []uint16{GroupSecp256r1, GroupSecp384r1, GroupSecp521r1},
+		SignatureAlgorithms: pkcs1Algorithms,
+		SNI:                 true,
+	},
+	{
+		Name:       "Java 8",
+		MinVersion: VersionTLS10,
+		MaxVersion: VersionTLS12,
+		Suites: []uint16{
+			0xc02c, 0xc02b, 0xc030, 0x009f, 0xc02f, 0x009e, 0xc024, 0xc028, 0x006b, 0xc023, 0xc027, 0x0067,
+			0xc00a, 0xc014, 0x0039, 0xc009, 0xc013, 0x0033, 0x009d, 0x009c, 0x003d, 0x003c, 0x0035, 0x002f, 0xc008, 0xc012, 0x0016, 0x000a,
+		},
+		Groups:              []uint16{GroupSecp256r1, GroupSecp384r1, GroupSecp521r1},
+		SignatureAlgorithms: pkcs1Algorithms,
+		SNI:                 true,
+	},
+	{
+		Name:       "OpenSSL 1.0.2",
+		MinVersion: VersionTLS10,
+		MaxVersion: VersionTLS12,
+		Suites: []uint16{
+			0xc030, 0xc02c, 0xc028, 0xc024, 0xc014, 0xc00a, 0x009f, 0x006b, 0x0039, 0x009d, 0x003d, 0x0035,
+			0xc02f, 0xc02b, 0xc027, 0xc023, 0xc013, 0xc009, 0x009e, 0x0067, 0x0033, 0x009c, 0x003c, 0x002f,
+			0xc012, 0xc008, 0x0016, 0x000a,
+		},
+		Groups:              []uint16{GroupSecp256r1, GroupSecp384r1, GroupSecp521r1},
+		SignatureAlgorithms: pkcs1Algorithms,
+		SNI:                 true,
+	},
+	{
+		// No AES-GCM with RSA authentication and no ChaCha20
+		Name:       "IE 11 Windows 7",
+		MinVersion: VersionTLS10,
+		MaxVersion: VersionTLS12,
+		Suites: []uint16{
+			0xc028, 0xc027, 0xc014, 0xc013, 0x009d, 0x009c, 0x003d, 0x003c, 0x0035, 0x002f,
+			0xc02c, 0xc02b, 0xc024, 0xc023, 0xc00a, 0xc009, 0x000a,
+		},
+		Groups:              []uint16{GroupSecp256r1, GroupSecp384r1},
+		SignatureAlgorithms: []uint16{0x0401, 0x0501, 0x0601, 0x0201, 0x0403, 0x0503, 0x0603, 0x0203},
+		SNI:                 true,
+	},
+	{
+		Name:       "IE 8 Windows XP",
+		MinVersion: VersionSSL30,
+		MaxVersion: VersionTLS10,
+		Suites:     []uint16{0x0004, 0x0005, 0x000a, 0x0009, 0x0003, 0x0006, 0x0012},
+		SNI:        false,
+	},
+}
+
+// Built-in profile by name
+func ProfileByName(name string) (Profile, bool) {
+	for _, profile := range Profiles {
+		if profile.Name == name {
+			return profile, true
+		}
+	}
+	return Profile{}, false
+}
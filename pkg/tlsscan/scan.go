@@ -0,0 +1,266 @@
+package tlsscan
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Dial opens a new TCP connection to the scanned endpoint
+type DialFunc func() (net.Conn, error)
+
+// Timeout of a single probe connection
+var Timeout = 10 * time.Second
+
+// Upper bound of cipher suite probes per protocol version
+const maxSuiteProbes = 64
+
+// Supported protocol versions, cipher suites, ALPN protocols and groups of an endpoint
+type Inventory struct {
+	Versions       []string            `json:"versions"`
+	CipherSuites   map[string][]string `json:"cipher_suites"` // version name: suites in server preference order
+	ALPN           []string            `json:"alpn"`
+	Groups         []string            `json:"groups"`
+	PreferredGroup string              `json:"preferred_group"`
+	WeakVersions   []string            `json:"weak_versions"`
+	WeakSuites     []string            `json:"weak_suites"`
+}
+
+// Probe that could not be carried out, as opposed to a server rejecting the hello
+type probeError struct {
+	err error
+}
+
+func (e *probeError) Error() string { return e.err.Error() }
+
+func (e *probeError) Unwrap() error { return e.err }
+
+// Enumerate everything the endpoint accepts. A failed probe stops the scan,
+// the inventory is incomplete then and must not be compared.
+func Scan(dial DialFunc, serverName string) (Inventory, error) {
+	inv := Inventory{CipherSuites: make(map[string][]string)}
+	weakSuites := make(map[string]bool)
+
+	for _, version := range Versions {
+		suites, err := enumerateSuites(dial, serverName, version)
+		if err != nil {
+			return inv, fmt.Errorf("%s cipher suites: %w", VersionName(version), err)
+		}
+		if len(suites) == 0 {
+			continue
+		}
+
+		name := VersionName(version)
+		inv.Versions = append(inv.Versions, name)
+		if IsWeakVersion(version) {
+			inv.WeakVersions = append(inv.WeakVersions, name)
+		}
+		for _, suite := range suites {
+			inv.CipherSuites[name] = append(inv.CipherSuites[name], SuiteName(suite))
+			if IsWeakSuite(suite) {
+				weakSuites[SuiteName(suite)] = true
+			}
+		}
+	}
+	for suite := range weakSuites {
+		inv.WeakSuites = append(inv.WeakSuites, suite)
+	}
+	sort.Strings(inv.WeakSuites)
+
+	var err error
+	if inv.Groups, inv.PreferredGroup, err = enumerateGroups(dial, serverName, inv.supports(VersionTLS13)); err != nil {
+		return inv, fmt.Errorf("groups: %w", err)
+	}
+	if inv.ALPN, err = enumerateALPN(dial, serverName); err != nil {
+		return inv, fmt.Errorf("ALPN: %w", err)
+	}
+	return inv, nil
+}
+
+// Summary used to detect changes between scans
+func (inv Inventory) Fingerprint() string {
+	var parts []string
+	for _, version := range inv.Versions {
+		parts = append(parts, version+"="+strings.Join(inv.CipherSuites[version], ","))
+	}
+	parts = append(parts, "alpn="+strings.Join(inv.ALPN, ","))
+	parts = append(parts, "groups="+strings.Join(inv.Groups, ","))
+	return strings.Join(parts, ";")
+}
+
+// All cipher suites as "TLS 1.2: TLS_ECDHE_..." lines
+func (inv Inventory) SuiteList() []string {
+	var list []string
+	for _, version := range inv.Versions {
+		for _, suite := range inv.CipherSuites[version] {
+			list = append(list, version+": "+suite)
+		}
+	}
+	return list
+}
+
+func (inv Inventory) supports(version uint16) bool {
+	for _, v := range inv.Versions {
+		if v == VersionName(version) {
+			return true
+		}
+	}
+	return false
+}
+
+// Offer every remaining suite, drop the one the server picks and repeat
+func enumerateSuites(dial DialFunc, serverName string, version uint16) ([]uint16, error) {
+	remaining := legacySuites
+	if version >= VersionTLS13 {
+		remaining = tls13Suites
+	}
+	remaining = append([]uint16(nil), remaining...)
+
+	var accepted []uint16
+	for i := 0; i < maxSuiteProbes && len(remaining) > 0; i++ {
+		sh, err := probe(dial, hello{
+			version:    version,
+			suites:     remaining,
+			groups:     Groups,
+			keyShares:  []uint16{GroupX25519, GroupSecp256r1},
+			serverName: serverName,
+		})
+		if isProbeError(err) {
+			return accepted, err
+		}
+		if err != nil || sh.version != version || !contains(remaining, sh.suite) {
+			break
+		}
+		accepted = append(accepted, sh.suite)
+		remaining = remove(remaining, sh.suite)
+	}
+	return accepted, nil
+}
+
+// Offer one group at a time, then all of them to learn the server preference
+func enumerateGroups(dial DialFunc, serverName string, tls13 bool) ([]string, string, error) {
+	version := VersionTLS12
+	suites := ecdheSuites()
+	if tls13 {
+		version = VersionTLS13
+		suites = tls13Suites
+	}
+
+	var groups []string
+	for _, group := range Groups {
+		h := hello{version: version, suites: suites, groups: []uint16{group}, serverName: serverName}
+		if tls13 && hasKeyShare(group) {
+			h.keyShares = []uint16{group}
+		}
+		sh, err := probe(dial, h)
+		if isProbeError(err) {
+			return groups, "", err
+		}
+		if err != nil || sh.version != version {
+			continue
+		}
+		// TLS 1.2 does not echo the group, an ECDHE suite means the curve was accepted
+		if tls13 && sh.group != group {
+			continue
+		}
+		groups = append(groups, GroupName(group))
+	}
+
+	// Without key shares a TLS 1.3 server has to ask for the group it prefers
+	preferred := ""
+	if tls13 {
+		sh, err := probe(dial, hello{version: version, suites: suites, groups: Groups, serverName: serverName})
+		if isProbeError(err) {
+			return groups, "", err
+		}
+		if err == nil && sh.retry {
+			preferred = GroupName(sh.group)
+		}
+	}
+	return groups, preferred, nil
+}
+
+func enumerateALPN(dial DialFunc, serverName string) ([]string, error) {
+	var protocols []string
+	for _, protocol := range ALPNProtocols {
+		conn, err := dial()
+		if err != nil {
+			return protocols, &probeError{err}
+		}
+		conn.SetDeadline(time.Now().Add(Timeout))
+		tlsConn := tls.Client(conn, &tls.Config{
+			ServerName:         serverName,
+			InsecureSkipVerify: true,
+			NextProtos:         []string{protocol},
+		})
+		err = tlsConn.Handshake()
+		negotiated := tlsConn.ConnectionState().NegotiatedProtocol
+		tlsConn.Close()
+		if isTimeout(err) {
+			return protocols, &probeError{err}
+		}
+		if err == nil && negotiated == protocol {
+			protocols = append(protocols, protocol)
+		}
+	}
+	return protocols, nil
+}
+
+// Send the hello on a new connection. Dial errors and timeouts are returned as
+// probe errors, an alert or a closed connection means the server rejected the hello.
+func probe(dial DialFunc, h hello) (*serverHello, error) {
+	conn, err := dial()
+	if err != nil {
+		return nil, &probeError{err}
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(Timeout))
+	sh, err := h.do(conn)
+	if isTimeout(err) {
+		return nil, &probeError{err}
+	}
+	return sh, err
+}
+
+func isProbeError(err error) bool {
+	var probeErr *probeError
+	return errors.As(err, &probeErr)
+}
+
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+func ecdheSuites() []uint16 {
+	var suites []uint16
+	for _, suite := range legacySuites {
+		if strings.HasPrefix(SuiteName(suite), "TLS_ECDHE_") {
+			suites = append(suites, suite)
+		}
+	}
+	return suites
+}
+
+func contains(list []uint16, v uint16) bool {
+	for _, item := range list {
+		if item == v {
+			return true
+		}
+	}
+	return false
+}
+
+func remove(list []uint16, v uint16) []uint16 {
+	var result []uint16
+	for _, item := range list {
+		if item != v {
+			result = append(result, item)
+		}
+	}
+	return result
+}
@@ -0,0 +1,137 @@
+package tlsscan
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"io"
+	"math/big"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Self-signed ECDSA certificate for localhost
+func testCertificate(t *testing.T) tls.Certificate {
+	t.Helper()
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: cert}
+}
+
+// TLS server on a loopback port, every connection does one handshake
+func testServer(t *testing.T, config *tls.Config) DialFunc {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				conn.SetDeadline(time.Now().Add(5 * time.Second))
+				tls.Server(conn, config).Handshake()
+				conn.Close()
+			}()
+		}
+	}()
+	return func() (net.Conn, error) {
+		return net.DialTimeout("tcp", listener.Addr().String(), time.Second)
+	}
+}
+
+func TestScan(t *testing.T) {
+	dial := testServer(t, &tls.Config{
+		Certificates:     []tls.Certificate{testCertificate(t)},
+		MinVersion:       tls.VersionTLS12,
+		MaxVersion:       tls.VersionTLS12,
+		CipherSuites:     []uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256, tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305},
+		CurvePreferences: []tls.CurveID{tls.X25519, tls.CurveP256},
+		NextProtos:       []string{"http/1.1"},
+	})
+
+	inv, err := Scan(dial, "localhost")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(inv.Versions, ",") != "TLS 1.2" || len(inv.WeakVersions) != 0 {
+		t.Errorf("versions = %v, weak %v", inv.Versions, inv.WeakVersions)
+	}
+	suites := inv.CipherSuites["TLS 1.2"]
+	if len(suites) != 2 || !containsString(suites, "TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256") {
+		t.Errorf("suites = %v", suites)
+	}
+	if strings.Join(inv.Groups, ",") != "x25519,secp256r1" {
+		t.Errorf("groups = %v", inv.Groups)
+	}
+	if strings.Join(inv.ALPN, ",") != "http/1.1" {
+		t.Errorf("ALPN = %v", inv.ALPN)
+	}
+}
+
+func TestScanProbeFailure(t *testing.T) {
+	dial := testServer(t, &tls.Config{Certificates: []tls.Certificate{testCertificate(t)}, MinVersion: tls.VersionTLS12})
+	errDown := errors.New("connection refused")
+	connections := 0
+	flaky := func() (net.Conn, error) {
+		if connections++; connections > 6 {
+			return nil, errDown
+		}
+		return dial()
+	}
+	if _, err := Scan(flaky, "localhost"); !errors.Is(err, errDown) {
+		t.Errorf("err = %v, want the dial error", err)
+	}
+
+	// A server that never answers is a failed probe, not a rejected hello
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go io.Copy(io.Discard, conn)
+		}
+	}()
+	defer func(timeout time.Duration) { Timeout = timeout }(Timeout)
+	Timeout = 100 * time.Millisecond
+	silent := func() (net.Conn, error) { return net.Dial("tcp", listener.Addr().String()) }
+	if _, err := Scan(silent, "localhost"); !isTimeout(err) {
+		t.Errorf("err = %v, want a timeout", err)
+	}
+}
+
+func containsString(list []string, v string) bool {
+	for _, item := range list {
+		if item == v {
+			return true
+		}
+	}
+	return false
+}
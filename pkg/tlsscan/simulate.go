@@ -0,0 +1,326 @@
+package tlsscan
+
+import (
+	"crypto/x509"
+	"fmt"
+	"strings"
+)
+
+// Signature schemes (RFC 8446, 4.2.3)
+var signatureAlgorithmNames = map[uint16]string{
+	0x0201: "rsa_pkcs1_sha1",
+	0x0203: "ecdsa_sha1",
+	0x0401: "rsa_pkcs1_sha256",
+	0x0403: "ecdsa_secp256r1_sha256",
+	0x0501: "rsa_pkcs1_sha384",
+	0x0503: "ecdsa_secp384r1_sha384",
+	0x0601: "rsa_pkcs1_sha512",
+	0x0603: "ecdsa_secp521r1_sha512",
+	0x0804: "rsa_pss_rsae_sha256",
+	0x0805: "rsa_pss_rsae_sha384",
+	0x0806: "rsa_pss_rsae_sha512",
+	0x0807: "ed25519",
+	0x0808: "ed448",
+	0x0809: "rsa_pss_pss_sha256",
+	0x080a: "rsa_pss_pss_sha384",
+	0x080b: "rsa_pss_pss_sha512",
+}
+
+// Client profile of a simulated handshake
+type Profile struct {
+	Name                string
+	MinVersion          uint16
+	MaxVersion          uint16
+	Suites              []uint16 // in client preference order, TLS 1.3 suites included
+	Groups              []uint16
+	SignatureAlgorithms []uint16 // empty: not restricted
+	SNI                 bool
+}
+
+// Outcome of a simulated handshake
+type Simulation struct {
+	Client  string `json:"client"`
+	Success bool   `json:"success"`
+	Version string `json:"version"`
+	Suite   string `json:"suite"`
+	Reason  string `json:"reason"` // why the client fails
+}
+
+// Name of the signature scheme like "rsa_pss_rsae_sha256"
+func SignatureAlgorithmName(id uint16) string {
+	if name, ok := signatureAlgorithmNames[id]; ok {
+		return name
+	}
+	return fmt.Sprintf("0x%04x", id)
+}
+
+// Protocol version by name like "TLS 1.2"
+func VersionByName(name string) (uint16, error) {
+	return byName(versionNames, name, "protocol version")
+}
+
+// Cipher suite by IANA name
+func SuiteByName(name string) (uint16, error) {
+	return byName(suiteNames, name, "cipher suite")
+}
+
+// Key exchange group by name like "x25519"
+func GroupByName(name string) (uint16, error) {
+	return byName(groupNames, name, "group")
+}
+
+// Signature scheme by name like "ecdsa_secp256r1_sha256"
+func SignatureAlgorithmByName(name string) (uint16, error) {
+	return byName(signatureAlgorithmNames, name, "signature algorithm")
+}
+
+func byName(names map[uint16]string, name string, kind string) (uint16, error) {
+	for id, n := range names {
+		if strings.EqualFold(n, strings.TrimSpace(name)) {
+			return id, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown %s %q", kind, name)
+}
+
+// Send the ClientHello of the profile and tell whether the client would get through the handshake.
+// The certificate chain is checked against the signature algorithms of the profile.
+// A probe that cannot be carried out, like a failed dial, is an error and not a failing client.
+func Simulate(dial DialFunc, serverName string, profile Profile, certs []*x509.Certificate) (Simulation, error) {
+	result := Simulation{Client: profile.Name}
+
+	h := profile.hello(serverName)
+	sh, err := probe(dial, h)
+	if err == nil && sh.retry {
+		// HelloRetryRequest asks for a key share of another group the client has to offer
+		if !contains(profile.Groups, sh.group) || !hasKeyShare(sh.group) {
+			result.Reason = "no common key exchange group, server asked for " + GroupName(sh.group)
+			return result, nil
+		}
+		h.keyShares = []uint16{sh.group}
+		sh, err = probe(dial, h)
+	}
+	if isProbeError(err) {
+		return result, err
+	}
+	if err != nil {
+		result.Reason, err = profile.diagnose(dial, serverName, err)
+		return result, err
+	}
+
+	result.Version = VersionName(sh.version)
+	result.Suite = SuiteName(sh.suite)
+	switch {
+	case sh.version < profile.MinVersion || sh.version > profile.MaxVersion:
+		result.Reason = "server negotiated " + VersionName(sh.version) + ", the client supports " + profile.versionRange()
+	case !contains(profile.Suites, sh.suite):
+		result.Reason = "server picked " + SuiteName(sh.suite) + " the client did not offer"
+	case sh.retry:
+		result.Reason = "server sent a second HelloRetryRequest"
+	default:
+		result.Reason = profile.certificateProblem(sh.version, certs)
+	}
+	result.Success = result.Reason == ""
+	return result, nil
+}
+
+// ClientHello of the profile at its highest version
+func (p Profile) hello(serverName string) hello {
+	h := hello{
+		version:             p.MaxVersion,
+		suites:              p.Suites,
+		groups:              p.Groups,
+		signatureAlgorithms: p.SignatureAlgorithms,
+	}
+	if p.SNI {
+		h.serverName = serverName
+	}
+	if p.MaxVersion >= VersionTLS13 {
+		for v := p.MaxVersion; v >= p.MinVersion && v >= VersionTLS10; v-- {
+			h.versions = append(h.versions, v)
+		}
+		for _, group := range p.Groups {
+			if hasKeyShare(group) {
+				h.keyShares = []uint16{group}
+				break
+			}
+		}
+	}
+	return h
+}
+
+// Narrow down why the server rejected the hello: protocol version, SNI, cipher suites or groups
+func (p Profile) diagnose(dial DialFunc, serverName string, rejected error) (string, error) {
+	name := ""
+	if p.SNI {
+		name = serverName
+	}
+	version, err := p.acceptedVersion(dial, name)
+	if err != nil {
+		return "", err
+	}
+	if version == 0 {
+		if !p.SNI {
+			withSNI, err := p.acceptedVersion(dial, serverName)
+			if err != nil {
+				return "", err
+			}
+			if withSNI != 0 {
+				return "server requires SNI, the client does not send it", nil
+			}
+		}
+		return "no common protocol version, the client supports " + p.versionRange(), nil
+	}
+
+	// Profile suites with every group
+	h := hello{version: version, suites: p.suitesFor(version), groups: Groups, keyShares: []uint16{GroupX25519, GroupSecp256r1}, serverName: name}
+	sh, err := probe(dial, h)
+	if isProbeError(err) {
+		return "", err
+	}
+	if err != nil || sh.version != version {
+		return "no common cipher suite in " + VersionName(version), nil
+	}
+
+	// Every suite with the profile groups
+	h = hello{version: version, suites: versionSuites(version), groups: p.Groups, serverName: name}
+	if version >= VersionTLS13 {
+		h.keyShares = p.hello(serverName).keyShares
+	}
+	sh, err = probe(dial, h)
+	if isProbeError(err) {
+		return "", err
+	}
+	if err != nil || (sh.retry && !contains(p.Groups, sh.group)) {
+		return "no common key exchange group", nil
+	}
+	return "server rejected the handshake: " + rejected.Error(), nil
+}
+
+// Highest version in the profile range the server accepts with any suite, 0 when there is none
+func (p Profile) acceptedVersion(dial DialFunc, serverName string) (uint16, error) {
+	for v := p.MaxVersion; v >= p.MinVersion && v >= VersionSSL30; v-- {
+		sh, err := probe(dial, hello{
+			version:    v,
+			suites:     versionSuites(v),
+			groups:     Groups,
+			keyShares:  []uint16{GroupX25519, GroupSecp256r1},
+			serverName: serverName,
+		})
+		if isProbeError(err) {
+			return 0, err
+		}
+		if err == nil && sh.version == v {
+			return v, nil
+		}
+	}
+	return 0, nil
+}
+
+// Suites of the profile usable with the version
+func (p Profile) suitesFor(version uint16) []uint16 {
+	var suites []uint16
+	for _, suite := range p.Suites {
+		if contains(tls13Suites, suite) == (version >= VersionTLS13) {
+			suites = append(suites, suite)
+		}
+	}
+	return suites
+}
+
+func (p Profile) versionRange() string {
+	if p.MinVersion == p.MaxVersion {
+		return VersionName(p.MinVersion) + " only"
+	}
+	return VersionName(p.MinVersion) + " to " + VersionName(p.MaxVersion)
+}
+
+// Why the client would reject the certificate chain, empty when it accepts it.
+// Before TLS 1.2 clients send no signature algorithms, there is nothing to compare.
+func (p Profile) certificateProblem(version uint16, certs []*x509.Certificate) string {
+	if len(certs) == 0 || len(p.SignatureAlgorithms) == 0 || version < VersionTLS12 {
+		return ""
+	}
+
+	leaf := certs[0]
+	if !containsAny(p.SignatureAlgorithms, keySchemes(leaf, version)) {
+		return "client supports no signature algorithm for the " + leaf.PublicKeyAlgorithm.String() + " key of the certificate"
+	}
+	for _, cert := range certs {
+		// The signature of a root is never checked
+		if cert.IsCA && string(cert.RawSubject) == string(cert.RawIssuer) {
+			continue
+		}
+		schemes := certificateSchemes(cert.SignatureAlgorithm)
+		if len(schemes) > 0 && !containsAny(p.SignatureAlgorithms, schemes) {
+			return "client does not support the " + cert.SignatureAlgorithm.String() + " signature of " + cert.Subject.CommonName
+		}
+	}
+	return ""
+}
+
+// Schemes the server can sign the handshake with using the key of the certificate
+func keySchemes(cert *x509.Certificate, version uint16) []uint16 {
+	switch cert.PublicKeyAlgorithm {
+	case x509.RSA:
+		if version >= VersionTLS13 {
+			return []uint16{0x0804, 0x0805, 0x0806}
+		}
+		return []uint16{0x0804, 0x0805, 0x0806, 0x0401, 0x0501, 0x0601, 0x0201}
+	case x509.ECDSA:
+		if version >= VersionTLS13 {
+			return []uint16{0x0403, 0x0503, 0x0603}
+		}
+		return []uint16{0x0403, 0x0503, 0x0603, 0x0203}
+	case x509.Ed25519:
+		return []uint16{0x0807}
+	}
+	return nil
+}
+
+// Schemes matching the signature algorithm of a certificate
+func certificateSchemes(algorithm x509.SignatureAlgorithm) []uint16 {
+	switch algorithm {
+	case x509.SHA1WithRSA:
+		return []uint16{0x0201}
+	case x509.SHA256WithRSA:
+		return []uint16{0x0401}
+	case x509.SHA384WithRSA:
+		return []uint16{0x0501}
+	case x509.SHA512WithRSA:
+		return []uint16{0x0601}
+	case x509.ECDSAWithSHA1:
+		return []uint16{0x0203}
+	case x509.ECDSAWithSHA256:
+		return []uint16{0x0403}
+	case x509.ECDSAWithSHA384:
+		return []uint16{0x0503}
+	case x509.ECDSAWithSHA512:
+		return []uint16{0x0603}
+	case x509.SHA256WithRSAPSS:
+		return []uint16{0x0804, 0x0809}
+	case x509.SHA384WithRSAPSS:
+		return []uint16{0x0805, 0x080a}
+	case x509.SHA512WithRSAPSS:
+		return []uint16{0x0806, 0x080b}
+	case x509.PureEd25519:
+		return []uint16{0x0807}
+	}
+	return nil
+}
+
+func versionSuites(version uint16) []uint16 {
+	if version >= VersionTLS13 {
+		return tls13Suites
+	}
+	return legacySuites
+}
+
+func containsAny(list []uint16, values []uint16) bool {
+	for _, v := range values {
+		if contains(list, v) {
+			return true
+		}
+	}
+	return false
+}
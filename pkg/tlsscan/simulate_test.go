@@ -0,0 +1,138 @@
+package tlsscan
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net"
+	"testing"
+)
+
+func TestSimulate(t *testing.T) {
+	cert := testCertificate(t)
+	modern := Profile{
+		Name:                "modern",
+		MinVersion:          VersionTLS12,
+		MaxVersion:          VersionTLS13,
+		Suites:              []uint16{0x1301, 0x1302, 0x1303, 0xc02b, 0xcca9},
+		Groups:              []uint16{GroupX25519, GroupSecp256r1},
+		SignatureAlgorithms: modernAlgorithms,
+		SNI:                 true,
+	}
+	restrict := func(f func(p *Profile)) Profile {
+		p := modern
+		f(&p)
+		return p
+	}
+	requireSNI := func(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+		if hello.ServerName == "" {
+			return nil, errors.New("no server name")
+		}
+		return &cert, nil
+	}
+
+	tests := []struct {
+		name    string
+		server  *tls.Config
+		profile Profile
+		version string
+		reason  string
+	}{
+		{"TLS 1.3", &tls.Config{}, modern, "TLS 1.3", ""},
+		{"HelloRetryRequest for another group", &tls.Config{CurvePreferences: []tls.CurveID{tls.CurveP256}}, modern, "TLS 1.3", ""},
+		{"TLS 1.2 client", &tls.Config{}, restrict(func(p *Profile) { p.MaxVersion = VersionTLS12 }), "TLS 1.2", ""},
+		{"no common version", &tls.Config{MaxVersion: tls.VersionTLS12}, restrict(func(p *Profile) { p.MinVersion = VersionTLS13 }),
+			"", "no common protocol version, the client supports TLS 1.3 only"},
+		{"no common suite", &tls.Config{MaxVersion: tls.VersionTLS12, CipherSuites: []uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256}},
+			restrict(func(p *Profile) { p.Suites = []uint16{0xcca9} }), "", "no common cipher suite in TLS 1.2"},
+		{"no common group", &tls.Config{CurvePreferences: []tls.CurveID{tls.CurveP384}}, modern, "", "no common key exchange group"},
+		{"SNI required", &tls.Config{GetCertificate: requireSNI}, restrict(func(p *Profile) { p.SNI = false }),
+			"", "server requires SNI, the client does not send it"},
+		{"certificate key not supported", &tls.Config{MaxVersion: tls.VersionTLS12}, restrict(func(p *Profile) { p.SignatureAlgorithms = []uint16{0x0804, 0x0401} }),
+			"TLS 1.2", "client supports no signature algorithm for the ECDSA key of the certificate"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := tt.server.Clone()
+			if config.GetCertificate == nil {
+				config.Certificates = []tls.Certificate{cert}
+			}
+			config.MinVersion = tls.VersionTLS12
+			dial := testServer(t, config)
+
+			result, err := Simulate(dial, "localhost", tt.profile, []*x509.Certificate{cert.Leaf})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Success != (tt.reason == "") || result.Reason != tt.reason || result.Version != tt.version {
+				t.Errorf("got success %v in %q: %q, want %q: %q", result.Success, result.Version, result.Reason, tt.version, tt.reason)
+			}
+		})
+	}
+}
+
+func TestSimulateProbeFailure(t *testing.T) {
+	errDown := errors.New("connection refused")
+	down := func() (net.Conn, error) { return nil, errDown }
+	if _, err := Simulate(down, "localhost", Profiles[0], nil); !errors.Is(err, errDown) {
+		t.Errorf("err = %v, want the dial error", err)
+	}
+
+	// The server goes away while the rejected hello is diagnosed
+	dial := testServer(t, &tls.Config{Certificates: []tls.Certificate{testCertificate(t)}, MaxVersion: tls.VersionTLS12})
+	connections := 0
+	flaky := func() (net.Conn, error) {
+		if connections++; connections > 1 {
+			return nil, errDown
+		}
+		return dial()
+	}
+	tls13 := Profiles[0]
+	tls13.MinVersion = VersionTLS13
+	if result, err := Simulate(flaky, "localhost", tls13, nil); !errors.Is(err, errDown) {
+		t.Errorf("got %+v, %v, want the dial error", result, err)
+	}
+}
+
+func TestCertificateProblem(t *testing.T) {
+	root := &x509.Certificate{IsCA: true, RawSubject: []byte("root"), RawIssuer: []byte("root"), SignatureAlgorithm: x509.SHA1WithRSA}
+	intermediate := &x509.Certificate{IsCA: true, RawSubject: []byte("ca"), RawIssuer: []byte("root"), SignatureAlgorithm: x509.SHA256WithRSA}
+	sha1Intermediate := &x509.Certificate{IsCA: true, RawSubject: []byte("ca"), RawIssuer: []byte("root"), SignatureAlgorithm: x509.SHA1WithRSA}
+	sha1Intermediate.Subject.CommonName = "Legacy CA"
+	rsaLeaf := &x509.Certificate{PublicKeyAlgorithm: x509.RSA, SignatureAlgorithm: x509.SHA256WithRSA}
+	ecdsaLeaf := &x509.Certificate{PublicKeyAlgorithm: x509.ECDSA, SignatureAlgorithm: x509.SHA256WithRSA}
+	pssOnly := Profile{SignatureAlgorithms: []uint16{0x0804, 0x0401}}
+
+	tests := []struct {
+		name    string
+		profile Profile
+		version uint16
+		certs   []*x509.Certificate
+		want    string
+	}{
+		{"supported chain", pssOnly, VersionTLS12, []*x509.Certificate{rsaLeaf, intermediate, root}, ""},
+		{"root signature is not checked", pssOnly, VersionTLS13, []*x509.Certificate{rsaLeaf, intermediate, root}, ""},
+		{"ECDSA key", pssOnly, VersionTLS12, []*x509.Certificate{ecdsaLeaf}, "client supports no signature algorithm for the ECDSA key of the certificate"},
+		{"SHA-1 intermediate", pssOnly, VersionTLS12, []*x509.Certificate{rsaLeaf, sha1Intermediate},
+			"client does not support the SHA1-RSA signature of Legacy CA"},
+		{"no signature algorithms before TLS 1.2", pssOnly, VersionTLS11, []*x509.Certificate{ecdsaLeaf}, ""},
+		{"client without restrictions", Profile{}, VersionTLS12, []*x509.Certificate{ecdsaLeaf}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.profile.certificateProblem(tt.version, tt.certs); got != tt.want {
+				t.Errorf("certificateProblem = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProfileHello(t *testing.T) {
+	h := Profiles[0].hello("example.com")
+	if h.version != VersionTLS13 || h.serverName != "example.com" || len(h.keyShares) != 1 || h.keyShares[0] != GroupX25519 {
+		t.Errorf("hello = %+v", h)
+	}
+	if got := VersionName(h.versions[len(h.versions)-1]); got != "TLS 1.2" {
+		t.Errorf("lowest offered version = %s, want TLS 1.2", got)
+	}
+}
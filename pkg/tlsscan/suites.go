@@ -0,0 +1,180 @@
+package tlsscan
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Protocol versions
+const (
+	VersionSSL30 uint16 = 0x0300
+	VersionTLS10 uint16 = 0x0301
+	VersionTLS11 uint16 = 0x0302
+	VersionTLS12 uint16 = 0x0303
+	VersionTLS13 uint16 = 0x0304
+)
+
+// Versions probed in deep scan mode, oldest first
+var Versions = []uint16{VersionSSL30, VersionTLS10, VersionTLS11, VersionTLS12, VersionTLS13}
+
+var versionNames = map[uint16]string{
+	VersionSSL30: "SSL 3.0",
+	VersionTLS10: "TLS 1.0",
+	VersionTLS11: "TLS 1.1",
+	VersionTLS12: "TLS 1.2",
+	VersionTLS13: "TLS 1.3",
+}
+
+// TLS 1.3 cipher suites
+var tls13Suites = []uint16{0x1301, 0x1302, 0x1303, 0x1304, 0x1305}
+
+// Cipher suites offered to SSL 3.0 - TLS 1.2 servers, including the legacy ones we want to detect
+var legacySuites = []uint16{
+	// ECDHE
+	0xc02b, 0xc02c, 0xc02f, 0xc030, 0xcca8, 0xcca9, 0xc023, 0xc024, 0xc027, 0xc028,
+	0xc009, 0xc00a, 0xc013, 0xc014, 0xc007, 0xc011, 0xc008, 0xc012, 0xc006, 0xc010,
+	// DHE
+	0x009e, 0x009f, 0xccaa, 0x0067, 0x006b, 0x0033, 0x0039, 0x0016, 0x0015, 0x0014, 0x0011, 0x0012,
+	// RSA key exchange
+	0x009c, 0x009d, 0x003c, 0x003d, 0x002f, 0x0035, 0x0041, 0x0084, 0x000a, 0x0009, 0x0008,
+	0x0005, 0x0004, 0x0003, 0x0006, 0x0002, 0x0001, 0x003b,
+	// Anonymous
+	0x0018, 0x001b, 0x0034, 0x003a, 0x006c, 0x006d, 0x00a6, 0x00a7, 0xc018, 0xc019,
+}
+
+var suiteNames = map[uint16]string{
+	0x0001: "TLS_RSA_WITH_NULL_MD5",
+	0x0002: "TLS_RSA_WITH_NULL_SHA",
+	0x0003: "TLS_RSA_EXPORT_WITH_RC4_40_MD5",
+	0x0004: "TLS_RSA_WITH_RC4_128_MD5",
+	0x0005: "TLS_RSA_WITH_RC4_128_SHA",
+	0x0006: "TLS_RSA_EXPORT_WITH_RC2_CBC_40_MD5",
+	0x0008: "TLS_RSA_EXPORT_WITH_DES40_CBC_SHA",
+	0x0009: "TLS_RSA_WITH_DES_CBC_SHA",
+	0x000a: "TLS_RSA_WITH_3DES_EDE_CBC_SHA",
+	0x0011: "TLS_DHE_DSS_EXPORT_WITH_DES40_CBC_SHA",
+	0x0012: "TLS_DHE_DSS_WITH_DES_CBC_SHA",
+	0x0014: "TLS_DHE_RSA_EXPORT_WITH_DES40_CBC_SHA",
+	0x0015: "TLS_DHE_RSA_WITH_DES_CBC_SHA",
+	0x0016: "TLS_DHE_RSA_WITH_3DES_EDE_CBC_SHA",
+	0x0018: "TLS_DH_anon_WITH_RC4_128_MD5",
+	0x001b: "TLS_DH_anon_WITH_3DES_EDE_CBC_SHA",
+	0x002f: "TLS_RSA_WITH_AES_128_CBC_SHA",
+	0x0033: "TLS_DHE_RSA_WITH_AES_128_CBC_SHA",
+	0x0034: "TLS_DH_anon_WITH_AES_128_CBC_SHA",
+	0x0035: "TLS_RSA_WITH_AES_256_CBC_SHA",
+	0x0039: "TLS_DHE_RSA_WITH_AES_256_CBC_SHA",
+	0x003a: "TLS_DH_anon_WITH_AES_256_CBC_SHA",
+	0x003b: "TLS_RSA_WITH_NULL_SHA256",
+	0x003c: "TLS_RSA_WITH_AES_128_CBC_SHA256",
+	0x003d: "TLS_RSA_WITH_AES_256_CBC_SHA256",
+	0x0041: "TLS_RSA_WITH_CAMELLIA_128_CBC_SHA",
+	0x0067: "TLS_DHE_RSA_WITH_AES_128_CBC_SHA256",
+	0x006b: "TLS_DHE_RSA_WITH_AES_256_CBC_SHA256",
+	0x006c: "TLS_DH_anon_WITH_AES_128_CBC_SHA256",
+	0x006d: "TLS_DH_anon_WITH_AES_256_CBC_SHA256",
+	0x0084: "TLS_RSA_WITH_CAMELLIA_256_CBC_SHA",
+	0x009c: "TLS_RSA_WITH_AES_128_GCM_SHA256",
+	0x009d: "TLS_RSA_WITH_AES_256_GCM_SHA384",
+	0x009e: "TLS_DHE_RSA_WITH_AES_128_GCM_SHA256",
+	0x009f: "TLS_DHE_RSA_WITH_AES_256_GCM_SHA384",
+	0x00a6: "TLS_DH_anon_WITH_AES_128_GCM_SHA256",
+	0x00a7: "TLS_DH_anon_WITH_AES_256_GCM_SHA384",
+	0x1301: "TLS_AES_128_GCM_SHA256",
+	0x1302: "TLS_AES_256_GCM_SHA384",
+	0x1303: "TLS_CHACHA20_POLY1305_SHA256",
+	0x1304: "TLS_AES_128_CCM_SHA256",
+	0x1305: "TLS_AES_128_CCM_8_SHA256",
+	0xc006: "TLS_ECDHE_ECDSA_WITH_NULL_SHA",
+	0xc007: "TLS_ECDHE_ECDSA_WITH_RC4_128_SHA",
+	0xc008: "TLS_ECDHE_ECDSA_WITH_3DES_EDE_CBC_SHA",
+	0xc009: "TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA",
+	0xc00a: "TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA",
+	0xc010: "TLS_ECDHE_RSA_WITH_NULL_SHA",
+	0xc011: "TLS_ECDHE_RSA_WITH_RC4_128_SHA",
+	0xc012: "TLS_ECDHE_RSA_WITH_3DES_EDE_CBC_SHA",
+	0xc013: "TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA",
+	0xc014: "TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA",
+	0xc018: "TLS_ECDH_anon_WITH_AES_128_CBC_SHA",
+	0xc019: "TLS_ECDH_anon_WITH_AES_256_CBC_SHA",
+	0xc023: "TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA256",
+	0xc024: "TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA384",
+	0xc027: "TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA256",
+	0xc028: "TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA384",
+	0xc02b: "TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256",
+	0xc02c: "TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384",
+	0xc02f: "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256",
+	0xc030: "TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384",
+	0xcca8: "TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256",
+	0xcca9: "TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256",
+	0xccaa: "TLS_DHE_RSA_WITH_CHACHA20_POLY1305_SHA256",
+}
+
+// Named groups (RFC 8446, RFC 7919)
+const (
+	GroupSecp256r1 uint16 = 0x0017
+	GroupSecp384r1 uint16 = 0x0018
+	GroupSecp521r1 uint16 = 0x0019
+	GroupX25519    uint16 = 0x001d
+	GroupX448      uint16 = 0x001e
+	GroupFFDHE2048 uint16 = 0x0100
+	GroupFFDHE3072 uint16 = 0x0101
+	GroupFFDHE4096 uint16 = 0x0102
+)
+
+// Groups probed in deep scan mode, in client preference order
+var Groups = []uint16{GroupX25519, GroupSecp256r1, GroupSecp384r1, GroupSecp521r1, GroupX448, GroupFFDHE2048, GroupFFDHE3072, GroupFFDHE4096}
+
+var groupNames = map[uint16]string{
+	GroupSecp256r1: "secp256r1",
+	GroupSecp384r1: "secp384r1",
+	GroupSecp521r1: "secp521r1",
+	GroupX25519:    "x25519",
+	GroupX448:      "x448",
+	GroupFFDHE2048: "ffdhe2048",
+	GroupFFDHE3072: "ffdhe3072",
+	GroupFFDHE4096: "ffdhe4096",
+}
+
+// ALPN protocols probed in deep scan mode
+var ALPNProtocols = []string{"h2", "http/1.1"}
+
+// Name of the protocol version like "TLS 1.2"
+func VersionName(version uint16) string {
+	if name, ok := versionNames[version]; ok {
+		return name
+	}
+	return fmt.Sprintf("0x%04x", version)
+}
+
+// IANA name of the cipher suite
+func SuiteName(id uint16) string {
+	if name, ok := suiteNames[id]; ok {
+		return name
+	}
+	return fmt.Sprintf("0x%04x", id)
+}
+
+// Name of the key exchange group
+func GroupName(id uint16) string {
+	if name, ok := groupNames[id]; ok {
+		return name
+	}
+	return fmt.Sprintf("0x%04x", id)
+}
+
+// Deprecated protocol versions (RFC 8996)
+func IsWeakVersion(version uint16) bool {
+	return version < VersionTLS12
+}
+
+// Weak cipher suites: NULL, EXPORT, anonymous, RC4, RC2, DES and 3DES
+func IsWeakSuite(id uint16) bool {
+	name := SuiteName(id)
+	for _, weak := range []string{"_NULL_", "_EXPORT_", "_anon_", "_RC4_", "_RC2_", "_DES_", "_DES40_", "_3DES_"} {
+		if strings.Contains(name, weak) {
+			return true
+		}
+	}
+	return false
+}